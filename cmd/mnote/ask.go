@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/giantswarm/mnote/internal/ask"
+	"github.com/giantswarm/mnote/internal/config"
+	"github.com/giantswarm/mnote/internal/summarize"
+	"github.com/giantswarm/mnote/internal/transcript"
+	"github.com/spf13/cobra"
+)
+
+// AskOptions holds the options of the ask command
+type AskOptions struct {
+	Question    string
+	Paths       []string
+	TopK        int
+	Interactive bool
+
+	// Output locations used by the run, see Options
+	OutputDir    string
+	NameTemplate string
+}
+
+func newAskCmd() *cobra.Command {
+	opts := &AskOptions{
+		TopK: ask.DefaultTopK,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "ask [flags] question video|transcript|directory...",
+		Short: "Answer a question using one or more transcripts",
+		Long: `Answer a question about recorded meetings. The most relevant transcript passages
+are retrieved and the answer cites them by file name and timestamp.
+With --interactive, follow-up questions can be asked with the conversation history kept.
+The passages are sent to the chat model as they are, without redaction.`,
+		Args: cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.Question = args[0]
+			opts.Paths = args[1:]
+			return runAsk(opts, cmd.InOrStdin(), cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().IntVarP(&opts.TopK, "top", "k", opts.TopK,
+		"Number of transcript passages to send with each question")
+	cmd.Flags().BoolVarP(&opts.Interactive, "interactive", "i", false,
+		"Keep asking follow-up questions read from stdin")
+	addLayoutFlags(cmd.Flags(), &opts.OutputDir, &opts.NameTemplate)
+
+	return cmd
+}
+
+func runAsk(opts *AskOptions, in io.Reader, out io.Writer) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	outputs, err := newLayout(cfg, &Options{Inputs: opts.Paths, OutputDir: opts.OutputDir, NameTemplate: opts.NameTemplate})
+	if err != nil {
+		return err
+	}
+	files, err := transcript.Resolve(opts.Paths, outputs)
+	if err != nil {
+		return err
+	}
+
+	var passages []transcript.Passage
+	for _, file := range files {
+		p, err := transcript.LoadPassages(file, transcript.DefaultPassageWords)
+		if err != nil {
+			return err
+		}
+		passages = append(passages, p...)
+	}
+
+	client, err := summarize.NewOpenAIClient()
+	if err != nil {
+		return fmt.Errorf("failed to initialize chat client: %w", err)
+	}
+	asker := ask.NewAsker(cfg, client, passages, opts.TopK)
+
+	answer, err := asker.Ask(opts.Question)
+	if err != nil {
+		return fmt.Errorf("failed to answer question: %w", err)
+	}
+	fmt.Fprintln(out, answer)
+
+	if !opts.Interactive {
+		return nil
+	}
+
+	// Read follow-up questions until EOF or an exit command
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(out, "\n> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return scanner.Err()
+		}
+		question := strings.TrimSpace(scanner.Text())
+		switch question {
+		case "":
+			continue
+		case "exit", "quit":
+			return nil
+		case "reset":
+			asker.Reset()
+			fmt.Fprintln(out, "Conversation history cleared.")
+			continue
+		}
+
+		answer, err := asker.Ask(question)
+		if err != nil {
+			return fmt.Errorf("failed to answer question: %w", err)
+		}
+		fmt.Fprintln(out, answer)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunAsk(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Set HOME for config loading
+	oldHome := os.Getenv("HOME")
+	os.Setenv("HOME", tmpDir)
+	defer os.Setenv("HOME", oldHome)
+
+	// Set mock OpenAI API key
+	os.Setenv("OPENAI_API_KEY", "test-key")
+	defer os.Unsetenv("OPENAI_API_KEY")
+
+	notesDir := filepath.Join(tmpDir, "notes")
+	os.MkdirAll(notesDir, 0755)
+	if err := os.WriteFile(filepath.Join(notesDir, "standup_transcript.md"), []byte("We decided to ship on Friday."), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	in := strings.NewReader("and who ships it?\nexit\n")
+	opts := &AskOptions{
+		Question:    "Did we decide to ship?",
+		Paths:       []string{notesDir},
+		Interactive: true,
+	}
+	if err := runAsk(opts, in, &out); err != nil {
+		t.Fatalf("runAsk() error = %v", err)
+	}
+	if got := strings.Count(out.String(), "Mock summary"); got != 2 {
+		t.Errorf("expected 2 answers, got %d in output %q", got, out.String())
+	}
+
+	// Directories without transcripts are rejected
+	emptyDir := filepath.Join(tmpDir, "empty")
+	os.MkdirAll(emptyDir, 0755)
+	opts = &AskOptions{Question: "anything?", Paths: []string{emptyDir}}
+	if err := runAsk(opts, strings.NewReader(""), &out); err == nil {
+		t.Error("runAsk() should fail without transcripts")
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"github.com/giantswarm/mnote/internal/config"
+	"github.com/giantswarm/mnote/internal/digest"
+	"github.com/giantswarm/mnote/internal/prompts"
+	"github.com/giantswarm/mnote/internal/summarize"
+	"github.com/giantswarm/mnote/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+// DigestOptions holds the options of the digest command
+type DigestOptions struct {
+	Dirs         []string
+	PromptName   string
+	DigestPrompt string
+	Since        string
+	Until        string
+	OutputPath   string
+
+	// Output locations used by the run, see Options
+	OutputDir    string
+	NameTemplate string
+}
+
+func newDigestCmd() *cobra.Command {
+	opts := &DigestOptions{
+		PromptName:   "summarize",
+		DigestPrompt: "digest",
+	}
+
+	cmd := &cobra.Command{
+		Use:   "digest [flags] directory...",
+		Short: "Roll up the summaries of several meetings into one digest",
+		Long: `Collect the summaries written by mnote in the given directories, optionally limited
+to a date range, and create one digest document that links back to each meeting's notes.
+The summaries are sent to the chat model as they are saved, without redaction.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.Dirs = args
+			return runDigest(opts, cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.PromptName, "prompt", "p", opts.PromptName,
+		"Name of the prompt whose summaries are collected")
+	cmd.Flags().StringVar(&opts.DigestPrompt, "digest-prompt", opts.DigestPrompt,
+		"Name of the prompt to use for the digest")
+	cmd.Flags().StringVar(&opts.Since, "since", "",
+		"Only include meetings on or after this date (YYYY-MM-DD)")
+	cmd.Flags().StringVar(&opts.Until, "until", "",
+		"Only include meetings on or before this date (YYYY-MM-DD)")
+	cmd.Flags().StringVarP(&opts.OutputPath, "output", "o", "",
+		"Path of the digest file (default: digest[_since][_until].md with the outputs of the first directory)")
+	addLayoutFlags(cmd.Flags(), &opts.OutputDir, &opts.NameTemplate)
+
+	return cmd
+}
+
+func runDigest(opts *DigestOptions, out io.Writer) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	var since, until time.Time
+	if opts.Since != "" {
+		if since, err = time.ParseInLocation(digest.DateLayout, opts.Since, time.Local); err != nil {
+			return &usageError{fmt.Sprintf("invalid since date: %s (expected YYYY-MM-DD)", opts.Since)}
+		}
+	}
+	if opts.Until != "" {
+		if until, err = time.ParseInLocation(digest.DateLayout, opts.Until, time.Local); err != nil {
+			return &usageError{fmt.Sprintf("invalid until date: %s (expected YYYY-MM-DD)", opts.Until)}
+		}
+		// Include the whole last day
+		until = until.AddDate(0, 0, 1)
+	}
+
+	prompt, err := prompts.Load(opts.DigestPrompt)
+	if err != nil {
+		return err
+	}
+
+	outputs, err := newLayout(cfg, &Options{Inputs: opts.Dirs, OutputDir: opts.OutputDir, NameTemplate: opts.NameTemplate})
+	if err != nil {
+		return err
+	}
+	meetings, err := digest.Collect(opts.Dirs, opts.PromptName, outputs, since, until)
+	if err != nil {
+		return err
+	}
+	if len(meetings) == 0 {
+		return fmt.Errorf("no %s summaries found in the given range", opts.PromptName)
+	}
+
+	outputPath := opts.OutputPath
+	if outputPath == "" {
+		name := "digest"
+		if opts.Since != "" {
+			name += "_" + opts.Since
+		}
+		if opts.Until != "" {
+			name += "_" + opts.Until
+		}
+		// The digest of the first directory is written where its outputs are
+		outputPath = filepath.Join(outputs.Dir(filepath.Join(opts.Dirs[0], name+".md")), name+".md")
+	}
+
+	client, err := summarize.NewOpenAIClient()
+	if err != nil {
+		return fmt.Errorf("failed to initialize chat client: %w", err)
+	}
+
+	slog.Info("Creating digest", "meetings", len(meetings), "prompt", opts.DigestPrompt)
+	text, err := digest.NewDigester(cfg, client, digest.DefaultMaxChars).Digest(meetings, prompt.Content)
+	if err != nil {
+		return fmt.Errorf("failed to create digest: %w", err)
+	}
+
+	title := "Meeting digest"
+	if opts.Since != "" || opts.Until != "" {
+		title = fmt.Sprintf("Meeting digest %s – %s", opts.Since, opts.Until)
+	}
+	if err := utils.WriteFile(outputPath, []byte(digest.Render(title, text, meetings, outputPath))); err != nil {
+		return fmt.Errorf("failed to save digest: %w", err)
+	}
+	slog.Info("Digest saved", "path", outputPath)
+	fmt.Fprintln(out, outputPath)
+
+	return nil
+}
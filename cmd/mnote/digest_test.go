@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/giantswarm/mnote/internal/utils"
+)
+
+func TestRunDigest(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Set HOME for config loading
+	oldHome := os.Getenv("HOME")
+	os.Setenv("HOME", tmpDir)
+	defer os.Setenv("HOME", oldHome)
+
+	// Set mock OpenAI API key
+	os.Setenv("OPENAI_API_KEY", "test-key")
+	defer os.Unsetenv("OPENAI_API_KEY")
+
+	notesDir := filepath.Join(tmpDir, "notes")
+	os.MkdirAll(notesDir, 0755)
+	os.WriteFile(filepath.Join(notesDir, "standup_summarize.md"), []byte("standup summary"), 0644)
+	os.WriteFile(filepath.Join(notesDir, "planning_summarize.md"), []byte("planning summary"), 0644)
+
+	var out bytes.Buffer
+	if err := runDigest(&DigestOptions{Dirs: []string{notesDir}, PromptName: "summarize", DigestPrompt: "digest"}, &out); err != nil {
+		t.Fatalf("runDigest() error = %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(notesDir, "digest.md"))
+	if err != nil {
+		t.Fatalf("digest file not created: %v", err)
+	}
+	if !strings.Contains(string(content), "[standup](standup_summarize.md)") {
+		t.Errorf("digest does not link to meeting notes: %q", content)
+	}
+
+	// With an output directory the digest is written there, the source directory is left alone
+	outDir := filepath.Join(tmpDir, "out")
+	os.MkdirAll(outDir, 0755)
+	os.WriteFile(filepath.Join(outDir, "standup_summarize.md"), []byte("standup summary"), 0644)
+	os.Remove(filepath.Join(notesDir, "digest.md"))
+	if err := runDigest(&DigestOptions{Dirs: []string{notesDir}, PromptName: "summarize", DigestPrompt: "digest", OutputDir: outDir}, &out); err != nil {
+		t.Fatalf("runDigest() with --output-dir error = %v", err)
+	}
+	if !utils.FileExists(filepath.Join(outDir, "digest.md")) || utils.FileExists(filepath.Join(notesDir, "digest.md")) {
+		t.Errorf("expected the digest in the output directory only")
+	}
+
+	// Invalid dates are usage errors
+	err = runDigest(&DigestOptions{Dirs: []string{notesDir}, PromptName: "summarize", DigestPrompt: "digest", Since: "last week"}, &out)
+	if !isUsageError(err) {
+		t.Errorf("expected usage error for invalid date, got %v", err)
+	}
+
+	// Unknown digest prompts are rejected
+	err = runDigest(&DigestOptions{Dirs: []string{notesDir}, PromptName: "summarize", DigestPrompt: "missing"}, &out)
+	if err == nil {
+		t.Error("expected error for missing digest prompt")
+	}
+}
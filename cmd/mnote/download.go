@@ -0,0 +1,70 @@
+package main
+
+import (
+	"log/slog"
+
+	"github.com/giantswarm/mnote/internal/fetch"
+	"github.com/giantswarm/mnote/internal/progress"
+)
+
+// stageDownload is the progress stage of downloading a recording
+const stageDownload = "download"
+
+// downloadInputs downloads the recordings of URL inputs to the output directory, or the
+// current directory without one, and replaces the URLs with the downloaded files. A dry run
+// does not download, URLs that were not downloaded before are reported and left out.
+func downloadInputs(opts *Options) (inputs []string, pending int, err error) {
+	dir := opts.OutputDir
+	if dir == "" {
+		dir = "."
+	}
+	for _, input := range opts.Inputs {
+		if !fetch.IsURL(input) {
+			inputs = append(inputs, input)
+			continue
+		}
+		if opts.DryRun {
+			if path, ok := fetch.Downloaded(input, dir); ok {
+				inputs = append(inputs, path)
+			} else {
+				slog.Info("Recording would be downloaded", "url", fetch.Display(input))
+				pending++
+			}
+			continue
+		}
+		path, err := download(input, dir)
+		if err != nil {
+			return nil, 0, err
+		}
+		inputs = append(inputs, path)
+	}
+	return inputs, pending, nil
+}
+
+// download downloads a recording into dir and returns its file
+func download(rawURL, dir string) (string, error) {
+	display := fetch.Display(rawURL)
+	task := progress.Default().Start(display, stageDownload, 0, progress.UnitBytes)
+	defer progress.Default().Finish(display)
+	defer task.Done()
+
+	slog.Debug("Downloading recording", "url", display, "dir", dir)
+	result, err := fetch.Download(rawURL, dir, fetch.Options{
+		Progress: func(done, total int64) {
+			task.SetTotal(total)
+			task.Set(done)
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case result.Reused:
+		slog.Info("Recording already downloaded", "url", display, "path", result.Path)
+	case result.Resumed > 0:
+		slog.Info("Recording downloaded", "url", display, "path", result.Path, "bytes", result.Size, "resumed_at", result.Resumed)
+	default:
+		slog.Info("Recording downloaded", "url", display, "path", result.Path, "bytes", result.Size)
+	}
+	return result.Path, nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/mnote/internal/utils"
+)
+
+func TestRunURL(t *testing.T) {
+	tmpDir := t.TempDir()
+	oldHome := os.Getenv("HOME")
+	os.Setenv("HOME", tmpDir)
+	defer os.Setenv("HOME", oldHome)
+	os.Setenv("OPENAI_API_KEY", "test-key")
+	defer os.Unsetenv("OPENAI_API_KEY")
+
+	// One server exports the recording and transcribes it
+	var downloads int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			downloads++
+			w.Header().Set("ETag", `"v1"`)
+			http.ServeContent(w, r, "", time.Time{}, bytes.NewReader([]byte("recording")))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"text": "mock transcription"})
+	}))
+	defer server.Close()
+	os.Setenv("TRANSCRIPTION_API_URL", server.URL)
+	defer os.Unsetenv("TRANSCRIPTION_API_URL")
+
+	utils.SetFFmpegRunner(&utils.MockFFmpegRunner{})
+	defer utils.SetFFmpegRunner(&utils.DefaultFFmpegRunner{})
+
+	outDir := filepath.Join(tmpDir, "notes")
+	url := server.URL + "/recordings/standup.m4a?token=secret"
+	opts := &Options{Inputs: []string{url}, PromptName: "summarize", Language: "en", KeepGoing: true, OutputDir: outDir}
+	if err := run(opts); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	for _, name := range []string{"standup.m4a", "standup_transcript.md", "standup_summarize.md"} {
+		if !utils.FileExists(filepath.Join(outDir, name)) {
+			t.Errorf("expected %s in the output directory", name)
+		}
+	}
+
+	// The next run uses the downloaded recording
+	opts.Inputs = []string{url}
+	if err := run(opts); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	if downloads != 1 {
+		t.Errorf("expected the recording to be downloaded once, got %d downloads", downloads)
+	}
+
+	// A dry run does not download
+	dryRun := &Options{Inputs: []string{server.URL + "/recordings/retro.m4a"}, PromptName: "summarize", Language: "en", OutputDir: outDir, DryRun: true}
+	if err := run(dryRun); err != nil {
+		t.Fatalf("run() with --dry-run error = %v", err)
+	}
+	if downloads != 1 || utils.FileExists(filepath.Join(outDir, "retro.m4a")) {
+		t.Errorf("expected a dry run not to download, got %d downloads", downloads)
+	}
+
+	// Invalid options are reported before anything is downloaded
+	for _, invalid := range []*Options{
+		{Inputs: []string{server.URL + "/recordings/retro.m4a"}, PromptName: "summarize", Language: "en", OutputDir: outDir, Jobs: -1},
+		{Inputs: []string{server.URL + "/recordings/retro.m4a"}, PromptName: "summarize", Language: "en", OutputDir: outDir, NameTemplate: "{{.Title}}.md"},
+	} {
+		if err := run(invalid); !isUsageError(err) {
+			t.Errorf("expected usage error, got %v", err)
+		}
+	}
+	if downloads != 1 {
+		t.Errorf("expected invalid options not to download, got %d downloads", downloads)
+	}
+}
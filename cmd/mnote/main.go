@@ -2,24 +2,66 @@ package main
 
 import (
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/giantswarm/mnote/internal/config"
+	"github.com/giantswarm/mnote/internal/hooks"
+	"github.com/giantswarm/mnote/internal/index"
+	"github.com/giantswarm/mnote/internal/layout"
+	"github.com/giantswarm/mnote/internal/logging"
 	"github.com/giantswarm/mnote/internal/process"
+	"github.com/giantswarm/mnote/internal/progress"
+	"github.com/giantswarm/mnote/internal/prompts"
+	"github.com/giantswarm/mnote/internal/redact"
+	"github.com/giantswarm/mnote/internal/state"
 	"github.com/giantswarm/mnote/internal/summarize"
 	"github.com/giantswarm/mnote/internal/transcribe"
-	"github.com/giantswarm/mnote/internal/utils"
+	"github.com/giantswarm/mnote/internal/verify"
+	"github.com/giantswarm/mnote/internal/version"
+	"github.com/giantswarm/mnote/internal/walk"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 // Options holds the command-line options
 type Options struct {
-	VideoDir     string
+	Inputs       []string
 	PromptName   string
 	Language     string
-	ForceRebuild bool
+	Force        []string
+	Verify       bool
+	MediaLinks   bool
+	Redact       bool
+	RefreshStale bool
+	KeepGoing    bool
+	RetryFailed  bool
+	DryRun       bool
+	WaitLocked   bool
+
+	// Output locations
+	OutputDir    string
+	NameTemplate string
+	KeepAudio    bool
+
+	// Directory traversal
+	Recursive      bool
+	MaxDepth       int
+	Include        []string
+	Exclude        []string
+	FollowSymlinks bool
+
+	// Concurrency, a stage limit of 0 uses the number of jobs
+	Jobs           int
+	ExtractJobs    int
+	TranscribeJobs int
+	SummarizeJobs  int
+
+	// stdin provides the inputs for "-"
+	stdin io.Reader
 }
 
 // usageError represents an error that should trigger usage information
@@ -32,34 +74,159 @@ func (e *usageError) Error() string {
 }
 
 func NewRootCmd() *cobra.Command {
+	logOpts := &logging.Options{Format: logging.FormatText}
+
+	cmd := &cobra.Command{
+		Use:   "mnote",
+		Short: "Process video files to generate transcriptions and summaries",
+		Long: `mnote is a tool for processing video files to generate transcriptions and summaries.
+It supports multiple languages and custom prompts for summarization.
+
+'mnote run' processes videos end to end. The stages can also be run on their own:
+'mnote extract' extracts the audio, 'mnote transcribe' transcribes audio or videos and
+'mnote summarize' summarizes any plain text or markdown transcript.`,
+		Version: version.Version,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// Log lines are written through the progress bars so they do not overwrite each other
+			tracker := progress.New(cmd.ErrOrStderr(), progressMode(*logOpts, cmd.ErrOrStderr()))
+			if err := logging.Setup(*logOpts, tracker); err != nil {
+				return &usageError{err.Error()}
+			}
+			progress.SetDefault(tracker)
+			return nil
+		},
+	}
+
+	// Logging applies to all subcommands, the log is written to stderr
+	cmd.PersistentFlags().BoolVarP(&logOpts.Quiet, "quiet", "q", false,
+		"Only log warnings and errors")
+	cmd.PersistentFlags().BoolVarP(&logOpts.Verbose, "verbose", "v", false,
+		"Log debug details")
+	cmd.PersistentFlags().StringVar(&logOpts.Format, "log-format", logOpts.Format,
+		"Format of the log: text or json")
+	cmd.PersistentFlags().StringVar(&logOpts.File, "log-file", "",
+		"Append the log to this file as well, with --quiet it still receives the progress")
+
+	// Add subcommands
+	cmd.AddCommand(newRunCmd())
+	cmd.AddCommand(newExtractCmd())
+	cmd.AddCommand(newTranscribeCmd())
+	cmd.AddCommand(newSummarizeCmd())
+	cmd.AddCommand(newAskCmd())
+	cmd.AddCommand(newDigestCmd())
+	cmd.AddCommand(newIndexCmd())
+	cmd.AddCommand(newSearchCmd())
+	cmd.AddCommand(newPromptsCmd())
+	cmd.AddCommand(newVerifyCmd())
+	cmd.AddCommand(newStatusCmd())
+	cmd.AddCommand(newWatchCmd())
+
+	return cmd
+}
+
+func newRunCmd() *cobra.Command {
 	opts := &Options{
 		PromptName: "summarize",
 		Language:   "",
+		Jobs:       1,
+		KeepGoing:  true,
 	}
 
 	cmd := &cobra.Command{
-		Use:   "mnote [flags] video-directory",
-		Short: "Process video files to generate transcriptions and summaries",
-		Long: `mnote is a tool for processing video files to generate transcriptions and summaries.
-It supports multiple languages and custom prompts for summarization.`,
-		Args: cobra.ExactArgs(1),
+		Use:   "run [flags] video|audio|directory|glob|url|-...",
+		Short: "Extract, transcribe and summarize videos",
+		Long: `Extract the audio of videos, transcribe it and summarize the transcript. Outputs
+that exist already are kept unless --force is given.
+
+Inputs can be any mix of video and audio files, directories, glob patterns and HTTP(S)
+URLs. With "-" the paths are read from stdin, one per line (find . -name '*.mp4' | mnote run -).
+
+Recordings at URLs are downloaded to the output directory, or the current directory
+without --output-dir, and processed like local files. An interrupted download is resumed
+by the next run.`,
+		Args: cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			opts.VideoDir = args[0]
+			opts.Inputs = args
+			opts.stdin = cmd.InOrStdin()
 			return run(opts)
 		},
 	}
 
 	// Add flags
-	cmd.Flags().StringVarP(&opts.PromptName, "prompt", "p", opts.PromptName,
-		"Name of the prompt file to use for summarization")
-	cmd.Flags().StringVarP(&opts.Language, "language", "l", opts.Language,
-		"Language of the audio (en, de, es, fr, auto)")
-	cmd.Flags().BoolVarP(&opts.ForceRebuild, "force", "f", false,
-		"Force rebuild of transcription and summary")
+	addProcessFlags(cmd.Flags(), opts)
+	addWalkFlags(cmd.Flags(), opts)
+	cmd.Flags().BoolVar(&opts.KeepGoing, "keep-going", opts.KeepGoing,
+		"Continue with the remaining videos when one fails (--keep-going=false stops at the first failure)")
+	cmd.Flags().BoolVar(&opts.RetryFailed, "retry-failed", false,
+		"Only process the videos that failed in the previous run")
+	cmd.Flags().BoolVar(&opts.DryRun, "dry-run", false,
+		"Show what would be done with estimated audio minutes, tokens and cost, without calling any API or writing files")
+	cmd.Flags().IntVarP(&opts.Jobs, "jobs", "j", opts.Jobs,
+		"Number of videos to process concurrently")
+	cmd.Flags().IntVar(&opts.ExtractJobs, "extract-jobs", 0,
+		"Maximum number of concurrent ffmpeg audio extractions (default: --jobs)")
+	cmd.Flags().IntVar(&opts.TranscribeJobs, "transcribe-jobs", 0,
+		"Maximum number of concurrent transcriptions (default: --jobs)")
+	cmd.Flags().IntVar(&opts.SummarizeJobs, "summarize-jobs", 0,
+		"Maximum number of concurrent summarizations (default: --jobs)")
 
 	return cmd
 }
 
+// addProcessFlags adds the flags controlling how a video is processed
+func addProcessFlags(flags *pflag.FlagSet, opts *Options) {
+	flags.StringVarP(&opts.PromptName, "prompt", "p", opts.PromptName,
+		"Name of the prompt to use for summarization (see 'mnote prompts list')")
+	flags.StringVarP(&opts.Language, "language", "l", opts.Language,
+		"Language of the audio (en, de, es, fr, auto)")
+	flags.StringSliceVarP(&opts.Force, "force", "f", nil,
+		"Rebuild outputs even if they exist: audio, transcript, summary or all (the default without a value). Outputs made from a rebuilt one are rebuilt as well, e.g. --force=transcript")
+	flags.Lookup("force").NoOptDefVal = process.ForceAll
+	flags.BoolVar(&opts.RefreshStale, "refresh-stale", false,
+		"Regenerate summaries whose prompt, model or transcript changed since they were created")
+	flags.BoolVar(&opts.Verify, "verify", false,
+		"Check new summaries against the transcript and add a verification section")
+	flags.BoolVar(&opts.MediaLinks, "media-links", false,
+		"Add video links with media fragments (video.mp4#t=754) to timestamp references")
+	flags.BoolVar(&opts.Redact, "redact", false,
+		"Replace personal data in the transcript with placeholders before it is sent to the chat model")
+	flags.BoolVar(&opts.WaitLocked, "wait-locked", false,
+		"Wait for videos being processed by another mnote process instead of skipping them")
+	flags.StringVarP(&opts.OutputDir, "output-dir", "o", "",
+		"Write outputs to this directory, mirroring the source tree, and extracted audio to the cache")
+	flags.StringVar(&opts.NameTemplate, "name-template", "",
+		"Template of the output file names, e.g. '{{.Date}}-{{.Title}}/{{.Kind}}.md' (default: '{{.Title}}_{{.Kind}}.md')")
+	flags.BoolVar(&opts.KeepAudio, "keep-audio", false,
+		"Keep cached audio after the transcript was written")
+}
+
+// addLayoutFlags adds the flags locating the outputs of the inputs, like the ones of the run.
+// -o is left to commands that use it for their own output.
+func addLayoutFlags(flags *pflag.FlagSet, outputDir, nameTemplate *string) {
+	shorthand := "o"
+	if flags.ShorthandLookup(shorthand) != nil {
+		shorthand = ""
+	}
+	flags.StringVarP(outputDir, "output-dir", shorthand, "",
+		"Output directory of the videos, mirroring the source tree (default: next to the videos)")
+	flags.StringVar(nameTemplate, "name-template", "",
+		"Template of the output file names, e.g. '{{.Date}}-{{.Title}}/{{.Kind}}.md' (default: '{{.Title}}_{{.Kind}}.md')")
+}
+
+// addWalkFlags adds the flags selecting the videos in directories
+func addWalkFlags(flags *pflag.FlagSet, opts *Options) {
+	flags.BoolVarP(&opts.Recursive, "recursive", "r", false,
+		"Process videos in subdirectories")
+	flags.IntVar(&opts.MaxDepth, "max-depth", 0,
+		"Maximum number of subdirectory levels to descend, implies --recursive (default: no limit)")
+	flags.StringSliceVar(&opts.Include, "include", nil,
+		"Only process videos matching these gitignore style patterns")
+	flags.StringSliceVar(&opts.Exclude, "exclude", nil,
+		"Skip videos and directories matching these gitignore style patterns")
+	flags.BoolVar(&opts.FollowSymlinks, "follow-symlinks", false,
+		"Descend into symlinked directories")
+}
+
 func run(opts *Options) error {
 	// Load configuration
 	cfg, err := config.LoadConfig()
@@ -67,85 +234,281 @@ func run(opts *Options) error {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
-	// Validate video directory
-	if _, err := os.Stat(opts.VideoDir); os.IsNotExist(err) {
-		return fmt.Errorf("video directory does not exist: %s", opts.VideoDir)
+	// Validate the options before downloading or walking anything
+	if err := opts.validate(cfg); err != nil {
+		return err
+	}
+	processOpts, err := opts.processOptions()
+	if err != nil {
+		return err
+	}
+	walkOpts, err := opts.walkOptions()
+	if err != nil {
+		return err
+	}
+
+	// Download the recordings of URLs, then find the videos to process, or only the ones
+	// that failed last time
+	inputs := opts.Inputs
+	var pending int
+	if opts.Inputs, pending, err = downloadInputs(opts); err != nil {
+		return err
+	}
+	outputs, err := newLayout(cfg, opts)
+	if err != nil {
+		return err
+	}
+	videos, err := findVideos(opts, walkOpts, outputs)
+	if err != nil {
+		return err
+	}
+	if len(videos) == 0 && !opts.RetryFailed && pending == 0 {
+		return fmt.Errorf("no supported video files found in: %s", strings.Join(inputs, ", "))
+	}
+
+	processor, err := newProcessor(cfg, opts, outputs)
+	if err != nil {
+		return err
+	}
+	defer progress.Default().Close()
+	processor.SetLimits(process.Limits{
+		Extract:    opts.ExtractJobs,
+		Transcribe: opts.TranscribeJobs,
+		Summarize:  opts.SummarizeJobs,
+	})
+
+	if len(videos) == 0 {
+		slog.Info("No failed videos to retry")
+		return nil
+	}
+
+	// Only show what would be done
+	if opts.DryRun {
+		plans := processor.PlanVideos(videos, processOpts)
+		if err := process.WritePlan(os.Stdout, plans); err != nil {
+			return fmt.Errorf("failed to write plan: %w", err)
+		}
+		return nil
+	}
+
+	// Process video files
+	slog.Info("Processing videos", "videos", len(videos), "language", opts.Language,
+		"prompt", opts.PromptName, "force", processOpts.Force, "jobs", opts.Jobs)
+
+	results := processor.ProcessVideos(videos, processOpts, process.BatchOptions{
+		Jobs:      opts.Jobs,
+		KeepGoing: opts.KeepGoing,
+	})
+	if err := process.WriteReport(os.Stdout, results); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+
+	failed := process.Failed(results)
+	if len(failed) == 0 {
+		return nil
+	}
+	if !opts.KeepGoing {
+		return fmt.Errorf("failed to process video: %s: %w", failed[0].Path, failed[0].Err)
+	}
+	return fmt.Errorf("%d of %d videos failed, run again with --retry-failed to process them", len(failed), len(results))
+}
+
+// progressMode reports progress as bars on a terminal, as periodic log lines otherwise and
+// not at all with --quiet
+func progressMode(opts logging.Options, w io.Writer) int {
+	if opts.Quiet {
+		return progress.ModeOff
+	}
+	if f, ok := w.(*os.File); ok && opts.Format != logging.FormatJSON {
+		if info, err := f.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+			return progress.ModeBars
+		}
 	}
+	return progress.ModeLog
+}
 
+// validate checks the processing options and fills in defaults from the configuration,
+// without touching the inputs
+func (o *Options) validate(cfg *config.Config) error {
 	// Set default language from config if not specified
-	if opts.Language == "" {
-		opts.Language = cfg.DefaultLanguage
+	if o.Language == "" {
+		o.Language = cfg.DefaultLanguage
+	}
+	if err := validateLanguage(o.Language); err != nil {
+		return err
+	}
+	if _, err := prompts.Load(o.PromptName); err != nil {
+		return err
 	}
 
-	// Validate language
-	validLangs := map[string]bool{
-		"auto": true,
-		"en":   true,
-		"de":   true,
-		"es":   true,
-		"fr":   true,
+	// Validate concurrency
+	if o.Jobs < 0 {
+		return &usageError{fmt.Sprintf("invalid number of jobs: %d (must not be negative)", o.Jobs)}
 	}
-	if !validLangs[opts.Language] {
-		return &usageError{fmt.Sprintf("invalid language: %s (supported: auto, en, de, es, fr)", opts.Language)}
+	if o.ExtractJobs < 0 || o.TranscribeJobs < 0 || o.SummarizeJobs < 0 {
+		return &usageError{"invalid stage job limit: must not be negative"}
 	}
 
-	// Validate prompt file
-	promptDir := filepath.Join(os.Getenv("HOME"), ".config", "mnote", "prompts")
-	promptFile := filepath.Join(promptDir, opts.PromptName)
-	if _, err := os.Stat(promptFile); os.IsNotExist(err) {
-		return fmt.Errorf("prompt file does not exist: %s", promptFile)
+	// Validate the name template, the layout itself depends on the inputs
+	template := o.NameTemplate
+	if template == "" {
+		template = cfg.NameTemplate
+	}
+	if _, err := layout.New(layout.Options{Template: template}); err != nil {
+		return &usageError{err.Error()}
 	}
+	return nil
+}
 
+// newProcessor creates the processor writing to outputs with the optional stages the
+// options enable, the options are checked by validate
+func newProcessor(cfg *config.Config, opts *Options, outputs *layout.Layout) (*process.Processor, error) {
 	// Initialize components
-	var transcriber transcribe.Transcriber
-	var summarizer summarize.Summarizer
-
-	transcriber = transcribe.NewTranscriber(cfg)
-	summarizer, err = summarize.NewSummarizer(cfg)
+	transcriber := transcribe.NewTranscriber(cfg)
+	summarizer, err := summarize.NewSummarizer(cfg)
 	if err != nil {
-		return fmt.Errorf("failed to initialize summarizer: %w", err)
+		return nil, fmt.Errorf("failed to initialize summarizer: %w", err)
 	}
 
 	processor := process.NewProcessor(cfg, transcriber, summarizer)
+	processor.SetLayout(outputs)
 
-	// Process video files in directory
-	fmt.Printf("Processing videos in: %s\n", opts.VideoDir)
-	fmt.Printf("Using language: %s\n", opts.Language)
-	fmt.Printf("Using prompt: %s\n", opts.PromptName)
-	fmt.Printf("Force rebuild: %v\n", opts.ForceRebuild)
+	if opts.Redact {
+		redactor, err := redact.NewRedactor(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize redaction: %w", err)
+		}
+		processor.SetRedactor(redactor)
+	}
 
-	// Create process options
-	processOpts := process.Options{
-		Language:     opts.Language,
-		PromptName:   opts.PromptName,
-		ForceRebuild: opts.ForceRebuild,
+	if opts.Verify {
+		client, err := summarize.NewOpenAIClient()
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize chat client: %w", err)
+		}
+		processor.SetVerifier(verify.NewVerifier(cfg, client))
 	}
 
-	// Process all video files in the directory
-	entries, err := os.ReadDir(opts.VideoDir)
-	if err != nil {
-		return fmt.Errorf("failed to read directory: %w", err)
+	if len(cfg.Hooks) > 0 {
+		processor.SetHooks(hooks.NewRunner(cfg.Hooks))
 	}
 
-	foundVideo := false
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
+	// Keep the search index up to date if an embeddings endpoint is configured
+	if cfg.EmbeddingsAPIURL != "" {
+		indexer, err := index.NewIndexer(index.Path(cfg), cfg.EmbeddingModel, index.NewEmbedder(cfg))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load search index: %w", err)
 		}
-		filePath := filepath.Join(opts.VideoDir, entry.Name())
-		if utils.IsVideoFile(filePath) {
-			foundVideo = true
-			if err := processor.ProcessVideo(filePath, processOpts); err != nil {
-				return fmt.Errorf("failed to process video: %w", err)
+		processor.SetIndexer(indexer)
+	}
+
+	return processor, nil
+}
+
+// validateLanguage checks that the transcription language is supported
+func validateLanguage(lang string) error {
+	validLangs := map[string]bool{
+		"auto": true,
+		"en":   true,
+		"de":   true,
+		"es":   true,
+		"fr":   true,
+	}
+	if !validLangs[lang] {
+		return &usageError{fmt.Sprintf("invalid language: %s (supported: auto, en, de, es, fr)", lang)}
+	}
+	return nil
+}
+
+// processOptions returns the options for processing a single video
+func (o *Options) processOptions() (process.Options, error) {
+	force, err := process.ParseForce(o.Force)
+	if err != nil {
+		return process.Options{}, &usageError{err.Error()}
+	}
+	return process.Options{
+		Language:     o.Language,
+		PromptName:   o.PromptName,
+		Force:        force,
+		Verify:       o.Verify,
+		MediaLinks:   o.MediaLinks,
+		Redact:       o.Redact,
+		RefreshStale: o.RefreshStale,
+		KeepAudio:    o.KeepAudio,
+		WaitLocked:   o.WaitLocked,
+	}, nil
+}
+
+// walkOptions returns the options for finding videos in directories
+func (o *Options) walkOptions() (walk.Options, error) {
+	if o.MaxDepth < 0 {
+		return walk.Options{}, &usageError{fmt.Sprintf("invalid maximum depth: %d (must not be negative)", o.MaxDepth)}
+	}
+	return walk.Options{
+		Recursive:      o.Recursive || o.MaxDepth > 0,
+		MaxDepth:       o.MaxDepth,
+		Include:        o.Include,
+		Exclude:        o.Exclude,
+		FollowSymlinks: o.FollowSymlinks,
+	}, nil
+}
+
+// findVideos returns the videos of the inputs, or the ones that failed in the previous run
+func findVideos(opts *Options, walkOpts walk.Options, outputs *layout.Layout) ([]string, error) {
+	stdin := opts.stdin
+	if stdin == nil {
+		stdin = os.Stdin
+	}
+	videos, err := walk.Resolve(opts.Inputs, stdin, walkOpts)
+	if err != nil {
+		return nil, err
+	}
+	if !opts.RetryFailed {
+		return videos, nil
+	}
+
+	// Keep the videos recorded as failed in the state of their output directory
+	manifests := map[string]*state.Manifest{}
+	var failed []string
+	for _, video := range videos {
+		dir := outputs.Dir(video)
+		if manifests[dir] == nil {
+			if manifests[dir], err = state.Load(dir); err != nil {
+				return nil, err
 			}
 		}
+		if f := manifests[dir].Files[filepath.Base(video)]; f != nil && f.Status == state.StatusFailed {
+			failed = append(failed, video)
+		}
 	}
+	return failed, nil
+}
 
-	if !foundVideo {
-		return fmt.Errorf("no supported video files found in directory: %s", opts.VideoDir)
+// newLayout creates the layout of the outputs. Audio goes to the cache if one is configured
+// or the outputs are written to a separate directory.
+func newLayout(cfg *config.Config, opts *Options) (*layout.Layout, error) {
+	template := opts.NameTemplate
+	if template == "" {
+		template = cfg.NameTemplate
 	}
-
-	return nil
+	audioDir := cfg.AudioCacheDir
+	if audioDir == "" && opts.OutputDir != "" {
+		dir, err := layout.DefaultAudioDir()
+		if err != nil {
+			return nil, err
+		}
+		audioDir = dir
+	}
+	l, err := layout.New(layout.Options{
+		OutputDir: opts.OutputDir,
+		Roots:     walk.Roots(opts.Inputs),
+		Template:  template,
+		AudioDir:  audioDir,
+	})
+	if err != nil {
+		return nil, &usageError{err.Error()}
+	}
+	return l, nil
 }
 
 // isUsageError determines if an error is related to command usage
@@ -160,8 +523,8 @@ func isUsageError(err error) bool {
 }
 
 func main() {
-	cmd := NewRootCmd()
-	if err := cmd.Execute(); err != nil {
+	cmd, err := NewRootCmd().ExecuteC()
+	if err != nil {
 		if isUsageError(err) {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			cmd.Usage()
@@ -19,7 +19,10 @@ func init() {
 }
 
 func TestNewRootCmd(t *testing.T) {
-	cmd := NewRootCmd()
+	cmd, _, err := NewRootCmd().Find([]string{"run"})
+	if err != nil || cmd.Name() != "run" {
+		t.Fatalf("expected run subcommand, got %v", err)
+	}
 
 	// Test default values
 	opts := &Options{}
@@ -30,16 +33,18 @@ func TestNewRootCmd(t *testing.T) {
 		case "language":
 			opts.Language = f.DefValue
 		case "force":
-			val, _ := cmd.Flags().GetBool(f.Name)
-			opts.ForceRebuild = val
+			opts.Force, _ = cmd.Flags().GetStringSlice(f.Name)
 		}
 	})
 
 	if opts.PromptName != "summarize" {
 		t.Errorf("expected default prompt to be 'summarize', got %s", opts.PromptName)
 	}
-	if opts.ForceRebuild {
-		t.Error("expected force rebuild to be false by default")
+	if len(opts.Force) != 0 {
+		t.Error("expected nothing to be rebuilt by default")
+	}
+	if f := cmd.Flags().Lookup("force"); f.NoOptDefVal != "all" {
+		t.Errorf("expected --force without a value to rebuild all outputs, got %q", f.NoOptDefVal)
 	}
 }
 
@@ -124,7 +129,7 @@ chatgpt_model: "gpt-4"
 		{
 			name: "valid options",
 			opts: &Options{
-				VideoDir:   videoDir,
+				Inputs:     []string{videoDir},
 				PromptName: "summarize",
 				Language:   "en",
 			},
@@ -135,7 +140,7 @@ chatgpt_model: "gpt-4"
 		{
 			name: "invalid directory",
 			opts: &Options{
-				VideoDir:   "/nonexistent",
+				Inputs:     []string{"/nonexistent"},
 				PromptName: "summarize",
 				Language:   "en",
 			},
@@ -146,7 +151,7 @@ chatgpt_model: "gpt-4"
 		{
 			name: "invalid language",
 			opts: &Options{
-				VideoDir:   videoDir,
+				Inputs:     []string{videoDir},
 				PromptName: "summarize",
 				Language:   "invalid",
 			},
@@ -157,7 +162,7 @@ chatgpt_model: "gpt-4"
 		{
 			name: "invalid prompt",
 			opts: &Options{
-				VideoDir:   videoDir,
+				Inputs:     []string{videoDir},
 				PromptName: "nonexistent",
 				Language:   "en",
 			},
@@ -202,3 +207,203 @@ chatgpt_model: "gpt-4"
 		})
 	}
 }
+
+func TestRunKeepGoing(t *testing.T) {
+	tmpDir := t.TempDir()
+	oldHome := os.Getenv("HOME")
+	os.Setenv("HOME", tmpDir)
+	defer os.Setenv("HOME", oldHome)
+	os.Setenv("OPENAI_API_KEY", "test-key")
+	defer os.Unsetenv("OPENAI_API_KEY")
+
+	// The first video is already transcribed, extracting the audio of the second one fails
+	videoDir := filepath.Join(tmpDir, "videos")
+	os.MkdirAll(videoDir, 0755)
+	for _, name := range []string{"a", "b"} {
+		os.WriteFile(filepath.Join(videoDir, name+".mp4"), []byte("video"), 0644)
+	}
+	os.WriteFile(filepath.Join(videoDir, "a_transcript.md"), []byte("transcript a"), 0644)
+
+	mockFFmpeg := &utils.MockFFmpegRunner{ForceError: true}
+	utils.SetFFmpegRunner(mockFFmpeg)
+	defer utils.SetFFmpegRunner(&utils.DefaultFFmpegRunner{})
+
+	opts := &Options{Inputs: []string{videoDir}, PromptName: "summarize", Language: "en", KeepGoing: true}
+	err := run(opts)
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 videos failed") {
+		t.Fatalf("expected one failed video, got %v", err)
+	}
+	if !utils.FileExists(filepath.Join(videoDir, "a_summarize.md")) {
+		t.Error("expected the first video to be summarized")
+	}
+
+	// Retrying only processes the failed video, its transcript is added meanwhile so no
+	// transcription server is needed
+	mockFFmpeg.ForceError = false
+	os.WriteFile(filepath.Join(videoDir, "b_transcript.md"), []byte("transcript b"), 0644)
+	os.Remove(filepath.Join(videoDir, "a_summarize.md"))
+	opts.RetryFailed = true
+	if err := run(opts); err != nil {
+		t.Fatalf("run() with --retry-failed error = %v", err)
+	}
+	if utils.FileExists(filepath.Join(videoDir, "a_summarize.md")) {
+		t.Error("expected the succeeded video not to be processed again")
+	}
+	if !utils.FileExists(filepath.Join(videoDir, "b_summarize.md")) {
+		t.Error("expected the failed video to be summarized")
+	}
+
+	// Nothing is left to retry
+	if err := run(opts); err != nil {
+		t.Fatalf("run() with nothing to retry error = %v", err)
+	}
+}
+
+func TestRunRecursive(t *testing.T) {
+	tmpDir := t.TempDir()
+	oldHome := os.Getenv("HOME")
+	os.Setenv("HOME", tmpDir)
+	defer os.Setenv("HOME", oldHome)
+	os.Setenv("OPENAI_API_KEY", "test-key")
+	defer os.Unsetenv("OPENAI_API_KEY")
+
+	// Already transcribed videos in nested directories, one of them ignored
+	videoDir := filepath.Join(tmpDir, "videos")
+	for _, rel := range []string{"2024/01/team/standup", "2024/01/team/skip"} {
+		base := filepath.Join(videoDir, filepath.FromSlash(rel))
+		os.MkdirAll(filepath.Dir(base), 0755)
+		os.WriteFile(base+".mp4", []byte("video"), 0644)
+		os.WriteFile(base+"_audio.mp3", []byte("audio"), 0644)
+		os.WriteFile(base+"_transcript.md", []byte("transcript"), 0644)
+	}
+	os.WriteFile(filepath.Join(videoDir, ".mnoteignore"), []byte("skip.mp4\n"), 0644)
+
+	opts := &Options{Inputs: []string{videoDir}, PromptName: "summarize", Language: "en", KeepGoing: true}
+	if err := run(opts); err == nil {
+		t.Fatal("expected no videos to be found without --recursive")
+	}
+
+	opts.Recursive = true
+	if err := run(opts); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	teamDir := filepath.Join(videoDir, "2024", "01", "team")
+	if !utils.FileExists(filepath.Join(teamDir, "standup_summarize.md")) {
+		t.Error("expected summary next to the nested video")
+	}
+	if utils.FileExists(filepath.Join(teamDir, "skip_summarize.md")) {
+		t.Error("expected ignored video not to be processed")
+	}
+}
+
+func TestRunDryRun(t *testing.T) {
+	tmpDir := t.TempDir()
+	oldHome := os.Getenv("HOME")
+	os.Setenv("HOME", tmpDir)
+	defer os.Setenv("HOME", oldHome)
+	os.Setenv("OPENAI_API_KEY", "test-key")
+	defer os.Unsetenv("OPENAI_API_KEY")
+
+	videoDir := filepath.Join(tmpDir, "videos")
+	os.MkdirAll(videoDir, 0755)
+	os.WriteFile(filepath.Join(videoDir, "meeting.mp4"), []byte("video"), 0644)
+
+	mockFFmpeg := &utils.MockFFmpegRunner{}
+	utils.SetFFmpegRunner(mockFFmpeg)
+	defer utils.SetFFmpegRunner(&utils.DefaultFFmpegRunner{})
+
+	opts := &Options{Inputs: []string{videoDir}, PromptName: "summarize", Language: "en", DryRun: true}
+	if err := run(opts); err != nil {
+		t.Fatalf("run() with --dry-run error = %v", err)
+	}
+	entries, _ := os.ReadDir(videoDir)
+	if len(entries) != 1 || mockFFmpeg.ExtractCalled {
+		t.Errorf("expected a dry run not to extract audio or write files, got %d files", len(entries))
+	}
+}
+
+func TestRunOutputDir(t *testing.T) {
+	tmpDir := t.TempDir()
+	oldHome := os.Getenv("HOME")
+	os.Setenv("HOME", tmpDir)
+	defer os.Setenv("HOME", oldHome)
+	os.Setenv("OPENAI_API_KEY", "test-key")
+	defer os.Unsetenv("OPENAI_API_KEY")
+	cacheDir := filepath.Join(tmpDir, "cache")
+	os.Setenv("XDG_CACHE_HOME", cacheDir)
+	defer os.Unsetenv("XDG_CACHE_HOME")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"text": "mock transcription"})
+	}))
+	defer server.Close()
+	os.Setenv("TRANSCRIPTION_API_URL", server.URL)
+	defer os.Unsetenv("TRANSCRIPTION_API_URL")
+
+	utils.SetFFmpegRunner(&utils.MockFFmpegRunner{})
+	defer utils.SetFFmpegRunner(&utils.DefaultFFmpegRunner{})
+
+	// The share with the recordings is not written to
+	videoDir := filepath.Join(tmpDir, "nas")
+	os.MkdirAll(filepath.Join(videoDir, "team"), 0755)
+	os.WriteFile(filepath.Join(videoDir, "team", "standup.mp4"), []byte("video"), 0644)
+	os.Chmod(filepath.Join(videoDir, "team"), 0555)
+	defer os.Chmod(filepath.Join(videoDir, "team"), 0755)
+
+	outDir := filepath.Join(tmpDir, "notes")
+	opts := &Options{
+		Inputs:       []string{videoDir},
+		PromptName:   "summarize",
+		Language:     "en",
+		Recursive:    true,
+		KeepGoing:    true,
+		OutputDir:    outDir,
+		NameTemplate: "{{.Title}}/{{.Kind}}.md",
+	}
+	if err := run(opts); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	for _, name := range []string{"transcript.md", "summarize.md"} {
+		if !utils.FileExists(filepath.Join(outDir, "team", "standup", name)) {
+			t.Errorf("expected %s in the mirrored output directory", name)
+		}
+	}
+	entries, _ := os.ReadDir(filepath.Join(videoDir, "team"))
+	if len(entries) != 1 {
+		t.Errorf("expected nothing to be written next to the video, got %d files", len(entries))
+	}
+
+	// The cached audio was removed once the transcript was written
+	cached, _ := filepath.Glob(filepath.Join(cacheDir, "mnote", "audio", "*.mp3"))
+	if len(cached) != 0 {
+		t.Errorf("expected cached audio to be cleaned up, got %v", cached)
+	}
+
+	// The state is kept in the output directory
+	var out strings.Builder
+	statusOpts := &StatusOptions{Dirs: []string{videoDir}, PromptName: "summarize", Recursive: true, OutputDir: outDir, NameTemplate: opts.NameTemplate}
+	if err := runStatus(statusOpts, &out); err != nil {
+		t.Fatalf("runStatus() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "succeeded") || !strings.Contains(out.String(), "current") {
+		t.Errorf("expected a succeeded run with a current summary, got %s", out.String())
+	}
+}
+
+func TestRunInvalidForce(t *testing.T) {
+	tmpDir := t.TempDir()
+	oldHome := os.Getenv("HOME")
+	os.Setenv("HOME", tmpDir)
+	defer os.Setenv("HOME", oldHome)
+	os.Setenv("OPENAI_API_KEY", "test-key")
+	defer os.Unsetenv("OPENAI_API_KEY")
+
+	videoPath := filepath.Join(tmpDir, "test.mp4")
+	os.WriteFile(videoPath, []byte("video"), 0644)
+
+	opts := &Options{Inputs: []string{videoPath}, PromptName: "summarize", Language: "en", Force: []string{"transcript,video"}}
+	err := run(opts)
+	if !isUsageError(err) || !strings.Contains(err.Error(), "invalid output to rebuild: video") {
+		t.Errorf("expected a usage error for the invalid output, got %v", err)
+	}
+}
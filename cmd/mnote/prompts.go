@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/giantswarm/mnote/internal/prompts"
+	"github.com/giantswarm/mnote/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+func newPromptsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prompts",
+		Short: "Manage summarization prompts",
+		Long: `Manage the prompts used for summarization. Built-in prompts ship with mnote,
+user prompts are stored in ~/.config/mnote/prompts and override built-in prompts of the same name.`,
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List available prompts with their descriptions",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPromptsList(cmd.OutOrStdout())
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "show name",
+		Short: "Print a prompt",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			p, err := prompts.Load(args[0])
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), p.Content)
+			return nil
+		},
+	})
+
+	var from string
+	var edit bool
+	newCmd := &cobra.Command{
+		Use:   "new [flags] name",
+		Short: "Create a new user prompt",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := runPromptsNew(args[0], from, cmd.OutOrStdout()); err != nil {
+				return err
+			}
+			if edit {
+				return editPrompt(args[0], cmd.OutOrStdout())
+			}
+			return nil
+		},
+	}
+	newCmd.Flags().StringVar(&from, "from", "",
+		"Name of a built-in prompt to start from")
+	newCmd.Flags().BoolVarP(&edit, "edit", "e", false,
+		"Open the new prompt in $EDITOR")
+	cmd.AddCommand(newCmd)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "edit name",
+		Short: "Open a user prompt in $EDITOR, copying the built-in prompt first if needed",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return editPrompt(args[0], cmd.OutOrStdout())
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "validate [name...]",
+		Short: "Check user prompts for problems",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPromptsValidate(args, cmd.OutOrStdout())
+		},
+	})
+
+	return cmd
+}
+
+func runPromptsList(out io.Writer) error {
+	list, err := prompts.List()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSOURCE\tDESCRIPTION")
+	for _, p := range list {
+		source := p.Source
+		if p.Overrides {
+			source += " (overrides builtin)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", p.Name, source, p.Description)
+	}
+	return w.Flush()
+}
+
+func runPromptsNew(name, from string, out io.Writer) error {
+	if err := prompts.ValidateName(name); err != nil {
+		return &usageError{err.Error()}
+	}
+
+	path := prompts.Path(name)
+	if utils.FileExists(path) {
+		return fmt.Errorf("prompt already exists: %s", path)
+	}
+
+	content := prompts.Template("")
+	if from != "" {
+		raw, err := prompts.RawBuiltin(from)
+		if err != nil {
+			return err
+		}
+		content = raw
+	}
+
+	if err := utils.WriteFile(path, []byte(content)); err != nil {
+		return fmt.Errorf("failed to create prompt: %w", err)
+	}
+	fmt.Fprintf(out, "Prompt created: %s\n", path)
+	return nil
+}
+
+func editPrompt(name string, out io.Writer) error {
+	if err := prompts.ValidateName(name); err != nil {
+		return &usageError{err.Error()}
+	}
+
+	// Start from the built-in prompt if there is no user file yet
+	path := prompts.Path(name)
+	if !utils.FileExists(path) {
+		raw, err := prompts.RawBuiltin(name)
+		if err != nil {
+			return fmt.Errorf("prompt does not exist: %s (use 'mnote prompts new %s')", name, name)
+		}
+		if err := utils.WriteFile(path, []byte(raw)); err != nil {
+			return fmt.Errorf("failed to create prompt: %w", err)
+		}
+	}
+
+	editor := os.Getenv("EDITOR")
+	if editor == "" {
+		editor = "vi"
+	}
+	fields := strings.Fields(editor)
+	editCmd := exec.Command(fields[0], append(fields[1:], path)...)
+	editCmd.Stdin = os.Stdin
+	editCmd.Stdout = os.Stdout
+	editCmd.Stderr = os.Stderr
+	if err := editCmd.Run(); err != nil {
+		return fmt.Errorf("editor failed: %w", err)
+	}
+
+	return runPromptsValidate([]string{name}, out)
+}
+
+func runPromptsValidate(names []string, out io.Writer) error {
+	if len(names) == 0 {
+		list, err := prompts.List()
+		if err != nil {
+			return err
+		}
+		for _, p := range list {
+			if p.Source == prompts.SourceUser {
+				names = append(names, p.Name)
+			}
+		}
+	}
+
+	invalid := 0
+	for _, name := range names {
+		var raw []byte
+		var err error
+		if utils.FileExists(prompts.Path(name)) {
+			raw, err = utils.ReadFile(prompts.Path(name))
+		} else {
+			var content string
+			content, err = prompts.RawBuiltin(name)
+			raw = []byte(content)
+		}
+		if err != nil {
+			return fmt.Errorf("prompt does not exist: %s", name)
+		}
+
+		errs, warnings := prompts.Validate(string(raw))
+		for _, warning := range warnings {
+			fmt.Fprintf(out, "%s: warning: %s\n", name, warning)
+		}
+		for _, e := range errs {
+			fmt.Fprintf(out, "%s: error: %s\n", name, e)
+		}
+		if len(errs) > 0 {
+			invalid++
+		} else {
+			fmt.Fprintf(out, "%s: ok\n", name)
+		}
+	}
+
+	if invalid > 0 {
+		return fmt.Errorf("%d invalid prompts", invalid)
+	}
+	return nil
+}
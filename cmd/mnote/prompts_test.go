@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPromptsCommands(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Set HOME for prompt loading
+	oldHome := os.Getenv("HOME")
+	os.Setenv("HOME", tmpDir)
+	defer os.Setenv("HOME", oldHome)
+
+	var out bytes.Buffer
+	if err := runPromptsNew("weekly", "standup", &out); err != nil {
+		t.Fatalf("runPromptsNew() error = %v", err)
+	}
+	promptFile := filepath.Join(tmpDir, ".config", "mnote", "prompts", "weekly")
+	if content, err := os.ReadFile(promptFile); err != nil || !strings.Contains(string(content), "standup") {
+		t.Errorf("expected prompt file copied from standup, got %q, %v", content, err)
+	}
+	if err := runPromptsNew("weekly", "", &out); err == nil {
+		t.Error("runPromptsNew() should fail for existing prompt")
+	}
+	if err := runPromptsNew("../weekly", "", &out); !isUsageError(err) {
+		t.Errorf("runPromptsNew() should reject invalid names, got %v", err)
+	}
+
+	out.Reset()
+	if err := runPromptsList(&out); err != nil {
+		t.Fatalf("runPromptsList() error = %v", err)
+	}
+	for _, want := range []string{"weekly", "retrospective", "Daily standup notes"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("prompt list does not contain %q: %s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	if err := runPromptsValidate(nil, &out); err != nil {
+		t.Errorf("runPromptsValidate() error = %v", err)
+	}
+	os.WriteFile(promptFile, []byte("---\ndescription: broken"), 0644)
+	if err := runPromptsValidate([]string{"weekly"}, &out); err == nil {
+		t.Error("runPromptsValidate() should fail for broken prompt")
+	}
+
+	// Editing a built-in prompt copies it to the user directory first
+	os.Setenv("EDITOR", "true")
+	defer os.Unsetenv("EDITOR")
+	if err := editPrompt("lecture", &out); err != nil {
+		t.Fatalf("editPrompt() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, ".config", "mnote", "prompts", "lecture")); err != nil {
+		t.Errorf("expected lecture prompt copied for editing: %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/giantswarm/mnote/internal/config"
+	"github.com/giantswarm/mnote/internal/index"
+	"github.com/giantswarm/mnote/internal/transcript"
+	"github.com/spf13/cobra"
+)
+
+// IndexOptions holds the options of the index command
+type IndexOptions struct {
+	Paths []string
+
+	// Output locations used by the run, see Options
+	OutputDir    string
+	NameTemplate string
+}
+
+// SearchOptions holds the options of the search command
+type SearchOptions struct {
+	Query string
+	Limit int
+}
+
+func newIndexCmd() *cobra.Command {
+	opts := &IndexOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "index [flags] video|transcript|directory...",
+		Short: "Add transcripts to the search index",
+		Long: `Embed the transcripts of the given videos, transcript files or directories and store
+them in the local search index. Transcripts that did not change since they were indexed are skipped,
+and transcripts that no longer exist are removed from the index. The transcripts are sent to the
+embeddings endpoint as they are, without redaction.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.Paths = args
+			return runIndex(opts, cmd.OutOrStdout())
+		},
+	}
+
+	addLayoutFlags(cmd.Flags(), &opts.OutputDir, &opts.NameTemplate)
+
+	return cmd
+}
+
+func newSearchCmd() *cobra.Command {
+	opts := &SearchOptions{
+		Limit: 10,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "search [flags] query",
+		Short: "Search the transcript archive by meaning",
+		Long: `Search the local transcript index for passages that are semantically similar to the query.
+Results are ranked by score and show the transcript file and timestamp of each passage.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.Query = strings.Join(args, " ")
+			return runSearch(opts, cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().IntVarP(&opts.Limit, "limit", "n", opts.Limit,
+		"Maximum number of passages to show")
+
+	return cmd
+}
+
+func runIndex(opts *IndexOptions, out io.Writer) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	outputs, err := newLayout(cfg, &Options{Inputs: opts.Paths, OutputDir: opts.OutputDir, NameTemplate: opts.NameTemplate})
+	if err != nil {
+		return err
+	}
+	files, err := transcript.Resolve(opts.Paths, outputs)
+	if err != nil {
+		return err
+	}
+
+	indexer, err := index.NewIndexer(index.Path(cfg), cfg.EmbeddingModel, index.NewEmbedder(cfg))
+	if err != nil {
+		return fmt.Errorf("failed to load search index: %w", err)
+	}
+
+	indexed := 0
+	for _, file := range files {
+		changed, err := indexer.IndexTranscript(file)
+		if err != nil {
+			return fmt.Errorf("failed to index %s: %w", file, err)
+		}
+		if changed {
+			indexed++
+			slog.Info("Indexed transcript", "file", file)
+			fmt.Fprintln(out, file)
+		}
+	}
+
+	removed := 0
+	err = indexer.Index().Commit(func(latest *index.Index) bool {
+		removed = latest.Prune()
+		return removed > 0
+	})
+	if err != nil {
+		return err
+	}
+	if removed > 0 {
+		slog.Info("Removed missing transcripts from the index", "removed", removed)
+	}
+	slog.Info("Index updated", "updated", indexed, "transcripts", len(files))
+
+	return nil
+}
+
+func runSearch(opts *SearchOptions, out io.Writer) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	idx, err := index.Load(index.Path(cfg), cfg.EmbeddingModel)
+	if err != nil {
+		return fmt.Errorf("failed to load search index: %w", err)
+	}
+	if len(idx.Files) == 0 {
+		return fmt.Errorf("search index is empty, run 'mnote index' first")
+	}
+
+	vectors, err := index.NewEmbedder(cfg).Embed([]string{opts.Query})
+	if err != nil {
+		return fmt.Errorf("failed to embed query: %w", err)
+	}
+
+	for _, r := range idx.Search(vectors[0], opts.Limit) {
+		location := r.Passage.File
+		if ts := r.Passage.Timestamp(); ts != "" {
+			location += " @ " + ts
+		}
+		fmt.Fprintf(out, "%.3f  %s\n       %s\n", r.Score, location, r.Passage.Text)
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunIndexAndSearch(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Set HOME for config loading
+	oldHome := os.Getenv("HOME")
+	os.Setenv("HOME", tmpDir)
+	defer os.Setenv("HOME", oldHome)
+
+	// Embed texts by whether they mention the database
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Input []string `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		var data []map[string]interface{}
+		for n, text := range req.Input {
+			vector := []float32{0, 1}
+			if strings.Contains(strings.ToLower(text), "database") {
+				vector = []float32{1, 0}
+			}
+			data = append(data, map[string]interface{}{"index": n, "embedding": vector})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+	defer server.Close()
+
+	configDir := filepath.Join(tmpDir, ".config", "mnote")
+	os.MkdirAll(configDir, 0755)
+	os.WriteFile(filepath.Join(configDir, "config"), []byte("EMBEDDINGS_API_URL="+server.URL+"\n"), 0644)
+
+	notesDir := filepath.Join(tmpDir, "notes")
+	os.MkdirAll(notesDir, 0755)
+	os.WriteFile(filepath.Join(notesDir, "standup_transcript.md"), []byte("The deploy went fine."), 0644)
+	os.WriteFile(filepath.Join(notesDir, "planning_transcript.md"), []byte("We migrate the database."), 0644)
+
+	var out bytes.Buffer
+	if err := runSearch(&SearchOptions{Query: "db", Limit: 1}, &out); err == nil {
+		t.Error("runSearch() should fail with an empty index")
+	}
+
+	if err := runIndex(&IndexOptions{Paths: []string{notesDir}}, &out); err != nil {
+		t.Fatalf("runIndex() error = %v", err)
+	}
+	// Only the paths of the indexed transcripts go to stdout
+	if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); len(lines) != 2 || !strings.HasSuffix(lines[0], "_transcript.md") {
+		t.Errorf("unexpected index output: %q", out.String())
+	}
+
+	out.Reset()
+	if err := runSearch(&SearchOptions{Query: "database migration", Limit: 1}, &out); err != nil {
+		t.Fatalf("runSearch() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "planning_transcript.md") || strings.Contains(out.String(), "standup") {
+		t.Errorf("unexpected search output: %q", out.String())
+	}
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+
+	"github.com/giantswarm/mnote/internal/config"
+	"github.com/giantswarm/mnote/internal/layout"
+	"github.com/giantswarm/mnote/internal/lock"
+	"github.com/giantswarm/mnote/internal/process"
+	"github.com/giantswarm/mnote/internal/prompts"
+	"github.com/giantswarm/mnote/internal/redact"
+	"github.com/giantswarm/mnote/internal/summarize"
+	"github.com/giantswarm/mnote/internal/transcribe"
+	"github.com/giantswarm/mnote/internal/utils"
+	"github.com/giantswarm/mnote/internal/verify"
+	"github.com/spf13/cobra"
+)
+
+// ExtractOptions holds the options of the extract command
+type ExtractOptions struct {
+	Paths []string
+	Force bool
+
+	// Output locations used by the run, see Options
+	OutputDir    string
+	NameTemplate string
+}
+
+// TranscribeOptions holds the options of the transcribe command
+type TranscribeOptions struct {
+	Paths    []string
+	Language string
+	Force    bool
+
+	// Output locations used by the run, see Options
+	OutputDir    string
+	NameTemplate string
+}
+
+// SummarizeOptions holds the options of the summarize command
+type SummarizeOptions struct {
+	Paths      []string
+	PromptName string
+	Output     string
+	Force      bool
+	Verify     bool
+	Redact     bool
+
+	// Output locations used by the run, see Options
+	OutputDir    string
+	NameTemplate string
+}
+
+func newExtractCmd() *cobra.Command {
+	opts := &ExtractOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "extract [flags] video|audio...",
+		Short: "Extract the audio of videos or convert audio files",
+		Long: `Extract the audio of videos, or convert audio files, to <name>_audio.mp3 next to each
+input, or to the audio cache with --output-dir. Existing audio files are kept unless
+--force is given.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.Paths = args
+			return runExtract(opts, cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().BoolVarP(&opts.Force, "force", "f", false,
+		"Extract the audio again if the audio file exists")
+	addLayoutFlags(cmd.Flags(), &opts.OutputDir, &opts.NameTemplate)
+
+	return cmd
+}
+
+func runExtract(opts *ExtractOptions, out io.Writer) error {
+	for _, path := range opts.Paths {
+		if !utils.IsMediaFile(path) {
+			return &usageError{fmt.Sprintf("not a supported video or audio file: %s", path)}
+		}
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	outputs, err := newLayout(cfg, &Options{Inputs: opts.Paths, OutputDir: opts.OutputDir, NameTemplate: opts.NameTemplate})
+	if err != nil {
+		return err
+	}
+
+	for _, path := range opts.Paths {
+		audioPath := outputs.Audio(path)
+		if !opts.Force && utils.FileExists(audioPath) {
+			slog.Info("Audio file already exists", "file", path, "path", audioPath)
+			continue
+		}
+		if _, err := utils.ExtractAudioTo(path, audioPath, opts.Force); err != nil {
+			return fmt.Errorf("failed to extract audio from %s: %w", path, err)
+		}
+		slog.Info("Audio saved", "file", path, "path", audioPath)
+		fmt.Fprintln(out, audioPath)
+	}
+	return nil
+}
+
+func newTranscribeCmd() *cobra.Command {
+	opts := &TranscribeOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "transcribe [flags] video|audio...",
+		Short: "Transcribe audio files or the audio of videos",
+		Long: `Transcribe audio files, or videos whose audio is extracted first, to a transcript next
+to each input, or where --output-dir and --name-template put it. Audio files in a format
+the transcription backend does not accept are converted first. Existing transcripts are
+kept unless --force is given.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.Paths = args
+			return runTranscribe(opts, cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Language, "language", "l", "",
+		"Language of the audio (en, de, es, fr, auto)")
+	cmd.Flags().BoolVarP(&opts.Force, "force", "f", false,
+		"Transcribe again if the transcript exists")
+	addLayoutFlags(cmd.Flags(), &opts.OutputDir, &opts.NameTemplate)
+
+	return cmd
+}
+
+func runTranscribe(opts *TranscribeOptions, out io.Writer) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if opts.Language == "" {
+		opts.Language = cfg.DefaultLanguage
+	}
+	if err := validateLanguage(opts.Language); err != nil {
+		return err
+	}
+	for _, path := range opts.Paths {
+		if !utils.IsMediaFile(path) {
+			return &usageError{fmt.Sprintf("not a supported video or audio file: %s", path)}
+		}
+	}
+
+	outputs, err := newLayout(cfg, &Options{Inputs: opts.Paths, OutputDir: opts.OutputDir, NameTemplate: opts.NameTemplate})
+	if err != nil {
+		return err
+	}
+
+	transcriber := transcribe.NewTranscriber(cfg)
+	processor := process.NewProcessor(cfg, transcriber, nil)
+	processor.SetLayout(outputs)
+	for _, path := range opts.Paths {
+		if err := transcribeSource(processor, transcriber, outputs, path, opts, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// transcribeSource transcribes one video or audio file under the lock mnote run takes, files
+// being processed by another mnote process are skipped
+func transcribeSource(processor *process.Processor, transcriber transcribe.Transcriber, outputs *layout.Layout, path string, opts *TranscribeOptions, out io.Writer) error {
+	unlock, err := processor.LockSource(path, process.Options{})
+	var locked *lock.LockedError
+	if errors.As(err, &locked) {
+		slog.Info("Skipping file being processed by another mnote process", "file", path, "holder", locked.Holder.String())
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+	defer unlock()
+
+	transcriptPath := outputs.Transcript(path)
+	if !opts.Force && utils.FileExists(transcriptPath) {
+		slog.Info("Transcript file already exists", "file", path, "path", transcriptPath)
+		return nil
+	}
+
+	// Videos and audio the backend does not accept are transcribed from an extracted audio
+	// file, which is reused if it exists
+	audioPath := path
+	if utils.ProbeMedia(path).NeedsTranscode() {
+		if audioPath, err = utils.ExtractAudioTo(path, outputs.Audio(path), false); err != nil {
+			return fmt.Errorf("failed to extract audio from %s: %w", path, err)
+		}
+	}
+
+	result, err := transcriber.TranscribeAudio(audioPath, opts.Language)
+	if err != nil {
+		return fmt.Errorf("failed to transcribe %s: %w", path, err)
+	}
+	if err := process.SaveTranscript(transcriptPath, result); err != nil {
+		return err
+	}
+	slog.Info("Transcript saved", "file", path, "path", transcriptPath)
+	fmt.Fprintln(out, transcriptPath)
+	return nil
+}
+
+func newSummarizeCmd() *cobra.Command {
+	opts := &SummarizeOptions{
+		PromptName: "summarize",
+	}
+
+	cmd := &cobra.Command{
+		Use:   "summarize [flags] transcript...",
+		Short: "Summarize plain text or markdown transcripts",
+		Long: `Summarize transcripts with a prompt, without touching audio or video. Any plain text
+or markdown file can be summarized, for example a transcript from a meeting tool.
+
+The summary of name_transcript.md is written to name_<prompt>.md, the summary of
+other files to <file name>_<prompt>.md next to them. With --output-dir and
+--name-template, summaries are named like the ones of the run. Existing summaries are
+kept unless --force is given.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.Paths = args
+			return runSummarize(opts, cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.PromptName, "prompt", "p", opts.PromptName,
+		"Name of the prompt to use for summarization (see 'mnote prompts list')")
+	cmd.Flags().StringVarP(&opts.Output, "output", "o", "",
+		"Path of the summary, only with a single transcript")
+	cmd.Flags().BoolVarP(&opts.Force, "force", "f", false,
+		"Summarize again if the summary exists")
+	cmd.Flags().BoolVar(&opts.Verify, "verify", false,
+		"Check the summary against the transcript and add a verification section")
+	cmd.Flags().BoolVar(&opts.Redact, "redact", false,
+		"Replace sensitive values in the transcript with placeholders before it is sent")
+	addLayoutFlags(cmd.Flags(), &opts.OutputDir, &opts.NameTemplate)
+
+	return cmd
+}
+
+func runSummarize(opts *SummarizeOptions, out io.Writer) error {
+	if opts.Output != "" && len(opts.Paths) > 1 {
+		return &usageError{"--output can only be used with a single transcript"}
+	}
+	for _, path := range opts.Paths {
+		if !isTextFile(path) {
+			return &usageError{fmt.Sprintf("not a plain text or markdown file: %s", path)}
+		}
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if _, err := prompts.Load(opts.PromptName); err != nil {
+		return err
+	}
+	summarizer, err := summarize.NewSummarizer(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to initialize summarizer: %w", err)
+	}
+	outputs, err := newLayout(cfg, &Options{Inputs: opts.Paths, OutputDir: opts.OutputDir, NameTemplate: opts.NameTemplate})
+	if err != nil {
+		return err
+	}
+	processor := process.NewProcessor(cfg, nil, summarizer)
+	processor.SetLayout(outputs)
+	if opts.Redact {
+		redactor, err := redact.NewRedactor(cfg)
+		if err != nil {
+			return fmt.Errorf("failed to initialize redaction: %w", err)
+		}
+		processor.SetRedactor(redactor)
+	}
+	if opts.Verify {
+		client, err := summarize.NewOpenAIClient()
+		if err != nil {
+			return fmt.Errorf("failed to initialize chat client: %w", err)
+		}
+		processor.SetVerifier(verify.NewVerifier(cfg, client))
+	}
+
+	processOpts := process.Options{PromptName: opts.PromptName, Force: process.Force{process.ForceSummary: opts.Force}, Verify: opts.Verify, Redact: opts.Redact}
+	for _, path := range opts.Paths {
+		summaryPath := opts.Output
+		if summaryPath == "" {
+			summaryPath = summaryPathFor(outputs, path, opts.PromptName)
+		}
+		if !opts.Force && utils.FileExists(summaryPath) {
+			slog.Info("Summary file already exists", "file", path, "path", summaryPath)
+			continue
+		}
+		if err := processor.SummarizeFile(path, summaryPath, processOpts); err != nil {
+			return fmt.Errorf("failed to summarize %s: %w", path, err)
+		}
+		slog.Info("Summary saved", "file", path, "path", summaryPath)
+		fmt.Fprintln(out, summaryPath)
+	}
+	return nil
+}
+
+// summaryPathFor returns the summary path of a transcript, transcripts written by mnote
+// get the same summary path as their video
+func summaryPathFor(outputs *layout.Layout, transcriptPath, promptName string) string {
+	if summaryPath, ok := outputs.Sibling(transcriptPath, layout.KindTranscript, promptName, ".md"); ok {
+		return summaryPath
+	}
+	return outputs.Summary(transcriptPath, promptName)
+}
+
+// isTextFile reports whether the file is a plain text or markdown file
+func isTextFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".txt", ".md", ".markdown":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/giantswarm/mnote/internal/lock"
+	"github.com/giantswarm/mnote/internal/provenance"
+	"github.com/giantswarm/mnote/internal/state"
+	"github.com/giantswarm/mnote/internal/utils"
+)
+
+func TestRunExtract(t *testing.T) {
+	tmpDir := t.TempDir()
+	videoPath := filepath.Join(tmpDir, "meeting.mp4")
+	os.WriteFile(videoPath, []byte("video"), 0644)
+
+	mockFFmpeg := &utils.MockFFmpegRunner{}
+	utils.SetFFmpegRunner(mockFFmpeg)
+	defer utils.SetFFmpegRunner(&utils.DefaultFFmpegRunner{})
+
+	var out bytes.Buffer
+	if err := runExtract(&ExtractOptions{Paths: []string{videoPath}}, &out); err != nil {
+		t.Fatalf("runExtract() error = %v", err)
+	}
+	if !utils.FileExists(filepath.Join(tmpDir, "meeting_audio.mp3")) {
+		t.Error("expected audio file next to the video")
+	}
+
+	// Existing audio is kept
+	mockFFmpeg.ExtractCalled = false
+	if err := runExtract(&ExtractOptions{Paths: []string{videoPath}}, &out); err != nil {
+		t.Fatalf("runExtract() error = %v", err)
+	}
+	if mockFFmpeg.ExtractCalled {
+		t.Error("expected existing audio not to be extracted again")
+	}
+
+	err := runExtract(&ExtractOptions{Paths: []string{filepath.Join(tmpDir, "notes.txt")}}, &out)
+	if !isUsageError(err) {
+		t.Errorf("expected usage error for a non-video file, got %v", err)
+	}
+}
+
+func TestRunTranscribe(t *testing.T) {
+	tmpDir := t.TempDir()
+	oldHome := os.Getenv("HOME")
+	os.Setenv("HOME", tmpDir)
+	defer os.Setenv("HOME", oldHome)
+
+	var transcribed []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		_, header, _ := r.FormFile("file")
+		transcribed = append(transcribed, header.Filename)
+		json.NewEncoder(w).Encode(map[string]string{"text": "mock transcription"})
+	}))
+	defer server.Close()
+	os.Setenv("TRANSCRIPTION_API_URL", server.URL)
+	defer os.Unsetenv("TRANSCRIPTION_API_URL")
+
+	utils.SetFFmpegRunner(&utils.MockFFmpegRunner{})
+	defer utils.SetFFmpegRunner(&utils.DefaultFFmpegRunner{})
+
+	// A video is transcribed from its extracted audio, an mp3 file directly and an opus
+	// file, which the backend does not accept, from converted audio
+	videoPath := filepath.Join(tmpDir, "meeting.mp4")
+	audioPath := filepath.Join(tmpDir, "call.mp3")
+	opusPath := filepath.Join(tmpDir, "memo.opus")
+	os.WriteFile(videoPath, []byte("video"), 0644)
+	os.WriteFile(audioPath, []byte("audio"), 0644)
+	os.WriteFile(opusPath, []byte("audio"), 0644)
+
+	var out bytes.Buffer
+	opts := &TranscribeOptions{Paths: []string{videoPath, audioPath, opusPath}, Language: "en"}
+	if err := runTranscribe(opts, &out); err != nil {
+		t.Fatalf("runTranscribe() error = %v", err)
+	}
+	if strings.Join(transcribed, " ") != "meeting_audio.mp3 call.mp3 memo_audio.mp3" {
+		t.Errorf("expected the audio files to be transcribed, got %v", transcribed)
+	}
+	for _, name := range []string{"meeting_transcript.md", "call_transcript.md", "memo_transcript.md"} {
+		content, err := os.ReadFile(filepath.Join(tmpDir, name))
+		if err != nil || string(content) != "mock transcription" {
+			t.Errorf("unexpected transcript %s: %q, %v", name, content, err)
+		}
+	}
+
+	// Existing transcripts are kept
+	transcribed = nil
+	if err := runTranscribe(opts, &out); err != nil {
+		t.Fatalf("runTranscribe() error = %v", err)
+	}
+	if len(transcribed) != 0 {
+		t.Errorf("expected existing transcripts to be kept, got %v", transcribed)
+	}
+
+	// Files locked by a run are skipped
+	l, err := lock.Acquire(filepath.Join(tmpDir, state.Dir, "meeting.mp4.lock"), lock.DefaultStaleAfter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := runTranscribe(&TranscribeOptions{Paths: []string{videoPath}, Language: "en", Force: true}, &out); err != nil {
+		t.Fatalf("runTranscribe() of a locked file error = %v", err)
+	}
+	l.Release()
+	if len(transcribed) != 0 {
+		t.Errorf("expected a locked file to be skipped, got %v", transcribed)
+	}
+
+	err = runTranscribe(&TranscribeOptions{Paths: []string{videoPath}, Language: "xx"}, &out)
+	if !isUsageError(err) {
+		t.Errorf("expected usage error for an invalid language, got %v", err)
+	}
+
+	// The transcript goes where the layout of the run puts it
+	outDir := filepath.Join(tmpDir, "notes")
+	opts = &TranscribeOptions{Paths: []string{audioPath}, Language: "en", OutputDir: outDir, NameTemplate: "{{.Title}}/{{.Kind}}.md"}
+	if err := runTranscribe(opts, &out); err != nil {
+		t.Fatalf("runTranscribe() with --output-dir error = %v", err)
+	}
+	if !utils.FileExists(filepath.Join(outDir, "call", "transcript.md")) {
+		t.Error("expected the transcript in the output directory")
+	}
+}
+
+func TestRunSummarize(t *testing.T) {
+	tmpDir := t.TempDir()
+	oldHome := os.Getenv("HOME")
+	os.Setenv("HOME", tmpDir)
+	defer os.Setenv("HOME", oldHome)
+	os.Setenv("OPENAI_API_KEY", "test-key")
+	defer os.Unsetenv("OPENAI_API_KEY")
+
+	// A transcript from another tool and one written by mnote
+	notesPath := filepath.Join(tmpDir, "notes.txt")
+	transcriptPath := filepath.Join(tmpDir, "meeting_transcript.md")
+	os.WriteFile(notesPath, []byte("We agreed to ship on Friday."), 0644)
+	os.WriteFile(transcriptPath, []byte("We talked about the roadmap."), 0644)
+
+	var out bytes.Buffer
+	opts := &SummarizeOptions{Paths: []string{notesPath, transcriptPath}, PromptName: "summarize"}
+	if err := runSummarize(opts, &out); err != nil {
+		t.Fatalf("runSummarize() error = %v", err)
+	}
+	for _, name := range []string{"notes_summarize.md", "meeting_summarize.md"} {
+		content, err := os.ReadFile(filepath.Join(tmpDir, name))
+		if err != nil {
+			t.Fatalf("expected summary %s: %v", name, err)
+		}
+		if p, _ := provenance.Parse(string(content)); p == nil {
+			t.Errorf("expected provenance in %s", name)
+		}
+	}
+
+	// The output path can be chosen for a single transcript
+	outputPath := filepath.Join(tmpDir, "out", "summary.md")
+	os.MkdirAll(filepath.Dir(outputPath), 0755)
+	if err := runSummarize(&SummarizeOptions{Paths: []string{notesPath}, PromptName: "summarize", Output: outputPath}, &out); err != nil {
+		t.Fatalf("runSummarize() with --output error = %v", err)
+	}
+	if !utils.FileExists(outputPath) {
+		t.Error("expected summary at the output path")
+	}
+
+	err := runSummarize(&SummarizeOptions{Paths: []string{notesPath, transcriptPath}, PromptName: "summarize", Output: outputPath}, &out)
+	if !isUsageError(err) {
+		t.Errorf("expected usage error for --output with several transcripts, got %v", err)
+	}
+	err = runSummarize(&SummarizeOptions{Paths: []string{filepath.Join(tmpDir, "meeting.mp4")}, PromptName: "summarize"}, &out)
+	if !isUsageError(err) {
+		t.Errorf("expected usage error for a video, got %v", err)
+	}
+
+	// Redaction uses the mapping of the video the transcript belongs to
+	os.WriteFile(transcriptPath, []byte("Mail alice@example.com about the roadmap."), 0644)
+	if err := runSummarize(&SummarizeOptions{Paths: []string{transcriptPath}, PromptName: "summarize", Force: true, Redact: true}, &out); err != nil {
+		t.Fatalf("runSummarize() with --redact error = %v", err)
+	}
+	mapping, err := os.ReadFile(filepath.Join(tmpDir, "meeting_redaction.json"))
+	if err != nil || !strings.Contains(string(mapping), "alice@example.com") {
+		t.Errorf("expected the redaction mapping of the meeting, got %q, %v", mapping, err)
+	}
+
+	// A transcript named by a template gets the summary name of the template
+	templated := filepath.Join(tmpDir, "2024-03-11-retro", "transcript.md")
+	os.MkdirAll(filepath.Dir(templated), 0755)
+	os.WriteFile(templated, []byte("We looked back."), 0644)
+	opts = &SummarizeOptions{Paths: []string{templated}, PromptName: "summarize", NameTemplate: "{{.Date}}-{{.Title}}/{{.Kind}}.md"}
+	if err := runSummarize(opts, &out); err != nil {
+		t.Fatalf("runSummarize() with --name-template error = %v", err)
+	}
+	if !utils.FileExists(filepath.Join(tmpDir, "2024-03-11-retro", "summarize.md")) {
+		t.Error("expected the summary next to the templated transcript")
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/giantswarm/mnote/internal/config"
+	"github.com/giantswarm/mnote/internal/process"
+	"github.com/giantswarm/mnote/internal/state"
+	"github.com/giantswarm/mnote/internal/walk"
+	"github.com/spf13/cobra"
+)
+
+// StatusOptions holds the options of the status command
+type StatusOptions struct {
+	Dirs       []string
+	PromptName string
+	StaleOnly  bool
+	Recursive  bool
+	Stages     bool
+	JSON       bool
+
+	// Output locations used by the run, see Options
+	OutputDir    string
+	NameTemplate string
+}
+
+// videoStatus combines the recorded pipeline state of a video with the state of its summary
+type videoStatus struct {
+	Path    string           `json:"path"`
+	State   *state.FileState `json:"state,omitempty"`
+	Summary string           `json:"summary"`
+	Changes []string         `json:"summary_changes,omitempty"`
+}
+
+// stageOrder sorts stages in pipeline order, summaries of all prompts come last
+var stageOrder = map[string]int{
+	process.StageExtract:    0,
+	process.StageTranscribe: 1,
+	process.StageIndex:      2,
+}
+
+func newStatusCmd() *cobra.Command {
+	opts := &StatusOptions{
+		PromptName: "summarize",
+	}
+
+	cmd := &cobra.Command{
+		Use:   "status [flags] directory...",
+		Short: "Show the pipeline state of every video and whether its summary is stale",
+		Long: `Show the state of every video in the given directories as recorded in .mnote/state.json:
+the outcome of the last run, when it finished and the error if it failed. A video that
+stays "running" while mnote is not running was interrupted.
+
+The summary column shows whether the summary for --prompt is missing, current or
+stale. A summary is stale if the prompt, the chat model or the transcript changed
+since it was created. Stale summaries can be regenerated with 'mnote run --refresh-stale'.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.Dirs = args
+			return runStatus(opts, cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.PromptName, "prompt", "p", opts.PromptName,
+		"Name of the prompt whose summaries are checked")
+	cmd.Flags().BoolVar(&opts.StaleOnly, "stale", false,
+		"Only list stale summaries")
+	cmd.Flags().BoolVarP(&opts.Recursive, "recursive", "r", false,
+		"Include videos in subdirectories")
+	cmd.Flags().BoolVar(&opts.Stages, "stages", false,
+		"Show the status, duration, model and output of every stage")
+	cmd.Flags().BoolVar(&opts.JSON, "json", false,
+		"Print the status as JSON")
+	addLayoutFlags(cmd.Flags(), &opts.OutputDir, &opts.NameTemplate)
+
+	return cmd
+}
+
+func runStatus(opts *StatusOptions, out io.Writer) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	outputs, err := newLayout(cfg, &Options{Inputs: opts.Dirs, OutputDir: opts.OutputDir, NameTemplate: opts.NameTemplate})
+	if err != nil {
+		return err
+	}
+	processor := process.NewProcessor(cfg, nil, nil)
+	processor.SetLayout(outputs)
+	processOpts := process.Options{PromptName: opts.PromptName}
+
+	var statuses []*videoStatus
+	manifests := map[string]*state.Manifest{}
+	for _, dir := range opts.Dirs {
+		videos, err := walk.Find(dir, walk.Options{Recursive: opts.Recursive})
+		if err != nil {
+			return err
+		}
+		for _, path := range videos {
+			stateDir := outputs.Dir(path)
+			if manifests[stateDir] == nil {
+				if manifests[stateDir], err = state.Load(stateDir); err != nil {
+					return err
+				}
+			}
+			summary, err := processor.SummaryStatus(path, processOpts)
+			if err != nil {
+				return fmt.Errorf("failed to check %s: %w", path, err)
+			}
+			if opts.StaleOnly && summary.State != process.SummaryStale {
+				continue
+			}
+			statuses = append(statuses, &videoStatus{
+				Path:    path,
+				State:   manifests[stateDir].Files[filepath.Base(path)],
+				Summary: summary.State,
+				Changes: summary.Changes,
+			})
+		}
+	}
+
+	if opts.JSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(statuses)
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VIDEO\tLAST RUN\tFINISHED\tSUMMARY\tDETAILS")
+	for _, s := range statuses {
+		lastRun, finished, details := "new", "-", strings.Join(s.Changes, ", ")
+		if s.State != nil {
+			lastRun = s.State.Status
+			if !s.State.FinishedAt.IsZero() {
+				finished = s.State.FinishedAt.Local().Format("2006-01-02 15:04")
+			}
+			if s.State.Error != "" {
+				details = s.State.Error
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", s.Path, lastRun, finished, s.Summary, details)
+
+		if opts.Stages && s.State != nil {
+			for _, name := range sortedStages(s.State.Stages) {
+				stage := s.State.Stages[name]
+				details := stage.Output
+				if stage.Error != "" {
+					details = stage.Error
+				}
+				fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\n", name, stage.Status, stage.Duration(), stage.Model, details)
+			}
+		}
+	}
+	return w.Flush()
+}
+
+func sortedStages(stages map[string]*state.StageState) []string {
+	names := make([]string, 0, len(stages))
+	for name := range stages {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		oi, ok := stageOrder[names[i]]
+		if !ok {
+			oi = len(stageOrder)
+		}
+		oj, ok := stageOrder[names[j]]
+		if !ok {
+			oj = len(stageOrder)
+		}
+		if oi != oj {
+			return oi < oj
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
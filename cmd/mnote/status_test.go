@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/giantswarm/mnote/internal/prompts"
+	"github.com/giantswarm/mnote/internal/provenance"
+	"github.com/giantswarm/mnote/internal/state"
+)
+
+func TestRunStatus(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Set HOME for config loading
+	oldHome := os.Getenv("HOME")
+	os.Setenv("HOME", tmpDir)
+	defer os.Setenv("HOME", oldHome)
+
+	videoDir := filepath.Join(tmpDir, "videos")
+	os.MkdirAll(videoDir, 0755)
+	for _, name := range []string{"current", "stale", "missing"} {
+		os.WriteFile(filepath.Join(videoDir, name+".mp4"), []byte("video"), 0644)
+	}
+	os.WriteFile(filepath.Join(videoDir, "current_transcript.md"), []byte("transcript"), 0644)
+	os.WriteFile(filepath.Join(videoDir, "stale_transcript.md"), []byte("changed transcript"), 0644)
+
+	// Both summaries were created from the same transcript with the built-in prompt
+	prompt, err := prompts.Builtin("summarize")
+	if err != nil {
+		t.Fatalf("failed to load built-in prompt: %v", err)
+	}
+	p := provenance.New("summarize", prompt.Content, "gpt-4o", "transcript")
+	os.WriteFile(filepath.Join(videoDir, "current_summarize.md"), []byte(provenance.Add("summary", p)), 0644)
+	os.WriteFile(filepath.Join(videoDir, "stale_summarize.md"), []byte(provenance.Add("summary", p)), 0644)
+
+	// The last run of stale.mp4 failed
+	state.Update(videoDir, func(m *state.Manifest) error {
+		m.Files["stale.mp4"] = &state.FileState{
+			Status: state.StatusFailed,
+			Error:  "transcription failed",
+			Stages: map[string]*state.StageState{
+				"summarize:summarize": {Status: "skipped"},
+				"transcribe":          {Status: "failed", Model: "whisper-1", Error: "transcription failed"},
+				"extract":             {Status: "done", DurationMS: 1500, Output: "stale.mp3"},
+			},
+		}
+		return nil
+	})
+
+	var out bytes.Buffer
+	if err := runStatus(&StatusOptions{Dirs: []string{videoDir}, PromptName: "summarize"}, &out); err != nil {
+		t.Fatalf("runStatus() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected header and 3 videos, got %q", out.String())
+	}
+	for _, want := range []string{"current ", "missing ", "stale ", "new ", "failed ", "transcription failed"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("status output does not contain %q: %s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	if err := runStatus(&StatusOptions{Dirs: []string{videoDir}, PromptName: "summarize", StaleOnly: true}, &out); err != nil {
+		t.Fatalf("runStatus() error = %v", err)
+	}
+	if strings.Contains(out.String(), "current") || !strings.Contains(out.String(), "stale.mp4") {
+		t.Errorf("expected only stale summaries, got %s", out.String())
+	}
+
+	out.Reset()
+	if err := runStatus(&StatusOptions{Dirs: []string{videoDir}, PromptName: "summarize", Stages: true}, &out); err != nil {
+		t.Fatalf("runStatus() error = %v", err)
+	}
+	var stages []string
+	for _, line := range strings.Split(out.String(), "\n") {
+		if strings.HasPrefix(line, "  ") {
+			stages = append(stages, strings.Fields(line)[0])
+		}
+	}
+	if strings.Join(stages, " ") != "extract transcribe summarize:summarize" {
+		t.Errorf("expected stages in pipeline order, got %v", stages)
+	}
+	if !strings.Contains(out.String(), "1.5s") || !strings.Contains(out.String(), "whisper-1") {
+		t.Errorf("expected stage duration and model, got %s", out.String())
+	}
+
+	out.Reset()
+	if err := runStatus(&StatusOptions{Dirs: []string{videoDir}, PromptName: "summarize", JSON: true}, &out); err != nil {
+		t.Fatalf("runStatus() error = %v", err)
+	}
+	var statuses []videoStatus
+	if err := json.Unmarshal(out.Bytes(), &statuses); err != nil {
+		t.Fatalf("failed to parse JSON status: %v", err)
+	}
+	if len(statuses) != 3 {
+		t.Fatalf("expected 3 videos, got %d", len(statuses))
+	}
+	for _, s := range statuses {
+		if filepath.Base(s.Path) == "stale.mp4" && (s.State == nil || s.State.Status != state.StatusFailed || s.Summary != "stale") {
+			t.Errorf("unexpected status of stale.mp4: %+v", s)
+		}
+	}
+}
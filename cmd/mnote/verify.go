@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/giantswarm/mnote/internal/config"
+	"github.com/giantswarm/mnote/internal/layout"
+	"github.com/giantswarm/mnote/internal/provenance"
+	"github.com/giantswarm/mnote/internal/summarize"
+	"github.com/giantswarm/mnote/internal/transcript"
+	"github.com/giantswarm/mnote/internal/utils"
+	"github.com/giantswarm/mnote/internal/verify"
+	"github.com/spf13/cobra"
+)
+
+// VerifyOptions holds the options of the verify command
+type VerifyOptions struct {
+	Path           string
+	PromptName     string
+	TranscriptPath string
+	NoJudge        bool
+	Write          bool
+
+	// Output locations used by the run, see Options
+	OutputDir    string
+	NameTemplate string
+}
+
+func newVerifyCmd() *cobra.Command {
+	opts := &VerifyOptions{
+		PromptName: "summarize",
+	}
+
+	cmd := &cobra.Command{
+		Use:   "verify [flags] video|summary",
+		Short: "Check a summary's claims, names and quotes against the transcript",
+		Long: `Check a summary against its transcript. Quotes are matched fuzzily, names are looked up
+in the transcript, and the summary's claims are judged by the chat model.
+Unsupported statements are listed and the command fails if any were found.
+The transcript is sent to the chat model as it is, without redaction.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.Path = args[0]
+			return runVerify(opts, cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.PromptName, "prompt", "p", opts.PromptName,
+		"Name of the prompt the summary was created with")
+	cmd.Flags().StringVarP(&opts.TranscriptPath, "transcript", "t", "",
+		"Path of the transcript (default: derived from the video or summary path)")
+	cmd.Flags().BoolVar(&opts.NoJudge, "no-judge", false,
+		"Only check quotes and names, without asking the chat model about claims")
+	cmd.Flags().BoolVarP(&opts.Write, "write", "w", false,
+		"Add the verification section to the summary file")
+	addLayoutFlags(cmd.Flags(), &opts.OutputDir, &opts.NameTemplate)
+
+	return cmd
+}
+
+func runVerify(opts *VerifyOptions, out io.Writer) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// Find summary and transcript belonging to the input
+	outputs, err := newLayout(cfg, &Options{Inputs: []string{opts.Path}, OutputDir: opts.OutputDir, NameTemplate: opts.NameTemplate})
+	if err != nil {
+		return err
+	}
+	summaryPath := opts.Path
+	transcriptPath := opts.TranscriptPath
+	if utils.IsMediaFile(opts.Path) {
+		summaryPath = outputs.Summary(opts.Path, opts.PromptName)
+		if transcriptPath == "" {
+			transcriptPath = outputs.Transcript(opts.Path)
+		}
+	} else if transcriptPath == "" {
+		var ok bool
+		if transcriptPath, ok = outputs.Sibling(summaryPath, opts.PromptName, layout.KindTranscript, ".md"); !ok {
+			return &usageError{fmt.Sprintf("cannot derive transcript path from %s, use --transcript", summaryPath)}
+		}
+	}
+
+	content, err := utils.ReadFile(summaryPath)
+	if err != nil {
+		return fmt.Errorf("failed to read summary: %w", err)
+	}
+	recorded, summary := provenance.Parse(string(content))
+	transcriptText, _, err := transcript.ReadTimestamped(transcriptPath)
+	if err != nil {
+		return err
+	}
+
+	var client summarize.OpenAIClient
+	if !opts.NoJudge {
+		if client, err = summarize.NewOpenAIClient(); err != nil {
+			return fmt.Errorf("failed to initialize chat client: %w", err)
+		}
+	}
+
+	report, err := verify.NewVerifier(cfg, client).Verify(summary, transcriptText, !opts.NoJudge)
+	if err != nil {
+		return fmt.Errorf("verification failed: %w", err)
+	}
+	section := report.Markdown()
+	fmt.Fprint(out, section[strings.Index(section, "\n")+1:])
+
+	if opts.Write {
+		verified := verify.AppendSection(summary, report)
+		if recorded != nil {
+			verified = provenance.Add(verified, *recorded)
+		}
+		if err := utils.WriteFile(summaryPath, []byte(verified)); err != nil {
+			return fmt.Errorf("failed to save summary: %w", err)
+		}
+		fmt.Fprintf(out, "Verification added to: %s\n", summaryPath)
+	}
+
+	if unsupported := report.Unsupported(); len(unsupported) > 0 {
+		return fmt.Errorf("%d statements are not supported by the transcript", len(unsupported))
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunVerify(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Set HOME for config loading
+	oldHome := os.Getenv("HOME")
+	os.Setenv("HOME", tmpDir)
+	defer os.Setenv("HOME", oldHome)
+
+	videoPath := filepath.Join(tmpDir, "standup.mp4")
+	os.WriteFile(videoPath, []byte("video"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "standup_transcript.md"), []byte("Alice said the release is done."), 0644)
+	summaryPath := filepath.Join(tmpDir, "standup_summarize.md")
+	os.WriteFile(summaryPath, []byte("The release is done, says Alice."), 0644)
+
+	var out bytes.Buffer
+	if err := runVerify(&VerifyOptions{Path: videoPath, PromptName: "summarize", NoJudge: true}, &out); err != nil {
+		t.Fatalf("runVerify() error = %v, output %q", err, out.String())
+	}
+
+	// Unsupported names fail the verification and are written to the summary
+	os.WriteFile(summaryPath, []byte("The release is done, says Mallory."), 0644)
+	out.Reset()
+	err := runVerify(&VerifyOptions{Path: summaryPath, PromptName: "summarize", NoJudge: true, Write: true}, &out)
+	if err == nil {
+		t.Fatal("runVerify() should fail for unsupported statements")
+	}
+	content, _ := os.ReadFile(summaryPath)
+	if !strings.Contains(string(content), "**Name**: Mallory") {
+		t.Errorf("expected verification section in summary, got %q", content)
+	}
+
+	// Outputs written with another layout are found
+	outDir := filepath.Join(tmpDir, "notes")
+	os.MkdirAll(filepath.Join(outDir, "standup"), 0755)
+	os.WriteFile(filepath.Join(outDir, "standup", "transcript.md"), []byte("Alice said the release is done."), 0644)
+	os.WriteFile(filepath.Join(outDir, "standup", "summarize.md"), []byte("The release is done, says Alice."), 0644)
+	for _, path := range []string{videoPath, filepath.Join(outDir, "standup", "summarize.md")} {
+		opts := &VerifyOptions{Path: path, PromptName: "summarize", NoJudge: true, OutputDir: outDir, NameTemplate: "{{.Title}}/{{.Kind}}.md"}
+		if err := runVerify(opts, &out); err != nil {
+			t.Errorf("runVerify(%s) with --output-dir error = %v", path, err)
+		}
+	}
+
+	// Summaries with unknown names need an explicit transcript
+	err = runVerify(&VerifyOptions{Path: filepath.Join(tmpDir, "notes.md"), PromptName: "summarize", NoJudge: true}, &out)
+	if !isUsageError(err) {
+		t.Errorf("expected usage error, got %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/giantswarm/mnote/internal/config"
+	"github.com/giantswarm/mnote/internal/progress"
+	"github.com/giantswarm/mnote/internal/watch"
+	"github.com/spf13/cobra"
+)
+
+// WatchOptions holds the options of the watch command
+type WatchOptions struct {
+	Options
+	Dir    string
+	Settle time.Duration
+}
+
+func newWatchCmd() *cobra.Command {
+	opts := &WatchOptions{
+		Options: Options{PromptName: "summarize"},
+		Settle:  watch.DefaultSettle,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "watch [flags] directory",
+		Short: "Process new recordings in a directory as they appear",
+		Long: `Watch a directory and process new or changed videos once they have stopped growing.
+Videos that were added while mnote was not watching are processed on start. A video
+is only processed again when it differs from the version recorded in .mnote/state.json.
+Stop watching with Ctrl+C; the video being processed is finished first.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.Dir = args[0]
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return runWatch(ctx, opts)
+		},
+	}
+
+	addProcessFlags(cmd.Flags(), &opts.Options)
+	addWalkFlags(cmd.Flags(), &opts.Options)
+	cmd.Flags().DurationVar(&opts.Settle, "settle", opts.Settle,
+		"How long a video must be unchanged before it is processed")
+
+	return cmd
+}
+
+func runWatch(ctx context.Context, opts *WatchOptions) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	info, err := os.Stat(opts.Dir)
+	if err != nil || !info.IsDir() {
+		return fmt.Errorf("not a directory: %s", opts.Dir)
+	}
+	if err := opts.validate(cfg); err != nil {
+		return err
+	}
+	processOpts, err := opts.processOptions()
+	if err != nil {
+		return err
+	}
+	walkOpts, err := opts.walkOptions()
+	if err != nil {
+		return err
+	}
+	opts.Inputs = []string{opts.Dir}
+	outputs, err := newLayout(cfg, &opts.Options)
+	if err != nil {
+		return err
+	}
+	processor, err := newProcessor(cfg, &opts.Options, outputs)
+	if err != nil {
+		return err
+	}
+	defer progress.Default().Close()
+
+	watcher := watch.New(opts.Dir, watch.Options{
+		Walk:     walkOpts,
+		Settle:   opts.Settle,
+		StateDir: outputs.Dir,
+	}, func(path string) error {
+		return processor.ProcessVideo(path, processOpts)
+	})
+
+	slog.Info("Watching for new recordings (Ctrl+C to stop)", "dir", opts.Dir)
+	if err := watcher.Run(ctx); err != nil {
+		return err
+	}
+	slog.Info("Stopped watching", "dir", opts.Dir)
+	return nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/mnote/internal/state"
+	"github.com/giantswarm/mnote/internal/utils"
+)
+
+func TestRunWatch(t *testing.T) {
+	tmpDir := t.TempDir()
+	oldHome := os.Getenv("HOME")
+	os.Setenv("HOME", tmpDir)
+	defer os.Setenv("HOME", oldHome)
+	os.Setenv("OPENAI_API_KEY", "test-key")
+	defer os.Unsetenv("OPENAI_API_KEY")
+
+	// A recording that is already transcribed, so no transcription server is needed
+	videoDir := filepath.Join(tmpDir, "recordings")
+	os.MkdirAll(videoDir, 0755)
+	os.WriteFile(filepath.Join(videoDir, "call.mp4"), []byte("video"), 0644)
+	os.WriteFile(filepath.Join(videoDir, "call_audio.mp3"), []byte("audio"), 0644)
+	os.WriteFile(filepath.Join(videoDir, "call_transcript.md"), []byte("transcript"), 0644)
+
+	opts := &WatchOptions{
+		Options: Options{PromptName: "summarize", Language: "en"},
+		Dir:     videoDir,
+		Settle:  50 * time.Millisecond,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- runWatch(ctx, opts) }()
+
+	summaryPath := filepath.Join(videoDir, "call_summarize.md")
+	deadline := time.Now().Add(10 * time.Second)
+	for !utils.FileExists(summaryPath) && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("runWatch() error = %v", err)
+	}
+	if !utils.FileExists(summaryPath) {
+		t.Fatal("expected the recording to be summarized")
+	}
+	m, err := state.Load(videoDir)
+	if err != nil || m.Files["call.mp4"] == nil || m.Files["call.mp4"].Status != state.StatusSucceeded {
+		t.Error("expected the processed recording to be recorded in the state manifest")
+	}
+}
+
+func TestRunWatchInvalidDir(t *testing.T) {
+	oldHome := os.Getenv("HOME")
+	os.Setenv("HOME", t.TempDir())
+	defer os.Setenv("HOME", oldHome)
+
+	opts := &WatchOptions{Options: Options{PromptName: "summarize"}, Dir: "/nonexistent"}
+	if err := runWatch(context.Background(), opts); err == nil {
+		t.Error("expected error for missing directory")
+	}
+}
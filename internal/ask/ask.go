@@ -0,0 +1,193 @@
+package ask
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/giantswarm/mnote/internal/config"
+	"github.com/giantswarm/mnote/internal/summarize"
+	"github.com/giantswarm/mnote/internal/transcript"
+	"github.com/sashabaranov/go-openai"
+)
+
+// DefaultTopK is the default number of passages sent to the model per question
+const DefaultTopK = 8
+
+const systemPrompt = `You answer questions about meetings using only the transcript excerpts provided by the user.
+Every excerpt is labelled with its source as [file @ hh:mm:ss] or [file] when no timestamp is known.
+Cite the labels of the excerpts that support each statement of your answer, using exactly the same format.
+If the excerpts do not contain the answer, say that the transcripts do not mention it instead of guessing.`
+
+// Asker answers questions about a set of transcripts and keeps the conversation history
+type Asker struct {
+	client   summarize.OpenAIClient
+	config   *config.Config
+	passages []transcript.Passage
+	topK     int
+	history  []openai.ChatCompletionMessage
+}
+
+// NewAsker creates a new Asker over the given passages
+func NewAsker(cfg *config.Config, client summarize.OpenAIClient, passages []transcript.Passage, topK int) *Asker {
+	if topK <= 0 {
+		topK = DefaultTopK
+	}
+	return &Asker{
+		client:   client,
+		config:   cfg,
+		passages: passages,
+		topK:     topK,
+	}
+}
+
+// Ask answers a question, taking previous questions and answers into account
+func (a *Asker) Ask(question string) (string, error) {
+	// Follow-up questions often refer to the previous one, so use both for retrieval
+	query := question
+	if len(a.history) >= 2 {
+		query = a.history[len(a.history)-2].Content + " " + question
+	}
+	relevant := Retrieve(a.passages, query, a.topK)
+
+	messages := []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleSystem, Content: systemPrompt},
+	}
+	messages = append(messages, a.history...)
+	messages = append(messages, openai.ChatCompletionMessage{
+		Role:    openai.ChatMessageRoleUser,
+		Content: buildQuestion(question, relevant),
+	})
+
+	resp, err := a.client.CreateChatCompletion(
+		context.Background(),
+		openai.ChatCompletionRequest{
+			Model:    a.config.ChatGPTModel,
+			Messages: messages,
+		},
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to create chat completion: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("no response choices returned from API")
+	}
+	answer := resp.Choices[0].Message.Content
+
+	// Keep only the plain question in the history, excerpts are retrieved again per turn
+	a.history = append(a.history,
+		openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: question},
+		openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: answer},
+	)
+
+	return answer, nil
+}
+
+// Reset clears the conversation history
+func (a *Asker) Reset() {
+	a.history = nil
+}
+
+// Citation returns the label used to cite a passage
+func Citation(p transcript.Passage) string {
+	if ts := p.Timestamp(); ts != "" {
+		return fmt.Sprintf("[%s @ %s]", filepath.Base(p.File), ts)
+	}
+	return fmt.Sprintf("[%s]", filepath.Base(p.File))
+}
+
+func buildQuestion(question string, passages []transcript.Passage) string {
+	var b strings.Builder
+	if len(passages) == 0 {
+		b.WriteString("No relevant transcript excerpts were found.\n")
+	} else {
+		b.WriteString("Transcript excerpts:\n\n")
+		for _, p := range passages {
+			fmt.Fprintf(&b, "%s %s\n\n", Citation(p), p.Text)
+		}
+	}
+	fmt.Fprintf(&b, "Question: %s", question)
+	return b.String()
+}
+
+// Retrieve returns up to k passages ranked by their keyword relevance to the query
+func Retrieve(passages []transcript.Passage, query string, k int) []transcript.Passage {
+	terms := tokenize(query)
+	if len(terms) == 0 || len(passages) == 0 {
+		return nil
+	}
+
+	// Count in how many passages each term occurs
+	docs := make([]map[string]int, len(passages))
+	df := make(map[string]int)
+	for i, p := range passages {
+		docs[i] = make(map[string]int)
+		for _, term := range tokenize(p.Text) {
+			docs[i][term]++
+		}
+		for term := range docs[i] {
+			df[term]++
+		}
+	}
+
+	type scored struct {
+		index int
+		score float64
+	}
+	var results []scored
+	for i, doc := range docs {
+		score := 0.0
+		for _, term := range terms {
+			if tf := doc[term]; tf > 0 {
+				idf := math.Log(1 + float64(len(passages))/float64(df[term]))
+				score += (1 + math.Log(float64(tf))) * idf
+			}
+		}
+		if score > 0 {
+			results = append(results, scored{index: i, score: score})
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].score > results[j].score
+	})
+	if len(results) > k {
+		results = results[:k]
+	}
+
+	// Present the selected passages in transcript order
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].index < results[j].index
+	})
+	relevant := make([]transcript.Passage, len(results))
+	for i, r := range results {
+		relevant[i] = passages[r.index]
+	}
+	return relevant
+}
+
+var stopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "are": true, "was": true, "were": true,
+	"did": true, "does": true, "what": true, "who": true, "how": true, "when": true,
+	"why": true, "that": true, "this": true, "with": true, "about": true, "have": true,
+	"has": true, "had": true, "our": true, "you": true, "any": true, "from": true,
+	"der": true, "die": true, "das": true, "und": true, "ist": true, "wir": true,
+}
+
+func tokenize(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	var terms []string
+	for _, f := range fields {
+		if len([]rune(f)) < 3 || stopWords[f] {
+			continue
+		}
+		terms = append(terms, f)
+	}
+	return terms
+}
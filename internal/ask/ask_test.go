@@ -0,0 +1,92 @@
+package ask
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/giantswarm/mnote/internal/config"
+	"github.com/giantswarm/mnote/internal/transcript"
+	"github.com/sashabaranov/go-openai"
+)
+
+// recordingClient records the requests it receives
+type recordingClient struct {
+	requests []openai.ChatCompletionRequest
+}
+
+func (c *recordingClient) CreateChatCompletion(_ context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	c.requests = append(c.requests, req)
+	return openai.ChatCompletionResponse{
+		Choices: []openai.ChatCompletionChoice{
+			{Message: openai.ChatCompletionMessage{Content: "Yes [standup_transcript.md @ 00:12:34]"}},
+		},
+	}, nil
+}
+
+func testPassages() []transcript.Passage {
+	return []transcript.Passage{
+		{File: "/notes/standup_transcript.md", Start: 0, Text: "Good morning everyone, the build is green."},
+		{File: "/notes/standup_transcript.md", Start: 754, Text: "We decided to migrate the database on Friday."},
+		{File: "/notes/planning_transcript.md", Start: -1, Text: "Planning the next sprint and the database backups."},
+	}
+}
+
+func TestRetrieve(t *testing.T) {
+	got := Retrieve(testPassages(), "Did we decide on the database migration?", 1)
+	if len(got) != 1 {
+		t.Fatalf("expected 1 passage, got %d", len(got))
+	}
+	if got[0].Start != 754 {
+		t.Errorf("expected migration passage, got %+v", got[0])
+	}
+
+	if got := Retrieve(testPassages(), "kubernetes", 3); len(got) != 0 {
+		t.Errorf("expected no passages for unrelated query, got %d", len(got))
+	}
+}
+
+func TestCitation(t *testing.T) {
+	passages := testPassages()
+	if got := Citation(passages[1]); got != "[standup_transcript.md @ 00:12:34]" {
+		t.Errorf("Citation() = %s", got)
+	}
+	if got := Citation(passages[2]); got != "[planning_transcript.md]" {
+		t.Errorf("Citation() = %s", got)
+	}
+}
+
+func TestAskKeepsHistory(t *testing.T) {
+	client := &recordingClient{}
+	asker := NewAsker(config.DefaultConfig(), client, testPassages(), 2)
+
+	if _, err := asker.Ask("Did we decide on the database migration?"); err != nil {
+		t.Fatalf("Ask() error = %v", err)
+	}
+	if _, err := asker.Ask("When will it happen?"); err != nil {
+		t.Fatalf("Ask() error = %v", err)
+	}
+
+	if len(client.requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(client.requests))
+	}
+	followUp := client.requests[1].Messages
+	// system prompt, previous question, previous answer, new question
+	if len(followUp) != 4 {
+		t.Fatalf("expected 4 messages in follow-up request, got %d", len(followUp))
+	}
+	if followUp[1].Content != "Did we decide on the database migration?" {
+		t.Errorf("expected previous question in history, got %q", followUp[1].Content)
+	}
+	if !strings.Contains(followUp[3].Content, "[standup_transcript.md @ 00:12:34]") {
+		t.Errorf("expected follow-up to retrieve passages using the previous question, got %q", followUp[3].Content)
+	}
+
+	asker.Reset()
+	if _, err := asker.Ask("Did we decide on the database migration?"); err != nil {
+		t.Fatalf("Ask() error = %v", err)
+	}
+	if len(client.requests[2].Messages) != 2 {
+		t.Errorf("expected history to be cleared after Reset()")
+	}
+}
@@ -4,30 +4,82 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/viper"
 )
 
 // Config holds all configuration settings for mnote
 type Config struct {
-	TranscriptionAPIURL string            `mapstructure:"TRANSCRIPTION_API_URL"`
-	DefaultLanguage    string            `mapstructure:"DEFAULT_LANGUAGE"`
-	WhisperModels     map[string]string `mapstructure:"-"`
-	ChatGPTModel      string            `mapstructure:"CHATGPT_MODEL"`
+	TranscriptionAPIURL  string            `mapstructure:"TRANSCRIPTION_API_URL"`
+	DefaultLanguage      string            `mapstructure:"DEFAULT_LANGUAGE"`
+	WhisperModels        map[string]string `mapstructure:"-"`
+	ChatGPTModel         string            `mapstructure:"CHATGPT_MODEL"`
+	EmbeddingsAPIURL     string            `mapstructure:"EMBEDDINGS_API_URL"`
+	EmbeddingModel       string            `mapstructure:"EMBEDDING_MODEL"`
+	IndexPath            string            `mapstructure:"INDEX_PATH"`
+	RedactDictionaryFile string            `mapstructure:"REDACT_DICTIONARY_FILE"`
+	RedactPatternsFile   string            `mapstructure:"REDACT_PATTERNS_FILE"`
+	NameTemplate         string            `mapstructure:"NAME_TEMPLATE"`
+	AudioCacheDir        string            `mapstructure:"AUDIO_CACHE_DIR"`
+
+	// Long recordings are transcribed in chunks of this many minutes, 0 sends the whole audio
+	TranscriptionChunkMinutes int `mapstructure:"TRANSCRIPTION_CHUNK_MINUTES"`
+	// Longer transcripts are summarized in parts first, whose summaries are then combined
+	SummaryChunkChars int `mapstructure:"SUMMARY_CHUNK_CHARS"`
+
+	// Prices used for dry-run estimates, in USD
+	TranscriptionPricePerMinute float64 `mapstructure:"TRANSCRIPTION_PRICE_PER_MINUTE"`
+	ChatGPTInputPrice           float64 `mapstructure:"CHATGPT_INPUT_PRICE"`
+	ChatGPTOutputPrice          float64 `mapstructure:"CHATGPT_OUTPUT_PRICE"`
+
+	// Hooks are external commands run around pipeline stages, by hook name
+	Hooks map[string]Hook `mapstructure:"-"`
+}
+
+// Hook is an external command run by a pipeline hook
+type Hook struct {
+	Command string
+	// OnFailure is "fail" to fail the video if the command fails, or "ignore"
+	OnFailure string
 }
 
+// Hook names and failure policies
+const (
+	HookPreTranscribe  = "pre_transcribe"
+	HookPostTranscribe = "post_transcribe"
+	HookPostSummarize  = "post_summarize"
+	HookOnError        = "on_error"
+
+	HookFail   = "fail"
+	HookIgnore = "ignore"
+)
+
+// HookNames lists the supported hooks in pipeline order
+var HookNames = []string{HookPreTranscribe, HookPostTranscribe, HookPostSummarize, HookOnError}
+
 // DefaultConfig returns a Config with default values
 func DefaultConfig() *Config {
 	return &Config{
 		TranscriptionAPIURL: "https://api.kubeai.org/v1/audio/transcriptions",
-		DefaultLanguage: "auto",
+		DefaultLanguage:     "auto",
 		WhisperModels: map[string]string{
 			"en": "faster-whisper-medium-en-cpu",
 			"de": "systran-faster-whisper-large-v3",
 			"es": "systran-faster-whisper-large-v3",
 			"fr": "systran-faster-whisper-large-v3",
 		},
-		ChatGPTModel: "gpt-4o",
+		ChatGPTModel:   "gpt-4o",
+		EmbeddingModel: "text-embedding-3-small",
+
+		TranscriptionChunkMinutes: 10,
+		SummaryChunkChars:         100000,
+
+		TranscriptionPricePerMinute: 0.006,
+		ChatGPTInputPrice:           2.50,
+		ChatGPTOutputPrice:          10.00,
+
+		Hooks: map[string]Hook{},
 	}
 }
 
@@ -50,15 +102,6 @@ func LoadConfig() (*Config, error) {
 		return nil, fmt.Errorf("failed to create config directories: %w", err)
 	}
 
-	// Create default prompt file if it doesn't exist
-	defaultPromptFile := filepath.Join(promptsDir, "summarize")
-	if _, err := os.Stat(defaultPromptFile); os.IsNotExist(err) {
-		defaultPrompt := `Create a detailed summary of the following meeting transcript. Structure the summary according to the main topics discussed and organize the information into logical sections. For each topic, summarize who was involved, what was discussed in detail, what decisions were made, what problems or challenges were identified, and what solutions were proposed or implemented.`
-		if err := os.WriteFile(defaultPromptFile, []byte(defaultPrompt), 0644); err != nil {
-			return nil, fmt.Errorf("failed to create default prompt file: %w", err)
-		}
-	}
-
 	// Read config file
 	if err := v.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
@@ -97,6 +140,23 @@ CHATGPT_MODEL=gpt-4o`
 		}
 	}
 
+	// Load hooks, HOOK_POST_SUMMARIZE=command with HOOK_POST_SUMMARIZE_ON_FAILURE=fail|ignore
+	for _, name := range HookNames {
+		envKey := "HOOK_" + strings.ToUpper(name)
+		command := v.GetString(envKey)
+		if command == "" {
+			continue
+		}
+		hook := Hook{Command: command, OnFailure: HookFail}
+		if policy := v.GetString(envKey + "_ON_FAILURE"); policy != "" {
+			if policy != HookFail && policy != HookIgnore {
+				return nil, fmt.Errorf("invalid %s_ON_FAILURE: %s (must be fail or ignore)", envKey, policy)
+			}
+			hook.OnFailure = policy
+		}
+		config.Hooks[name] = hook
+	}
+
 	return config, nil
 }
 
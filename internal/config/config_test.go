@@ -84,7 +84,11 @@ func TestLoadConfig(t *testing.T) {
 	configContent := `TRANSCRIPTION_API_URL=https://test.api/transcribe
 DEFAULT_LANGUAGE=de
 WHISPER_MODEL_EN=custom-en-model
-CHATGPT_MODEL=gpt-4-turbo`
+CHATGPT_MODEL=gpt-4-turbo
+CHATGPT_INPUT_PRICE=10
+HOOK_POST_SUMMARIZE='cp "$MNOTE_SUMMARY" ~/wiki/'
+HOOK_ON_ERROR=notify-send failed
+HOOK_ON_ERROR_ON_FAILURE=ignore`
 
 	err = os.WriteFile(filepath.Join(configDir, "config"), []byte(configContent), 0644)
 	if err != nil {
@@ -109,4 +113,19 @@ CHATGPT_MODEL=gpt-4-turbo`
 	if cfg.ChatGPTModel != "gpt-4-turbo" {
 		t.Errorf("expected ChatGPTModel to be 'gpt-4-turbo', got %s", cfg.ChatGPTModel)
 	}
+	if cfg.ChatGPTInputPrice != 10 || cfg.ChatGPTOutputPrice != 10 {
+		t.Errorf("expected input price 10 and default output price 10, got %v and %v", cfg.ChatGPTInputPrice, cfg.ChatGPTOutputPrice)
+	}
+	wantHooks := map[string]Hook{
+		HookPostSummarize: {Command: `cp "$MNOTE_SUMMARY" ~/wiki/`, OnFailure: HookFail},
+		HookOnError:       {Command: "notify-send failed", OnFailure: HookIgnore},
+	}
+	if len(cfg.Hooks) != len(wantHooks) {
+		t.Errorf("expected %d hooks, got %v", len(wantHooks), cfg.Hooks)
+	}
+	for name, want := range wantHooks {
+		if cfg.Hooks[name] != want {
+			t.Errorf("expected hook %s to be %+v, got %+v", name, want, cfg.Hooks[name])
+		}
+	}
 }
@@ -0,0 +1,239 @@
+package digest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/mnote/internal/config"
+	"github.com/giantswarm/mnote/internal/layout"
+	"github.com/giantswarm/mnote/internal/provenance"
+	"github.com/giantswarm/mnote/internal/summarize"
+	"github.com/giantswarm/mnote/internal/utils"
+	"github.com/sashabaranov/go-openai"
+)
+
+// DefaultMaxChars is the default maximum size of the summaries sent in one request
+const DefaultMaxChars = 48000
+
+// DateLayout is the date format used for digest ranges
+const DateLayout = "2006-01-02"
+
+// Meeting is a summarized meeting that is part of a digest
+type Meeting struct {
+	Title       string
+	Date        time.Time
+	SummaryPath string
+	Summary     string
+}
+
+// Collect finds the summaries written for the given prompt in the directories. The summaries
+// of recordings are found with the layout they were processed with, summaries named
+// <title>_<prompt>.md are collected even if their recording no longer exists.
+// The meeting date is the modification time of the recording, or of the summary if there is
+// no recording. Zero since or until values leave the range open.
+func Collect(dirs []string, promptName string, outputs *layout.Layout, since, until time.Time) ([]Meeting, error) {
+	suffix := "_" + promptName + ".md"
+	var meetings []Meeting
+	seen := make(map[string]bool)
+
+	add := func(title, summaryPath, datePath string) error {
+		seen[summaryPath] = true
+		info, err := os.Stat(datePath)
+		if err != nil {
+			return fmt.Errorf("failed to stat %s: %w", datePath, err)
+		}
+		date := info.ModTime()
+		if !since.IsZero() && date.Before(since) {
+			return nil
+		}
+		if !until.IsZero() && !date.Before(until) {
+			return nil
+		}
+
+		summary, err := utils.ReadFile(summaryPath)
+		if err != nil {
+			return fmt.Errorf("failed to read summary: %w", err)
+		}
+		meetings = append(meetings, Meeting{
+			Title:       title,
+			Date:        date,
+			SummaryPath: summaryPath,
+			Summary:     provenance.Strip(string(summary)),
+		})
+		return nil
+	}
+
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read directory: %w", err)
+		}
+
+		// Summaries of recordings are dated by the recording
+		for _, entry := range entries {
+			path := filepath.Join(dir, entry.Name())
+			if entry.IsDir() || !utils.IsMediaFile(path) {
+				continue
+			}
+			summaryPath := outputs.Summary(path, promptName)
+			if seen[summaryPath] || !utils.FileExists(summaryPath) {
+				continue
+			}
+			title := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+			if err := add(title, summaryPath, path); err != nil {
+				return nil, err
+			}
+		}
+
+		// Summaries named by the template, e.g. in an output directory, and summaries named
+		// the default way are dated by themselves
+		found, err := outputs.Find(dir, promptName, ".md")
+		if err != nil {
+			return nil, err
+		}
+		for _, summaryPath := range found {
+			if seen[summaryPath] {
+				continue
+			}
+			data, _ := outputs.Match(summaryPath, promptName)
+			if err := add(data.Title, summaryPath, summaryPath); err != nil {
+				return nil, err
+			}
+		}
+		for _, entry := range entries {
+			summaryPath := filepath.Join(dir, entry.Name())
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), suffix) || seen[summaryPath] {
+				continue
+			}
+			if err := add(strings.TrimSuffix(entry.Name(), suffix), summaryPath, summaryPath); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	sort.SliceStable(meetings, func(i, j int) bool {
+		return meetings[i].Date.Before(meetings[j].Date)
+	})
+	return meetings, nil
+}
+
+// Digester rolls up meeting summaries into one digest
+type Digester struct {
+	client   summarize.OpenAIClient
+	config   *config.Config
+	maxChars int
+}
+
+// NewDigester creates a new Digester instance
+func NewDigester(cfg *config.Config, client summarize.OpenAIClient, maxChars int) *Digester {
+	if maxChars <= 0 {
+		maxChars = DefaultMaxChars
+	}
+	return &Digester{
+		client:   client,
+		config:   cfg,
+		maxChars: maxChars,
+	}
+}
+
+// Digest creates a digest of the meetings using the given prompt.
+// If the summaries do not fit into one request they are digested in chunks first,
+// and the partial digests are combined in a final request.
+func (d *Digester) Digest(meetings []Meeting, prompt string) (string, error) {
+	if len(meetings) == 0 {
+		return "", fmt.Errorf("no meetings to digest")
+	}
+
+	var sections []string
+	for _, m := range meetings {
+		sections = append(sections, fmt.Sprintf("## %s (%s)\n\n%s", m.Title, m.Date.Format(DateLayout), strings.TrimSpace(m.Summary)))
+	}
+
+	for {
+		chunks := chunk(sections, d.maxChars)
+		if len(chunks) == 1 {
+			return d.complete(prompt, chunks[0])
+		}
+
+		// Digest each chunk, then digest the partial digests
+		var partials []string
+		for i, c := range chunks {
+			partial, err := d.complete(prompt, c)
+			if err != nil {
+				return "", fmt.Errorf("failed to digest chunk %d of %d: %w", i+1, len(chunks), err)
+			}
+			partials = append(partials, partial)
+		}
+		if totalLen(partials) >= totalLen(sections) {
+			return "", fmt.Errorf("meeting summaries are too large to digest")
+		}
+		sections = partials
+	}
+}
+
+func (d *Digester) complete(prompt, content string) (string, error) {
+	resp, err := d.client.CreateChatCompletion(
+		context.Background(),
+		openai.ChatCompletionRequest{
+			Model: d.config.ChatGPTModel,
+			Messages: []openai.ChatCompletionMessage{
+				{Role: openai.ChatMessageRoleSystem, Content: prompt},
+				{Role: openai.ChatMessageRoleUser, Content: content},
+			},
+		},
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to create chat completion: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("no response choices returned from API")
+	}
+	return resp.Choices[0].Message.Content, nil
+}
+
+func totalLen(sections []string) int {
+	n := 0
+	for _, s := range sections {
+		n += len(s)
+	}
+	return n
+}
+
+// chunk groups sections so that each group stays below maxChars where possible
+func chunk(sections []string, maxChars int) []string {
+	var chunks []string
+	var current strings.Builder
+	for _, s := range sections {
+		if current.Len() > 0 && current.Len()+len(s)+2 > maxChars {
+			chunks = append(chunks, current.String())
+			current.Reset()
+		}
+		if current.Len() > 0 {
+			current.WriteString("\n\n")
+		}
+		current.WriteString(s)
+	}
+	if current.Len() > 0 {
+		chunks = append(chunks, current.String())
+	}
+	return chunks
+}
+
+// Render builds the digest document with links to each meeting's notes,
+// relative to the directory of the output path
+func Render(title, digest string, meetings []Meeting, outputPath string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", title)
+	b.WriteString(strings.TrimSpace(digest))
+	b.WriteString("\n\n## Meetings\n\n")
+
+	for _, m := range meetings {
+		fmt.Fprintf(&b, "- %s: [%s](%s)\n", m.Date.Format(DateLayout), m.Title, utils.RelativeLink(outputPath, m.SummaryPath))
+	}
+	return b.String()
+}
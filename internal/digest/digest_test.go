@@ -0,0 +1,152 @@
+package digest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/mnote/internal/config"
+	"github.com/giantswarm/mnote/internal/layout"
+	"github.com/sashabaranov/go-openai"
+)
+
+// countingClient returns the number of the request as the response
+type countingClient struct {
+	contents []string
+}
+
+func (c *countingClient) CreateChatCompletion(_ context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	c.contents = append(c.contents, req.Messages[1].Content)
+	return openai.ChatCompletionResponse{
+		Choices: []openai.ChatCompletionChoice{
+			{Message: openai.ChatCompletionMessage{Content: "digest part"}},
+		},
+	}, nil
+}
+
+const testPrompt = "Create a digest of the following meeting summaries."
+
+func writeFile(t *testing.T, path, content string, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCollect(t *testing.T) {
+	tmpDir := t.TempDir()
+	monday := time.Date(2024, 3, 11, 10, 0, 0, 0, time.UTC)
+	lastWeek := monday.AddDate(0, 0, -7)
+
+	// Summary dated by its video
+	writeFile(t, filepath.Join(tmpDir, "standup.mp4"), "video", monday)
+	writeFile(t, filepath.Join(tmpDir, "standup_summarize.md"), "standup summary", monday.AddDate(0, 0, 30))
+	// Summary without video is dated by itself
+	writeFile(t, filepath.Join(tmpDir, "planning_summarize.md"), "planning summary", monday.Add(time.Hour))
+	// Outside of the range
+	writeFile(t, filepath.Join(tmpDir, "old_summarize.md"), "old summary", lastWeek)
+	// Not a summary of the prompt
+	writeFile(t, filepath.Join(tmpDir, "standup_transcript.md"), "transcript", monday)
+
+	meetings, err := Collect([]string{tmpDir}, "summarize", layout.Default(), monday.Truncate(24*time.Hour), monday.AddDate(0, 0, 7))
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	if len(meetings) != 2 {
+		t.Fatalf("expected 2 meetings, got %d", len(meetings))
+	}
+	if meetings[0].Title != "standup" || meetings[1].Title != "planning" {
+		t.Errorf("unexpected meetings order: %s, %s", meetings[0].Title, meetings[1].Title)
+	}
+	if meetings[0].Summary != "standup summary" {
+		t.Errorf("unexpected summary: %q", meetings[0].Summary)
+	}
+
+	// Summaries written with another layout are found for their recordings
+	outDir := filepath.Join(t.TempDir(), "notes")
+	outputs, err := layout.New(layout.Options{OutputDir: outDir, Roots: []string{tmpDir}, Template: "{{.Title}}/{{.Kind}}.md"})
+	if err != nil {
+		t.Fatalf("layout.New() error = %v", err)
+	}
+	os.MkdirAll(filepath.Join(outDir, "standup"), 0755)
+	writeFile(t, filepath.Join(outDir, "standup", "summarize.md"), "standup notes", monday)
+	meetings, err = Collect([]string{tmpDir}, "summarize", outputs, monday.Truncate(24*time.Hour), monday.AddDate(0, 0, 7))
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	if len(meetings) != 2 || meetings[0].Title != "standup" || meetings[0].Summary != "standup notes" {
+		t.Errorf("expected the summary in the output directory, got %+v", meetings)
+	}
+
+	// Summaries are found in the output directory with the name template
+	dated, err := layout.New(layout.Options{Template: "{{.Date}}-{{.Title}}/{{.Kind}}.md"})
+	if err != nil {
+		t.Fatalf("layout.New() error = %v", err)
+	}
+	os.MkdirAll(filepath.Join(outDir, "2024-03-11-team sync"), 0755)
+	writeFile(t, filepath.Join(outDir, "2024-03-11-team sync", "summarize.md"), "sync notes", monday)
+	meetings, err = Collect([]string{outDir}, "summarize", dated, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	if len(meetings) != 1 || meetings[0].Title != "team sync" || meetings[0].Summary != "sync notes" {
+		t.Errorf("expected the templated summary, got %+v", meetings)
+	}
+}
+
+func TestDigestChunks(t *testing.T) {
+	meetings := []Meeting{
+		{Title: "a", Summary: strings.Repeat("a", 60)},
+		{Title: "b", Summary: strings.Repeat("b", 60)},
+		{Title: "c", Summary: strings.Repeat("c", 60)},
+	}
+
+	client := &countingClient{}
+	d := NewDigester(config.DefaultConfig(), client, 100)
+	got, err := d.Digest(meetings, testPrompt)
+	if err != nil {
+		t.Fatalf("Digest() error = %v", err)
+	}
+	if got != "digest part" {
+		t.Errorf("Digest() = %q", got)
+	}
+	// One request per meeting, plus one to combine the partial digests
+	if len(client.contents) != 4 {
+		t.Fatalf("expected 4 requests, got %d", len(client.contents))
+	}
+	if !strings.Contains(client.contents[3], "digest part\n\ndigest part") {
+		t.Errorf("expected final request to combine partial digests, got %q", client.contents[3])
+	}
+
+	client = &countingClient{}
+	d = NewDigester(config.DefaultConfig(), client, 0)
+	if _, err := d.Digest(meetings, testPrompt); err != nil {
+		t.Fatalf("Digest() error = %v", err)
+	}
+	if len(client.contents) != 1 {
+		t.Errorf("expected a single request, got %d", len(client.contents))
+	}
+}
+
+func TestRender(t *testing.T) {
+	meetings := []Meeting{
+		{Title: "standup", Date: time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC), SummaryPath: "/notes/team/standup_summarize.md"},
+		{Title: "Team Planning", Date: time.Date(2024, 3, 12, 0, 0, 0, 0, time.UTC), SummaryPath: "/notes/team/Team Planning_summarize.md"},
+	}
+	got := Render("Weekly digest", "Everything is fine.", meetings, "/notes/digest.md")
+	if !strings.HasPrefix(got, "# Weekly digest\n\nEverything is fine.") {
+		t.Errorf("unexpected digest header: %q", got)
+	}
+	if !strings.Contains(got, "- 2024-03-11: [standup](team/standup_summarize.md)") {
+		t.Errorf("expected relative link to meeting notes, got %q", got)
+	}
+	if !strings.Contains(got, "- 2024-03-12: [Team Planning](team/Team%20Planning_summarize.md)") {
+		t.Errorf("expected escaped link to meeting notes with spaces, got %q", got)
+	}
+}
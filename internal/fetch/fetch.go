@@ -0,0 +1,399 @@
+package fetch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/giantswarm/mnote/internal/state"
+	"github.com/giantswarm/mnote/internal/utils"
+	"github.com/giantswarm/mnote/internal/work"
+)
+
+// downloadsDir holds the partial downloads and their records in the state directory
+const downloadsDir = "downloads"
+
+// contentTypes maps media types to the extension of downloads whose name has none
+var contentTypes = map[string]string{
+	"video/mp4":        ".mp4",
+	"video/quicktime":  ".mov",
+	"video/x-matroska": ".mkv",
+	"video/x-msvideo":  ".avi",
+	"video/webm":       ".webm",
+	"audio/webm":       ".webm",
+	"audio/mpeg":       ".mp3",
+	"audio/mp4":        ".m4a",
+	"audio/x-m4a":      ".m4a",
+	"audio/wav":        ".wav",
+	"audio/x-wav":      ".wav",
+	"audio/wave":       ".wav",
+	"audio/ogg":        ".ogg",
+	"audio/flac":       ".flac",
+	"audio/opus":       ".opus",
+}
+
+// Options controls a download
+type Options struct {
+	// Client sends the requests, http.DefaultClient if nil
+	Client *http.Client
+	// Progress is called with the bytes downloaded so far, including a resumed part, and
+	// the total size, which is 0 if the server does not report it
+	Progress func(done, total int64)
+}
+
+// Result describes a downloaded recording
+type Result struct {
+	// Path is the local file of the recording
+	Path string
+	// Size is the size of the file
+	Size int64
+	// Resumed is the number of bytes that were kept from an interrupted download
+	Resumed int64
+	// Reused is set if the recording was downloaded by an earlier run
+	Reused bool
+}
+
+// record is saved next to a partial download, so it can be resumed and checked
+type record struct {
+	URL          string `json:"url"`
+	Name         string `json:"name"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	// Size is the Content-Length of the recording, -1 if it is not known
+	Size     int64 `json:"size"`
+	Complete bool  `json:"complete"`
+}
+
+// validator returns the value for If-Range that makes the server send the rest of the
+// recording only if it did not change, empty if the download cannot be resumed safely
+func (r *record) validator() string {
+	if r.ETag != "" && !strings.HasPrefix(r.ETag, "W/") {
+		return r.ETag
+	}
+	return r.LastModified
+}
+
+// IsURL reports whether an input is an HTTP or HTTPS URL
+func IsURL(input string) bool {
+	lower := strings.ToLower(input)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+// Display returns a URL without credentials and query, which often hold access tokens, to
+// show it in logs and errors
+func Display(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	u.User = nil
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String()
+}
+
+// Downloaded returns the file of a recording that was downloaded completely into dir before
+func Downloaded(rawURL, dir string) (string, bool) {
+	d := newDownload(rawURL, dir, Options{})
+	rec, err := d.load()
+	if err != nil || !rec.Complete {
+		return "", false
+	}
+	path := filepath.Join(dir, rec.Name)
+	if info, err := os.Stat(path); err != nil || (rec.Size >= 0 && info.Size() != rec.Size) {
+		return "", false
+	}
+	return path, true
+}
+
+// Download downloads the recording at rawURL into dir, named after the Content-Disposition
+// header or the URL. An interrupted download is resumed with a Range request if the server
+// supports it and the recording did not change, which is checked with its ETag or
+// modification time. The size is verified against the Content-Length. A recording that
+// was downloaded completely before is not downloaded again.
+func Download(rawURL, dir string, opts Options) (*Result, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || !IsURL(rawURL) || u.Host == "" {
+		return nil, fmt.Errorf("invalid URL: %s", Display(rawURL))
+	}
+	if path, ok := Downloaded(rawURL, dir); ok {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat download: %w", err)
+		}
+		return &Result{Path: path, Size: info.Size(), Reused: true}, nil
+	}
+
+	d := newDownload(rawURL, dir, opts)
+	if err := os.MkdirAll(d.stateDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create download directory: %w", err)
+	}
+	rec, err := d.load()
+	if err != nil {
+		return nil, err
+	}
+	if rec.Complete {
+		// The recording was removed or changed locally, download it again
+		rec = &record{}
+	}
+
+	result, err := d.fetch(rec)
+	if errors.Is(err, errRestart) {
+		// The partial download cannot be continued, start over
+		d.discard()
+		result, err = d.fetch(&record{})
+	}
+	return result, err
+}
+
+// errRestart is returned when a partial download has to be discarded
+var errRestart = errors.New("partial download cannot be resumed")
+
+// download is a download in progress
+type download struct {
+	url      string
+	dir      string
+	key      string
+	opts     Options
+	stateDir string
+}
+
+func newDownload(rawURL, dir string, opts Options) *download {
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	return &download{
+		url:      rawURL,
+		dir:      dir,
+		key:      work.Key(rawURL),
+		opts:     opts,
+		stateDir: filepath.Join(dir, state.Dir, downloadsDir),
+	}
+}
+
+func (d *download) recordPath() string {
+	return filepath.Join(d.stateDir, d.key+".json")
+}
+
+func (d *download) partPath() string {
+	return filepath.Join(d.stateDir, d.key+".part")
+}
+
+// load loads the record of an earlier download, a missing record starts a new download
+func (d *download) load() (*record, error) {
+	rec := &record{}
+	data, err := os.ReadFile(d.recordPath())
+	if os.IsNotExist(err) {
+		return rec, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read download record: %w", err)
+	}
+	if err := json.Unmarshal(data, rec); err != nil || rec.URL != d.url {
+		// A broken record cannot vouch for the partial download
+		d.discard()
+		return &record{}, nil
+	}
+	return rec, nil
+}
+
+func (d *download) save(rec *record) error {
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode download record: %w", err)
+	}
+	if err := utils.WriteFile(d.recordPath(), data); err != nil {
+		return fmt.Errorf("failed to save download record: %w", err)
+	}
+	return nil
+}
+
+// discard removes the partial download and its record
+func (d *download) discard() {
+	os.Remove(d.partPath())
+	os.Remove(d.recordPath())
+}
+
+// fetch requests the recording, or the rest of it if a resumable part exists, and writes
+// it to its file
+func (d *download) fetch(rec *record) (*Result, error) {
+	var offset int64
+	if info, err := os.Stat(d.partPath()); err == nil && rec.Name != "" && rec.validator() != "" {
+		offset = info.Size()
+	}
+
+	req, err := http.NewRequest(http.MethodGet, d.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set("If-Range", rec.validator())
+	}
+	resp, err := d.opts.Client.Do(req)
+	if err != nil {
+		// The error of the client repeats the URL with its query
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("failed to download %s: %w", Display(d.url), err)
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		if err := checkRange(resp, rec, offset); err != nil {
+			return nil, err
+		}
+		flags |= os.O_APPEND
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		return nil, errRestart
+	case resp.StatusCode == http.StatusOK:
+		// A new download, or the server sent the whole recording because it changed or
+		// does not support ranges
+		offset = 0
+		if rec, err = d.newRecord(resp); err != nil {
+			return nil, err
+		}
+		flags |= os.O_TRUNC
+	default:
+		return nil, fmt.Errorf("failed to download %s: %s", Display(d.url), resp.Status)
+	}
+	if err := d.save(rec); err != nil {
+		return nil, err
+	}
+
+	part, err := os.OpenFile(d.partPath(), flags, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open partial download: %w", err)
+	}
+	total := rec.Size
+	if total < 0 {
+		total = 0
+	}
+	w := &progressWriter{w: part, done: offset, total: total, progress: d.opts.Progress}
+	_, copyErr := io.Copy(w, resp.Body)
+	if err := part.Sync(); err != nil && copyErr == nil {
+		copyErr = err
+	}
+	if err := part.Close(); err != nil && copyErr == nil {
+		copyErr = err
+	}
+	if copyErr != nil {
+		return nil, fmt.Errorf("download of %s interrupted after %d bytes, run again to resume: %w", Display(d.url), w.done, copyErr)
+	}
+	if rec.Size >= 0 && w.done != rec.Size {
+		return nil, fmt.Errorf("incomplete download of %s: got %d of %d bytes, run again to resume", Display(d.url), w.done, rec.Size)
+	}
+
+	path := filepath.Join(d.dir, rec.Name)
+	if err := os.Rename(d.partPath(), path); err != nil {
+		return nil, fmt.Errorf("failed to save download: %w", err)
+	}
+	rec.Size = w.done
+	rec.Complete = true
+	if err := d.save(rec); err != nil {
+		return nil, err
+	}
+	return &Result{Path: path, Size: w.done, Resumed: offset}, nil
+}
+
+// newRecord records a recording the server sends in full and picks its file name
+func (d *download) newRecord(resp *http.Response) (*record, error) {
+	name, err := fileName(resp)
+	if err != nil {
+		return nil, err
+	}
+	// Do not replace a file that was not downloaded from this URL
+	if _, err := os.Stat(filepath.Join(d.dir, name)); err == nil {
+		ext := filepath.Ext(name)
+		name = strings.TrimSuffix(name, ext) + "-" + d.key[:8] + ext
+	}
+	return &record{
+		URL:          d.url,
+		Name:         name,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Size:         resp.ContentLength,
+	}, nil
+}
+
+// checkRange checks that a partial response continues the recording that was started
+func checkRange(resp *http.Response, rec *record, offset int64) error {
+	if etag := resp.Header.Get("ETag"); etag != "" && rec.ETag != "" && etag != rec.ETag {
+		return errRestart
+	}
+	var start, end, total int64
+	if _, err := fmt.Sscanf(resp.Header.Get("Content-Range"), "bytes %d-%d/%d", &start, &end, &total); err != nil {
+		return errRestart
+	}
+	if start != offset || (rec.Size >= 0 && total != rec.Size) {
+		return errRestart
+	}
+	return nil
+}
+
+// fileName derives the file name of a recording from the Content-Disposition header or
+// the URL path, adding the extension of its Content-Type if the name has no supported one
+func fileName(resp *http.Response) (string, error) {
+	var name string
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+		name = params["filename"]
+	}
+	if name == "" {
+		name = path.Base(resp.Request.URL.Path)
+	}
+	name = sanitize(filepath.Base(filepath.FromSlash(name)))
+	if name == "" {
+		name = sanitize(resp.Request.URL.Hostname())
+	}
+
+	if !utils.IsMediaFile(name) {
+		mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+		ext, ok := contentTypes[mediaType]
+		if !ok {
+			return "", fmt.Errorf("not a supported video or audio file: %s (%s)", Display(resp.Request.URL.String()), resp.Header.Get("Content-Type"))
+		}
+		name += ext
+	}
+	return name, nil
+}
+
+// sanitize makes a name safe as a file name. Leading dots are removed, as hidden files
+// are not processed.
+func sanitize(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	return strings.TrimLeft(strings.TrimSpace(name), ".")
+}
+
+// progressWriter counts the bytes written and reports them
+type progressWriter struct {
+	w        io.Writer
+	done     int64
+	total    int64
+	progress func(done, total int64)
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.done += int64(n)
+	if p.progress != nil {
+		p.progress(p.done, p.total)
+	}
+	return n, err
+}
@@ -0,0 +1,103 @@
+package hooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/giantswarm/mnote/internal/config"
+)
+
+// Event describes the file and stage a hook runs for. It is passed to the command as
+// JSON on stdin and as MNOTE_* environment variables.
+type Event struct {
+	Hook  string `json:"hook"`
+	File  string `json:"file"`
+	Stage string `json:"stage"`
+	// Outputs are the files of the video by kind, for example "audio", "transcript" or "summary"
+	Outputs map[string]string `json:"outputs,omitempty"`
+	Error   string            `json:"error,omitempty"`
+	// Metadata holds details like the language, prompt and model
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Runner runs the configured hook commands
+type Runner struct {
+	hooks  map[string]config.Hook
+	output io.Writer
+	logger *slog.Logger
+}
+
+// NewRunner creates a Runner for the configured hooks, the output of the commands is
+// passed through to stderr to keep stdout clean
+func NewRunner(hooks map[string]config.Hook) *Runner {
+	return &Runner{hooks: hooks, output: os.Stderr}
+}
+
+func (r *Runner) log() *slog.Logger {
+	if r.logger == nil {
+		return slog.Default()
+	}
+	return r.logger
+}
+
+// Run runs the command of the event's hook, if one is configured. A failing command is
+// an error if its policy is fail, otherwise a warning is printed.
+func (r *Runner) Run(event Event) error {
+	hook, ok := r.hooks[event.Hook]
+	if !ok || hook.Command == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s hook event: %w", event.Hook, err)
+	}
+
+	cmd := exec.Command("sh", "-c", hook.Command)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = r.output
+	cmd.Stderr = r.output
+	cmd.Env = append(os.Environ(), Env(event, data)...)
+	r.log().Debug("Running hook", "hook", event.Hook, "file", event.File, "command", hook.Command)
+	if err := cmd.Run(); err != nil {
+		err = fmt.Errorf("%s hook failed: %w", event.Hook, err)
+		if hook.OnFailure == config.HookIgnore {
+			r.log().Warn("Ignoring failed hook", "file", event.File, "error", err)
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// Env returns the environment variables describing an event: MNOTE_HOOK, MNOTE_FILE,
+// MNOTE_STAGE, MNOTE_ERROR, MNOTE_<OUTPUT> for every output, MNOTE_META_<KEY> for the
+// metadata and MNOTE_EVENT with the JSON encoded event
+func Env(event Event, data []byte) []string {
+	env := []string{
+		"MNOTE_HOOK=" + event.Hook,
+		"MNOTE_FILE=" + event.File,
+		"MNOTE_STAGE=" + event.Stage,
+		"MNOTE_ERROR=" + event.Error,
+		"MNOTE_EVENT=" + string(data),
+	}
+	env = append(env, prefixed("MNOTE_", event.Outputs)...)
+	return append(env, prefixed("MNOTE_META_", event.Metadata)...)
+}
+
+// prefixed returns sorted KEY=value pairs with upper case keys
+func prefixed(prefix string, values map[string]string) []string {
+	env := make([]string, 0, len(values))
+	for key, value := range values {
+		env = append(env, prefix+strings.ToUpper(key)+"="+value)
+	}
+	sort.Strings(env)
+	return env
+}
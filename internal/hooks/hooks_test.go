@@ -0,0 +1,90 @@
+package hooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/giantswarm/mnote/internal/config"
+	"github.com/giantswarm/mnote/internal/logging"
+)
+
+func TestRun(t *testing.T) {
+	tmpDir := t.TempDir()
+	eventPath := filepath.Join(tmpDir, "event.json")
+	envPath := filepath.Join(tmpDir, "env.txt")
+
+	var stdout bytes.Buffer
+	r := &Runner{
+		hooks: map[string]config.Hook{
+			config.HookPostSummarize: {
+				Command:   `cat > "` + eventPath + `" && echo "$MNOTE_FILE $MNOTE_SUMMARY $MNOTE_META_PROMPT" > "` + envPath + `"`,
+				OnFailure: config.HookFail,
+			},
+		},
+		output: &stdout,
+	}
+
+	event := Event{
+		Hook:     config.HookPostSummarize,
+		File:     "/videos/standup.mp4",
+		Stage:    "summarize",
+		Outputs:  map[string]string{"summary": "/videos/standup_summarize.md"},
+		Metadata: map[string]string{"prompt": "summarize"},
+	}
+	if err := r.Run(event); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	// The event is passed as JSON on stdin
+	data, err := os.ReadFile(eventPath)
+	if err != nil {
+		t.Fatalf("hook did not run: %v", err)
+	}
+	var got Event
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("invalid event JSON %q: %v", data, err)
+	}
+	if got.File != event.File || got.Outputs["summary"] != event.Outputs["summary"] {
+		t.Errorf("unexpected event: %+v", got)
+	}
+
+	// and in environment variables
+	env, _ := os.ReadFile(envPath)
+	if strings.TrimSpace(string(env)) != "/videos/standup.mp4 /videos/standup_summarize.md summarize" {
+		t.Errorf("unexpected environment: %q", env)
+	}
+
+	// Hooks without command do nothing
+	if err := r.Run(Event{Hook: config.HookPreTranscribe}); err != nil {
+		t.Errorf("Run() without command error = %v", err)
+	}
+}
+
+func TestRunFailurePolicy(t *testing.T) {
+	var log bytes.Buffer
+	r := &Runner{
+		hooks: map[string]config.Hook{
+			config.HookPreTranscribe:  {Command: "exit 3", OnFailure: config.HookFail},
+			config.HookPostTranscribe: {Command: "exit 3", OnFailure: config.HookIgnore},
+		},
+		output: &log,
+		logger: slog.New(logging.NewConsoleHandler(&log, slog.LevelInfo)),
+	}
+
+	err := r.Run(Event{Hook: config.HookPreTranscribe, File: "a.mp4"})
+	if err == nil || !strings.Contains(err.Error(), "pre_transcribe hook failed") {
+		t.Errorf("expected failing hook to fail, got %v", err)
+	}
+
+	if err := r.Run(Event{Hook: config.HookPostTranscribe, File: "a.mp4"}); err != nil {
+		t.Errorf("expected ignored hook failure, got %v", err)
+	}
+	if !strings.Contains(log.String(), "Warning: Ignoring failed hook") || !strings.Contains(log.String(), "post_transcribe hook failed") {
+		t.Errorf("expected a warning for the ignored failure, got %q", log.String())
+	}
+}
@@ -0,0 +1,112 @@
+package index
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/giantswarm/mnote/internal/config"
+	"github.com/giantswarm/mnote/internal/transcribe"
+)
+
+// batchSize is the maximum number of texts embedded in one request
+const batchSize = 64
+
+// Embedder interface defines the contract for text embedding
+type Embedder interface {
+	Embed(texts []string) ([][]float32, error)
+}
+
+// EmbedderImpl implements the Embedder interface for OpenAI compatible embeddings endpoints
+type EmbedderImpl struct {
+	config *config.Config
+	client transcribe.HTTPClient
+}
+
+// NewEmbedder creates a new Embedder instance
+func NewEmbedder(cfg *config.Config) Embedder {
+	return &EmbedderImpl{
+		config: cfg,
+		client: &http.Client{},
+	}
+}
+
+type embeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type embeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+// Embed returns one embedding vector per text
+func (e *EmbedderImpl) Embed(texts []string) ([][]float32, error) {
+	if e.config.EmbeddingsAPIURL == "" {
+		return nil, fmt.Errorf("EMBEDDINGS_API_URL is not configured")
+	}
+
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += batchSize {
+		end := start + batchSize
+		if end > len(texts) {
+			end = len(texts)
+		}
+		batch, err := e.embedBatch(texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, batch...)
+	}
+	return vectors, nil
+}
+
+func (e *EmbedderImpl) embedBatch(texts []string) ([][]float32, error) {
+	body, err := json.Marshal(embeddingRequest{Model: e.config.EmbeddingModel, Input: texts})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", e.config.EmbeddingsAPIURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey := os.Getenv("EMBEDDINGS_API_KEY"); apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result embeddingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(result.Data) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(result.Data))
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, d := range result.Data {
+		if d.Index < 0 || d.Index >= len(texts) {
+			return nil, fmt.Errorf("embedding index out of range: %d", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return vectors, nil
+}
@@ -0,0 +1,64 @@
+package index
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/giantswarm/mnote/internal/config"
+)
+
+func TestEmbed(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		var req embeddingRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+		if req.Model != "test-embedding" {
+			t.Errorf("expected model test-embedding, got %s", req.Model)
+		}
+
+		// Return the embeddings in reverse order to check the index handling
+		var resp embeddingResponse
+		for n := len(req.Input) - 1; n >= 0; n-- {
+			resp.Data = append(resp.Data, struct {
+				Index     int       `json:"index"`
+				Embedding []float32 `json:"embedding"`
+			}{Index: n, Embedding: []float32{float32(len(req.Input[n]))}})
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.EmbeddingsAPIURL = server.URL
+	cfg.EmbeddingModel = "test-embedding"
+
+	texts := make([]string, batchSize+1)
+	for n := range texts {
+		texts[n] = string(make([]byte, n))
+	}
+	vectors, err := NewEmbedder(cfg).Embed(texts)
+	if err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("expected 2 batched requests, got %d", requests)
+	}
+	if len(vectors) != len(texts) {
+		t.Fatalf("expected %d vectors, got %d", len(texts), len(vectors))
+	}
+	for n, v := range vectors {
+		if int(v[0]) != n {
+			t.Fatalf("vector %d does not belong to its text: %v", n, v)
+		}
+	}
+
+	cfg.EmbeddingsAPIURL = ""
+	if _, err := NewEmbedder(cfg).Embed(texts); err == nil {
+		t.Error("Embed() should fail without configured endpoint")
+	}
+}
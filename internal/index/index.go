@@ -0,0 +1,297 @@
+package index
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/giantswarm/mnote/internal/config"
+	"github.com/giantswarm/mnote/internal/lock"
+	"github.com/giantswarm/mnote/internal/transcript"
+	"github.com/giantswarm/mnote/internal/utils"
+)
+
+// Entry is an embedded transcript passage
+type Entry struct {
+	Start  float64   `json:"start"`
+	End    float64   `json:"end"`
+	Text   string    `json:"text"`
+	Vector []float32 `json:"vector"`
+}
+
+// FileEntry holds the embedded passages of one transcript
+type FileEntry struct {
+	Hash    string  `json:"hash"`
+	Entries []Entry `json:"entries"`
+}
+
+// Index is a file based vector index over transcripts
+type Index struct {
+	Model string                `json:"model"`
+	Files map[string]*FileEntry `json:"files"`
+
+	path string
+}
+
+// Result is a passage found by a search
+type Result struct {
+	Passage transcript.Passage
+	Score   float64
+}
+
+// DefaultPath returns the default location of the index file
+func DefaultPath() string {
+	return filepath.Join(os.Getenv("HOME"), ".config", "mnote", "index.json")
+}
+
+// Path returns the configured index path, or the default location if none is set
+func Path(cfg *config.Config) string {
+	if cfg.IndexPath != "" {
+		return cfg.IndexPath
+	}
+	return DefaultPath()
+}
+
+// Load reads the index at the given path. A missing file results in an empty index.
+// If the index was built with another model it is discarded.
+func Load(path, model string) (*Index, error) {
+	idx := &Index{Model: model, Files: make(map[string]*FileEntry), path: path}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return idx, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index: %w", err)
+	}
+
+	var stored Index
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("failed to decode index: %w", err)
+	}
+	if stored.Model == model && stored.Files != nil {
+		idx.Files = stored.Files
+	}
+	return idx, nil
+}
+
+// Save writes the index to its file
+func (i *Index) Save() error {
+	data, err := json.Marshal(i)
+	if err != nil {
+		return fmt.Errorf("failed to encode index: %w", err)
+	}
+
+	if err := utils.WriteFile(i.path, data); err != nil {
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+	return nil
+}
+
+// Commit applies fn to the index as it is on disk and saves it if fn reports a change. The
+// index file is locked meanwhile, so mnote processes sharing it do not lose each other's
+// transcripts. The index takes over the saved files.
+func (i *Index) Commit(fn func(latest *Index) bool) error {
+	return lock.With(i.path+".lock", func() error {
+		latest, err := Load(i.path, i.Model)
+		if err != nil {
+			return err
+		}
+		if !fn(latest) {
+			return nil
+		}
+		if err := latest.Save(); err != nil {
+			return err
+		}
+		i.Files = latest.Files
+		return nil
+	})
+}
+
+// Update embeds the transcript if it is not indexed yet or changed since it was indexed.
+// It reports whether the index was modified.
+func (i *Index) Update(transcriptPath string, embedder Embedder) (bool, error) {
+	absPath, err := filepath.Abs(transcriptPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to resolve path: %w", err)
+	}
+
+	hash, err := hashTranscript(transcriptPath)
+	if err != nil {
+		return false, err
+	}
+	if i.current(absPath, hash) {
+		return false, nil
+	}
+
+	passages, err := transcript.LoadPassages(transcriptPath, transcript.DefaultPassageWords)
+	if err != nil {
+		return false, err
+	}
+
+	texts := make([]string, len(passages))
+	for n, p := range passages {
+		texts[n] = p.Text
+	}
+	vectors, err := embedder.Embed(texts)
+	if err != nil {
+		return false, fmt.Errorf("failed to embed transcript: %w", err)
+	}
+
+	entry := &FileEntry{Hash: hash}
+	for n, p := range passages {
+		entry.Entries = append(entry.Entries, Entry{
+			Start:  p.Start,
+			End:    p.End,
+			Text:   p.Text,
+			Vector: vectors[n],
+		})
+	}
+	i.Files[absPath] = entry
+	return true, nil
+}
+
+// Current reports whether the transcript is indexed in its current version
+func (i *Index) Current(transcriptPath string) (bool, error) {
+	absPath, err := filepath.Abs(transcriptPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to resolve path: %w", err)
+	}
+	hash, err := hashTranscript(transcriptPath)
+	if err != nil {
+		return false, err
+	}
+	return i.current(absPath, hash), nil
+}
+
+func (i *Index) current(absPath, hash string) bool {
+	existing, ok := i.Files[absPath]
+	return ok && existing.Hash == hash
+}
+
+// Prune removes transcripts that no longer exist and reports how many were removed
+func (i *Index) Prune() int {
+	removed := 0
+	for path := range i.Files {
+		if !utils.FileExists(path) {
+			delete(i.Files, path)
+			removed++
+		}
+	}
+	return removed
+}
+
+// Search returns the k passages most similar to the query vector
+func (i *Index) Search(query []float32, k int) []Result {
+	var results []Result
+	for path, file := range i.Files {
+		for _, e := range file.Entries {
+			results = append(results, Result{
+				Passage: transcript.Passage{File: path, Start: e.Start, End: e.End, Text: e.Text},
+				Score:   cosine(query, e.Vector),
+			})
+		}
+	}
+
+	sort.Slice(results, func(a, b int) bool {
+		if results[a].Score != results[b].Score {
+			return results[a].Score > results[b].Score
+		}
+		if results[a].Passage.File != results[b].Passage.File {
+			return results[a].Passage.File < results[b].Passage.File
+		}
+		return results[a].Passage.Start < results[b].Passage.Start
+	})
+	if len(results) > k {
+		results = results[:k]
+	}
+	return results
+}
+
+// hashTranscript hashes the transcript together with its segments file
+func hashTranscript(transcriptPath string) (string, error) {
+	h := sha256.New()
+	data, err := utils.ReadFile(transcriptPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read transcript: %w", err)
+	}
+	h.Write(data)
+	if segments, err := utils.ReadFile(transcript.SegmentsPath(transcriptPath)); err == nil {
+		h.Write(segments)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for n := range a {
+		dot += float64(a[n]) * float64(b[n])
+		normA += float64(a[n]) * float64(a[n])
+		normB += float64(b[n]) * float64(b[n])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// Indexer keeps an index up to date while transcripts are processed
+type Indexer struct {
+	mu       sync.Mutex
+	index    *Index
+	embedder Embedder
+}
+
+// NewIndexer creates a new Indexer for the index at the given path
+func NewIndexer(path, model string, embedder Embedder) (*Indexer, error) {
+	idx, err := Load(path, model)
+	if err != nil {
+		return nil, err
+	}
+	return &Indexer{index: idx, embedder: embedder}, nil
+}
+
+// IndexTranscript adds or refreshes a transcript in the index and saves it if it changed
+func (x *Indexer) IndexTranscript(transcriptPath string) (bool, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	changed, err := x.index.Update(transcriptPath, x.embedder)
+	if err != nil || !changed {
+		return false, err
+	}
+	absPath, err := filepath.Abs(transcriptPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to resolve path: %w", err)
+	}
+	entry := x.index.Files[absPath]
+	err = x.index.Commit(func(latest *Index) bool {
+		latest.Files[absPath] = entry
+		return true
+	})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Indexed reports whether the transcript is indexed in its current version, without changing the index
+func (x *Indexer) Indexed(transcriptPath string) (bool, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.index.Current(transcriptPath)
+}
+
+// Index returns the underlying index
+func (x *Indexer) Index() *Index {
+	return x.index
+}
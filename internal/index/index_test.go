@@ -0,0 +1,137 @@
+package index
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// keywordEmbedder embeds texts by the presence of a fixed set of words
+type keywordEmbedder struct {
+	calls int
+}
+
+var keywords = []string{"database", "deploy", "holiday"}
+
+func (e *keywordEmbedder) Embed(texts []string) ([][]float32, error) {
+	e.calls++
+	vectors := make([][]float32, len(texts))
+	for n, text := range texts {
+		vectors[n] = make([]float32, len(keywords))
+		for k, word := range keywords {
+			if strings.Contains(strings.ToLower(text), word) {
+				vectors[n][k] = 1
+			}
+		}
+	}
+	return vectors, nil
+}
+
+func TestIndexUpdateAndSearch(t *testing.T) {
+	tmpDir := t.TempDir()
+	indexPath := filepath.Join(tmpDir, "index.json")
+	transcriptPath := filepath.Join(tmpDir, "standup_transcript.md")
+	if err := os.WriteFile(transcriptPath, []byte("We talked about the database."), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	embedder := &keywordEmbedder{}
+	indexer, err := NewIndexer(indexPath, "test-model", embedder)
+	if err != nil {
+		t.Fatalf("NewIndexer() error = %v", err)
+	}
+
+	if indexed, err := indexer.Indexed(transcriptPath); err != nil || indexed {
+		t.Fatalf("Indexed() = %v, %v, want false, nil", indexed, err)
+	}
+	changed, err := indexer.IndexTranscript(transcriptPath)
+	if err != nil || !changed {
+		t.Fatalf("IndexTranscript() = %v, %v, want true, nil", changed, err)
+	}
+
+	// Unchanged transcripts are not embedded again
+	changed, err = indexer.IndexTranscript(transcriptPath)
+	if err != nil || changed {
+		t.Fatalf("IndexTranscript() = %v, %v, want false, nil", changed, err)
+	}
+	if embedder.calls != 1 {
+		t.Errorf("expected 1 embedding call, got %d", embedder.calls)
+	}
+	if indexed, err := indexer.Indexed(transcriptPath); err != nil || !indexed {
+		t.Errorf("Indexed() = %v, %v, want true, nil", indexed, err)
+	}
+
+	// The saved index is reused with the same model
+	idx, err := Load(indexPath, "test-model")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	results := idx.Search([]float32{1, 0, 0}, 5)
+	if len(results) != 1 || results[0].Score < 0.99 {
+		t.Fatalf("unexpected search results: %+v", results)
+	}
+	if filepath.Base(results[0].Passage.File) != "standup_transcript.md" {
+		t.Errorf("unexpected result file: %s", results[0].Passage.File)
+	}
+
+	// Changing the model discards the index
+	idx, err = Load(indexPath, "other-model")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(idx.Files) != 0 {
+		t.Error("expected empty index for other model")
+	}
+
+	// Removed transcripts are pruned
+	os.Remove(transcriptPath)
+	if removed := indexer.Index().Prune(); removed != 1 {
+		t.Errorf("Prune() = %d, want 1", removed)
+	}
+}
+
+func TestIndexersSharingIndex(t *testing.T) {
+	tmpDir := t.TempDir()
+	indexPath := filepath.Join(tmpDir, "index.json")
+
+	// Two processes load the index before either of them saved it
+	first, err := NewIndexer(indexPath, "test-model", &keywordEmbedder{})
+	if err != nil {
+		t.Fatalf("NewIndexer() error = %v", err)
+	}
+	second, err := NewIndexer(indexPath, "test-model", &keywordEmbedder{})
+	if err != nil {
+		t.Fatalf("NewIndexer() error = %v", err)
+	}
+	for n, indexer := range []*Indexer{first, second} {
+		path := filepath.Join(tmpDir, []string{"standup", "retro"}[n]+"_transcript.md")
+		os.WriteFile(path, []byte("We talked about the database."), 0644)
+		if _, err := indexer.IndexTranscript(path); err != nil {
+			t.Fatalf("IndexTranscript() error = %v", err)
+		}
+	}
+
+	idx, err := Load(indexPath, "test-model")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(idx.Files) != 2 {
+		t.Errorf("expected both transcripts in the index, got %d", len(idx.Files))
+	}
+	if _, err := os.Stat(indexPath + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("expected the index lock to be released, got %v", err)
+	}
+}
+
+func TestCosine(t *testing.T) {
+	if got := cosine([]float32{1, 0}, []float32{1, 0}); got < 0.999 {
+		t.Errorf("cosine() of equal vectors = %v", got)
+	}
+	if got := cosine([]float32{1, 0}, []float32{0, 1}); got != 0 {
+		t.Errorf("cosine() of orthogonal vectors = %v", got)
+	}
+	if got := cosine([]float32{1}, []float32{1, 0}); got != 0 {
+		t.Errorf("cosine() of mismatched vectors = %v", got)
+	}
+}
@@ -0,0 +1,308 @@
+package layout
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/giantswarm/mnote/internal/utils"
+)
+
+// DefaultTemplate names outputs <name>_<kind>.md next to the source
+const DefaultTemplate = "{{.Title}}_{{.Kind}}.md"
+
+// Output kinds besides summaries, which are named after their prompt
+const (
+	KindTranscript = "transcript"
+	KindRedaction  = "redaction"
+)
+
+// Data is available in naming templates
+type Data struct {
+	// Title is the file name of the source without extension
+	Title string
+	// Date is the modification date of the source as 2006-01-02
+	Date string
+	// Kind is "transcript", "redaction" or the prompt name of a summary
+	Kind string
+}
+
+// Options configures where outputs are written
+type Options struct {
+	// OutputDir receives the outputs, mirroring the source tree below the roots. Empty
+	// writes the outputs next to the sources.
+	OutputDir string
+	// Roots are the directories the source tree is mirrored from
+	Roots []string
+	// Template names the output files relative to the output directory of a source
+	Template string
+	// AudioDir is the cache for extracted audio. Empty keeps the audio next to the source.
+	AudioDir string
+}
+
+// Layout decides where the outputs and the intermediate audio of a source are written
+type Layout struct {
+	outputDir string
+	roots     []string
+	template  *template.Template
+	audioDir  string
+}
+
+// New creates a layout, the template is checked to produce relative paths
+func New(opts Options) (*Layout, error) {
+	if opts.Template == "" {
+		opts.Template = DefaultTemplate
+	}
+	tmpl, err := template.New("name").Option("missingkey=error").Parse(opts.Template)
+	if err != nil {
+		return nil, fmt.Errorf("invalid name template: %w", err)
+	}
+	l := &Layout{template: tmpl}
+
+	sample, err := l.name(Data{Title: "title", Date: "2006-01-02", Kind: KindTranscript})
+	if err != nil {
+		return nil, fmt.Errorf("invalid name template: %w", err)
+	}
+	if filepath.IsAbs(sample) || sample == "." || strings.HasPrefix(sample, "..") || strings.HasSuffix(opts.Template, "/") {
+		return nil, fmt.Errorf("invalid name template %q: must produce a relative file path", opts.Template)
+	}
+	// Transcripts and summaries must not overwrite each other
+	other, _ := l.name(Data{Title: "title", Date: "2006-01-02", Kind: "summarize"})
+	if other == sample {
+		return nil, fmt.Errorf("invalid name template %q: must contain {{.Kind}}", opts.Template)
+	}
+
+	if opts.OutputDir != "" {
+		if l.outputDir, err = filepath.Abs(opts.OutputDir); err != nil {
+			return nil, fmt.Errorf("failed to resolve output directory: %w", err)
+		}
+	}
+	for _, root := range opts.Roots {
+		abs, err := filepath.Abs(root)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %w", root, err)
+		}
+		l.roots = append(l.roots, abs)
+	}
+	l.audioDir = opts.AudioDir
+	return l, nil
+}
+
+// defaultLayout writes <name>_<kind>.md and the audio next to the source
+var defaultLayout, _ = New(Options{})
+
+// Default returns the layout writing <name>_<kind>.md and the audio next to the source
+func Default() *Layout {
+	return defaultLayout
+}
+
+// Dir returns the directory the outputs of a source are written to: the directory of the
+// source, or its mirror in the output directory. Sources outside of the roots are written to
+// a directory named after a hash of their directory and its name, so sources with the same
+// name in different directories do not collide. The state of the source is kept there.
+func (l *Layout) Dir(source string) string {
+	if l.outputDir == "" {
+		return filepath.Dir(source)
+	}
+	abs, err := filepath.Abs(filepath.Dir(source))
+	if err != nil {
+		return l.outputDir
+	}
+	best := ""
+	for _, root := range l.roots {
+		if (abs == root || strings.HasPrefix(abs, root+string(filepath.Separator))) && len(root) > len(best) {
+			best = root
+		}
+	}
+	if best == "" {
+		sum := sha256.Sum256([]byte(abs))
+		return filepath.Join(l.outputDir, hex.EncodeToString(sum[:4])+"-"+filepath.Base(abs))
+	}
+	rel, _ := filepath.Rel(best, abs)
+	return filepath.Join(l.outputDir, rel)
+}
+
+// Path returns the path of an output of a source. The extension of the name produced by
+// the template is replaced with ext.
+func (l *Layout) Path(source, kind, ext string) string {
+	data := Data{
+		Title: strings.TrimSuffix(filepath.Base(source), filepath.Ext(source)),
+		Date:  time.Now().Format("2006-01-02"),
+		Kind:  kind,
+	}
+	if info, err := os.Stat(source); err == nil {
+		data.Date = info.ModTime().Format("2006-01-02")
+	}
+	name, err := l.name(data)
+	if err != nil {
+		// The template was checked by New, fall back to the default name
+		name = data.Title + "_" + kind + ext
+	}
+	name = strings.TrimSuffix(name, filepath.Ext(name)) + ext
+	return filepath.Join(l.Dir(source), filepath.FromSlash(name))
+}
+
+func (l *Layout) name(data Data) (string, error) {
+	var buf bytes.Buffer
+	if err := l.template.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return filepath.Clean(filepath.FromSlash(buf.String())), nil
+}
+
+// Match reports whether output is named by the template for an output of kind, and returns
+// the title and date found in its name
+func (l *Layout) Match(output, kind string) (Data, bool) {
+	data, _, ok := l.match(output, kind)
+	return data, ok
+}
+
+// match matches output against the template for kind and also returns the directory the
+// name produced by the template starts in
+func (l *Layout) match(output, kind string) (Data, string, bool) {
+	// Render the template with markers to find where title and date are in the name
+	const title, date = "\x00title\x00", "\x00date\x00"
+	pattern, err := l.name(Data{Title: title, Date: date, Kind: kind})
+	if err != nil {
+		return Data{}, "", false
+	}
+	pattern = filepath.ToSlash(strings.TrimSuffix(pattern, filepath.Ext(pattern)))
+	expr := strings.NewReplacer(title, "(?P<title>[^/]+?)", date, `(?P<date>\d{4}-\d{2}-\d{2})`).Replace(regexp.QuoteMeta(pattern))
+	re, err := regexp.Compile("(?:^|/)" + expr + "$")
+	if err != nil {
+		return Data{}, "", false
+	}
+
+	slashed := filepath.ToSlash(strings.TrimSuffix(output, filepath.Ext(output)))
+	m := re.FindStringSubmatchIndex(slashed)
+	if m == nil {
+		return Data{}, "", false
+	}
+	seen := map[string]string{}
+	for n, group := range re.SubexpNames() {
+		if group == "" || m[2*n] < 0 {
+			continue
+		}
+		value := slashed[m[2*n]:m[2*n+1]]
+		// A field used more than once must have the same value everywhere
+		if previous, ok := seen[group]; ok && previous != value {
+			return Data{}, "", false
+		}
+		seen[group] = value
+	}
+	start := m[0]
+	if start < len(slashed) && slashed[start] == '/' {
+		start++
+	}
+	return Data{Title: seen["title"], Date: seen["date"], Kind: kind}, filepath.FromSlash(slashed[:start]), true
+}
+
+// Sibling returns the output of another kind belonging to the same source as output, an
+// output of fromKind, by matching its path against the name template. It reports false if
+// the path was not named by the template.
+func (l *Layout) Sibling(output, fromKind, toKind, ext string) (string, bool) {
+	data, dir, ok := l.match(output, fromKind)
+	if !ok {
+		return "", false
+	}
+	data.Kind = toKind
+	name, err := l.name(data)
+	if err != nil {
+		return "", false
+	}
+	name = strings.TrimSuffix(name, filepath.Ext(name)) + ext
+	return filepath.Join(dir, name), true
+}
+
+// Find returns the outputs of a kind with the extension ext below dir, the files whose path
+// relative to dir is a name the template produces. Hidden directories, such as the state
+// directory, are skipped.
+func (l *Layout) Find(dir, kind, ext string) ([]string, error) {
+	sample, err := l.name(Data{Title: "title", Date: "2006-01-02", Kind: kind})
+	if err != nil {
+		return nil, err
+	}
+	depth := strings.Count(filepath.ToSlash(sample), "/")
+
+	var found []string
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && (strings.HasPrefix(d.Name(), ".") || strings.Count(filepath.ToSlash(rel), "/") >= depth) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) != ext {
+			return nil
+		}
+		if _, start, ok := l.match(rel, kind); ok && start == "" {
+			found = append(found, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find outputs in %s: %w", dir, err)
+	}
+	return found, nil
+}
+
+// Transcript returns the path of the transcript of a source
+func (l *Layout) Transcript(source string) string {
+	return l.Path(source, KindTranscript, ".md")
+}
+
+// Summary returns the path of the summary of a source for a prompt
+func (l *Layout) Summary(source, promptName string) string {
+	return l.Path(source, promptName, ".md")
+}
+
+// Redaction returns the path of the redaction mapping of a source
+func (l *Layout) Redaction(source string) string {
+	return l.Path(source, KindRedaction, ".json")
+}
+
+// Audio returns the path of the audio extracted from a source, in the cache if one is
+// configured. Otherwise it is <title>_audio.mp3 next to the source whatever the name
+// template, so it is recognized as audio made from the source. Cached files are named
+// after a hash of the source path to keep sources with the same name apart.
+func (l *Layout) Audio(source string) string {
+	if l.audioDir == "" {
+		return utils.AudioPath(source)
+	}
+	abs, err := filepath.Abs(source)
+	if err != nil {
+		abs = source
+	}
+	sum := sha256.Sum256([]byte(abs))
+	title := strings.TrimSuffix(filepath.Base(source), filepath.Ext(source))
+	return filepath.Join(l.audioDir, hex.EncodeToString(sum[:8])+"-"+title+".mp3")
+}
+
+// CachesAudio reports whether extracted audio is kept in the cache
+func (l *Layout) CachesAudio() bool {
+	return l.audioDir != ""
+}
+
+// DefaultAudioDir returns the default audio cache below the user cache directory
+func DefaultAudioDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find cache directory: %w", err)
+	}
+	return filepath.Join(dir, "mnote", "audio"), nil
+}
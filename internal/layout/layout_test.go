@@ -0,0 +1,193 @@
+package layout
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/mnote/internal/utils"
+)
+
+func TestDefault(t *testing.T) {
+	l := Default()
+	source := filepath.Join("videos", "standup.mp4")
+
+	// The default layout matches the paths mnote always used
+	if got, want := l.Transcript(source), utils.GetOutputPath(source, "transcript"); got != want {
+		t.Errorf("Transcript() = %s, want %s", got, want)
+	}
+	if got, want := l.Summary(source, "retro"), utils.GetOutputPath(source, "retro"); got != want {
+		t.Errorf("Summary() = %s, want %s", got, want)
+	}
+	if got, want := l.Redaction(source), utils.GetOutputPathWithExt(source, "redaction", ".json"); got != want {
+		t.Errorf("Redaction() = %s, want %s", got, want)
+	}
+	if got, want := l.Audio(source), utils.AudioPath(source); got != want {
+		t.Errorf("Audio() = %s, want %s", got, want)
+	}
+	if l.CachesAudio() {
+		t.Error("expected the default layout to keep the audio next to the source")
+	}
+}
+
+func TestOutputDir(t *testing.T) {
+	root := t.TempDir()
+	source := filepath.Join(root, "nas", "2024", "team", "standup.mp4")
+	os.MkdirAll(filepath.Dir(source), 0755)
+	os.WriteFile(source, []byte("video"), 0644)
+	modTime := time.Date(2024, 3, 11, 10, 0, 0, 0, time.Local)
+	os.Chtimes(source, modTime, modTime)
+
+	out := filepath.Join(root, "notes")
+	cache := filepath.Join(root, "cache")
+	l, err := New(Options{
+		OutputDir: out,
+		Roots:     []string{filepath.Join(root, "nas"), filepath.Join(root, "nas", "2024", "other")},
+		Template:  "{{.Date}}-{{.Title}}/{{.Kind}}.md",
+		AudioDir:  cache,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	// The source tree below the root is mirrored
+	if got, want := l.Dir(source), filepath.Join(out, "2024", "team"); got != want {
+		t.Errorf("Dir() = %s, want %s", got, want)
+	}
+	if got, want := l.Summary(source, "summarize"), filepath.Join(out, "2024", "team", "2024-03-11-standup", "summarize.md"); got != want {
+		t.Errorf("Summary() = %s, want %s", got, want)
+	}
+	if got, want := l.Redaction(source), filepath.Join(out, "2024", "team", "2024-03-11-standup", "redaction.json"); got != want {
+		t.Errorf("Redaction() = %s, want %s", got, want)
+	}
+
+	// Audio goes to the cache, named after the source path
+	audio := l.Audio(source)
+	if filepath.Dir(audio) != cache || !strings.HasSuffix(audio, "-standup.mp3") {
+		t.Errorf("unexpected cached audio path: %s", audio)
+	}
+	if other := l.Audio(filepath.Join(root, "nas", "standup.mp4")); other == audio {
+		t.Error("expected sources with the same name to have different cached audio")
+	}
+	if !l.CachesAudio() {
+		t.Error("expected audio to be cached")
+	}
+
+	// Sources outside of the roots get a directory of their own, apart from sources with the
+	// same name elsewhere
+	elsewhere := l.Dir(filepath.Join(root, "elsewhere", "talk.mp4"))
+	if filepath.Dir(elsewhere) != out || !strings.HasSuffix(elsewhere, "-elsewhere") {
+		t.Errorf("unexpected directory for a source outside of the roots: %s", elsewhere)
+	}
+	if l.Dir(filepath.Join(root, "elsewhere", "retro.mp4")) != elsewhere {
+		t.Error("expected sources of the same directory to share their directory")
+	}
+	if other := l.Dir(filepath.Join(root, "other", "elsewhere", "talk.mp4")); other == elsewhere {
+		t.Error("expected sources with the same name in other directories to be kept apart")
+	}
+}
+
+func TestTemplateAudio(t *testing.T) {
+	l, err := New(Options{Template: "{{.Date}}-{{.Title}}/{{.Kind}}.md"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	// Uncached audio stays next to the source where it is recognized, whatever the template
+	source := filepath.Join("videos", "standup.mp4")
+	if got, want := l.Audio(source), filepath.Join("videos", "standup_audio.mp3"); got != want {
+		t.Errorf("Audio() = %s, want %s", got, want)
+	}
+}
+
+func TestSibling(t *testing.T) {
+	dated, err := New(Options{Template: "{{.Date}}-{{.Title}}/{{.Kind}}.md"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	tests := []struct {
+		name   string
+		layout *Layout
+		output string
+		want   string
+	}{
+		{name: "default", layout: Default(), output: filepath.Join("notes", "team_sync_transcript.md"), want: filepath.Join("notes", "team_sync_retro.md")},
+		{name: "not a transcript", layout: Default(), output: filepath.Join("notes", "meeting_notes.txt")},
+		{
+			name:   "template",
+			layout: dated,
+			output: filepath.Join("out", "2024-03-11-standup", "transcript.md"),
+			want:   filepath.Join("out", "2024-03-11-standup", "retro.md"),
+		},
+		{name: "template elsewhere", layout: dated, output: filepath.Join("out", "standup_transcript.md")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.layout.Sibling(tt.output, KindTranscript, "retro", ".md")
+			if ok != (tt.want != "") || got != tt.want {
+				t.Errorf("Sibling() = %q, %v, want %q", got, ok, tt.want)
+			}
+		})
+	}
+
+	// A summary leads back to its transcript
+	if got, ok := dated.Sibling(filepath.Join("2024-03-11-standup", "retro.md"), "retro", KindTranscript, ".md"); !ok || got != filepath.Join("2024-03-11-standup", "transcript.md") {
+		t.Errorf("Sibling() of a summary = %q, %v", got, ok)
+	}
+}
+
+func TestFind(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		filepath.Join("2024-03-11-team standup", "transcript.md"),
+		filepath.Join("2024-03-11-team standup", "summarize.md"),
+		filepath.Join("2024-03-12-retro", "transcript.md"),
+		filepath.Join("old", "deep", "transcript.md"),
+		filepath.Join(".mnote", "transcript.md"),
+		"planning_transcript.md",
+	} {
+		os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755)
+		os.WriteFile(filepath.Join(dir, name), []byte("text"), 0644)
+	}
+
+	dated, err := New(Options{Template: "{{.Date}}-{{.Title}}/{{.Kind}}.md"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	got, err := dated.Find(dir, KindTranscript, ".md")
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	want := []string{
+		filepath.Join(dir, "2024-03-11-team standup", "transcript.md"),
+		filepath.Join(dir, "2024-03-12-retro", "transcript.md"),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Find() = %v, want %v", got, want)
+	}
+	if data, ok := dated.Match(got[0], KindTranscript); !ok || data.Title != "team standup" || data.Date != "2024-03-11" {
+		t.Errorf("Match() = %+v, %v", data, ok)
+	}
+
+	got, _ = Default().Find(dir, KindTranscript, ".md")
+	if len(got) != 1 || got[0] != filepath.Join(dir, "planning_transcript.md") {
+		t.Errorf("Find() with the default template = %v", got)
+	}
+}
+
+func TestNewInvalidTemplate(t *testing.T) {
+	for _, template := range []string{
+		"{{.Title",
+		"{{.Missing}}_{{.Kind}}.md",
+		"/abs/{{.Kind}}.md",
+		"../{{.Title}}/{{.Kind}}.md",
+		"{{.Title}}.md",
+	} {
+		if _, err := New(Options{Template: template}); err == nil {
+			t.Errorf("expected template %q to be rejected", template)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package lock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// DefaultStaleAfter is how long a lock is kept without a heartbeat before another process
+// may take it over
+const DefaultStaleAfter = 10 * time.Minute
+
+// PollInterval is how often Wait checks whether a lock was released
+var PollInterval = time.Second
+
+// UpdateStaleAfter is how long a lock taken by With is kept without a heartbeat, and
+// UpdatePollInterval how often With checks whether it was released. Updates are short, so
+// they are polled often.
+var (
+	UpdateStaleAfter   = time.Minute
+	UpdatePollInterval = 10 * time.Millisecond
+)
+
+// Holder identifies the process holding a lock, it is written to the lock file
+type Holder struct {
+	PID     int       `json:"pid"`
+	Host    string    `json:"host"`
+	Started time.Time `json:"started"`
+}
+
+func (h Holder) String() string {
+	if h.PID == 0 {
+		return "another process"
+	}
+	return fmt.Sprintf("pid %d on %s since %s", h.PID, h.Host, h.Started.Format(time.RFC3339))
+}
+
+// LockedError is returned when a lock is held by another process
+type LockedError struct {
+	Path   string
+	Holder Holder
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%s is locked by %s", e.Path, e.Holder)
+}
+
+// IsLocked reports whether err is a LockedError
+func IsLocked(err error) bool {
+	var locked *LockedError
+	return errors.As(err, &locked)
+}
+
+// Lock is a lock file held by this process. Its modification time is refreshed while it
+// is held, so locks of crashed processes on other hosts become stale.
+type Lock struct {
+	path string
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Acquire creates the lock file at path, returning a LockedError if another live process
+// holds it. Locks of processes that no longer run on this host, and locks without a
+// heartbeat for staleAfter, are stale and taken over.
+func Acquire(path string, staleAfter time.Duration) (*Lock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+	// A second attempt follows the removal of a stale lock
+	for attempt := 0; attempt < 2; attempt++ {
+		err := create(path)
+		if err == nil {
+			return hold(path, staleAfter), nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to create lock file: %w", err)
+		}
+
+		holder, stale := inspect(path, staleAfter)
+		if !stale {
+			return nil, &LockedError{Path: path, Holder: holder}
+		}
+		if err := breakStale(path, staleAfter); err != nil {
+			return nil, err
+		}
+	}
+	holder, _ := inspect(path, staleAfter)
+	return nil, &LockedError{Path: path, Holder: holder}
+}
+
+// Wait acquires the lock at path like Acquire, waiting until another process releases it
+func Wait(path string, staleAfter time.Duration) (*Lock, error) {
+	return wait(path, staleAfter, PollInterval)
+}
+
+// With holds the lock at path while fn runs, waiting for other processes to release it.
+// It guards the load, change and save of a file that several mnote processes update.
+func With(path string, fn func() error) error {
+	l, err := wait(path, UpdateStaleAfter, UpdatePollInterval)
+	if err != nil {
+		return err
+	}
+	fnErr := fn()
+	if err := l.Release(); err != nil && fnErr == nil {
+		return err
+	}
+	return fnErr
+}
+
+func wait(path string, staleAfter, interval time.Duration) (*Lock, error) {
+	for {
+		l, err := Acquire(path, staleAfter)
+		if !IsLocked(err) {
+			return l, err
+		}
+		time.Sleep(interval)
+	}
+}
+
+// Release stops the heartbeat and removes the lock file
+func (l *Lock) Release() error {
+	close(l.stop)
+	<-l.done
+	if err := os.Remove(l.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove lock file: %w", err)
+	}
+	return nil
+}
+
+// create writes a new lock file for this process, failing if the file exists
+func create(path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	host, _ := os.Hostname()
+	data, _ := json.Marshal(Holder{PID: os.Getpid(), Host: host, Started: time.Now()})
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(path)
+		return err
+	}
+	return f.Close()
+}
+
+// hold starts refreshing the modification time of the lock file
+func hold(path string, staleAfter time.Duration) *Lock {
+	l := &Lock{path: path, stop: make(chan struct{}), done: make(chan struct{})}
+	go func() {
+		defer close(l.done)
+		ticker := time.NewTicker(staleAfter / 4)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-l.stop:
+				return
+			case now := <-ticker.C:
+				os.Chtimes(path, now, now)
+			}
+		}
+	}()
+	return l
+}
+
+// inspect reads the holder of a lock and reports whether the lock is stale. A lock file
+// that cannot be parsed, for example because its holder crashed while writing it, is
+// judged by its age alone.
+func inspect(path string, staleAfter time.Duration) (Holder, bool) {
+	var holder Holder
+	info, err := os.Stat(path)
+	if err != nil {
+		// Released in the meantime
+		return holder, os.IsNotExist(err)
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(data, &holder)
+	}
+	if time.Since(info.ModTime()) > staleAfter {
+		return holder, true
+	}
+	host, _ := os.Hostname()
+	if holder.PID != 0 && holder.Host == host && !alive(holder.PID) {
+		return holder, true
+	}
+	return holder, false
+}
+
+// breakStale removes a stale lock. The lock is moved aside first and checked again, so a
+// lock that another process created after it broke the same stale lock is put back.
+func breakStale(path string, staleAfter time.Duration) error {
+	aside := path + "." + strconv.Itoa(os.Getpid()) + ".stale"
+	if err := os.Rename(path, aside); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to remove stale lock: %w", err)
+	}
+	defer os.Remove(aside)
+	if _, stale := inspect(aside, staleAfter); !stale {
+		// Link fails if yet another process holds the lock by now, which keeps it
+		os.Link(aside, path)
+	}
+	return nil
+}
+
+// alive reports whether a process with the pid runs on this host
+func alive(pid int) bool {
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = proc.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
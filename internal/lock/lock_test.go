@@ -0,0 +1,149 @@
+package lock
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAcquire(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".mnote", "video.mp4.lock")
+
+	l, err := Acquire(path, time.Minute)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	if _, err := Acquire(path, time.Minute); !IsLocked(err) {
+		t.Fatalf("Acquire() of a held lock error = %v, want a LockedError", err)
+	}
+
+	if err := l.Release(); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected the lock file to be removed, got %v", err)
+	}
+	l, err = Acquire(path, time.Minute)
+	if err != nil {
+		t.Fatalf("Acquire() after Release() error = %v", err)
+	}
+	l.Release()
+}
+
+func TestAcquireStale(t *testing.T) {
+	host, _ := os.Hostname()
+	tests := []struct {
+		name   string
+		holder Holder
+		age    time.Duration
+		stale  bool
+	}{
+		{
+			name:   "live process",
+			holder: Holder{PID: os.Getpid(), Host: host},
+			stale:  false,
+		},
+		{
+			name:   "process on another host",
+			holder: Holder{PID: 1, Host: "elsewhere"},
+			stale:  false,
+		},
+		{
+			name:   "process no longer running",
+			holder: Holder{PID: 1 << 30, Host: host},
+			stale:  true,
+		},
+		{
+			name:   "no heartbeat",
+			holder: Holder{PID: 1, Host: "elsewhere"},
+			age:    2 * time.Minute,
+			stale:  true,
+		},
+		{
+			name:  "unreadable lock",
+			age:   2 * time.Minute,
+			stale: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "video.mp4.lock")
+			data, _ := json.Marshal(tt.holder)
+			if tt.holder.PID == 0 {
+				data = []byte("{")
+			}
+			os.WriteFile(path, data, 0644)
+			modified := time.Now().Add(-tt.age)
+			os.Chtimes(path, modified, modified)
+
+			l, err := Acquire(path, time.Minute)
+			if tt.stale {
+				if err != nil {
+					t.Fatalf("Acquire() of a stale lock error = %v", err)
+				}
+				l.Release()
+				return
+			}
+			if !IsLocked(err) {
+				t.Fatalf("Acquire() error = %v, want a LockedError", err)
+			}
+		})
+	}
+}
+
+func TestWait(t *testing.T) {
+	PollInterval = 10 * time.Millisecond
+	defer func() { PollInterval = time.Second }()
+
+	path := filepath.Join(t.TempDir(), "video.mp4.lock")
+	held, err := Acquire(path, time.Minute)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		held.Release()
+	}()
+
+	l, err := Wait(path, time.Minute)
+	if err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	l.Release()
+}
+
+func TestWith(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json.lock")
+	held, err := Acquire(path, time.Minute)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+
+	// fn only runs once the other holder released the lock
+	released := make(chan struct{})
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(released)
+		held.Release()
+	}()
+	err = With(path, func() error {
+		select {
+		case <-released:
+		default:
+			t.Error("expected fn to run after the lock was released")
+		}
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("expected the lock to be held while fn runs: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("With() error = %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected the lock to be released, got %v", err)
+	}
+}
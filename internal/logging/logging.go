@@ -0,0 +1,224 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Log formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Attribute keys shared by the log records of the pipeline
+const (
+	// FileKey is the source file a record is about
+	FileKey = "file"
+	// StageKey is the pipeline stage a record is about
+	StageKey = "stage"
+	// LabelKey is a short name of the file, shown in front of console lines of concurrent runs
+	LabelKey = "label"
+)
+
+// Options configures the logger
+type Options struct {
+	// Quiet only logs warnings and errors to the console
+	Quiet bool
+	// Verbose logs debug details
+	Verbose bool
+	// Format is "text" for human readable lines or "json" for one JSON object per line
+	Format string
+	// File receives the log in addition to the console, appended to
+	File string
+}
+
+// Setup makes the configured logger the default slog logger. The console log is written
+// to w, usually stderr, so stdout is left to the output of commands. A log file is kept
+// open for the lifetime of the process.
+func Setup(opts Options, w io.Writer) error {
+	if opts.Quiet && opts.Verbose {
+		return errors.New("--quiet and --verbose cannot be used together")
+	}
+
+	level := slog.LevelInfo
+	switch {
+	case opts.Quiet:
+		level = slog.LevelWarn
+	case opts.Verbose:
+		level = slog.LevelDebug
+	}
+
+	var handlers []slog.Handler
+	switch opts.Format {
+	case FormatText, "":
+		handlers = append(handlers, NewConsoleHandler(w, level))
+	case FormatJSON:
+		handlers = append(handlers, slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level}))
+	default:
+		return fmt.Errorf("invalid log format: %s (supported: %s, %s)", opts.Format, FormatText, FormatJSON)
+	}
+
+	if opts.File != "" {
+		f, err := os.OpenFile(opts.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open log file: %w", err)
+		}
+		// The file keeps the details of unattended runs even with --quiet
+		fileLevel := slog.LevelInfo
+		if opts.Verbose {
+			fileLevel = slog.LevelDebug
+		}
+		fileOpts := &slog.HandlerOptions{Level: fileLevel}
+		if opts.Format == FormatJSON {
+			handlers = append(handlers, slog.NewJSONHandler(f, fileOpts))
+		} else {
+			handlers = append(handlers, slog.NewTextHandler(f, fileOpts))
+		}
+	}
+
+	if len(handlers) == 1 {
+		slog.SetDefault(slog.New(handlers[0]))
+	} else {
+		slog.SetDefault(slog.New(multiHandler(handlers)))
+	}
+	return nil
+}
+
+// ConsoleHandler writes records as short human readable lines: the label of the file in
+// brackets if there is one, the message and the remaining attributes as key=value pairs.
+// Warnings and errors are marked as such, the file attribute and times are left out.
+type ConsoleHandler struct {
+	mu    *sync.Mutex
+	w     io.Writer
+	level slog.Leveler
+	label string
+	attrs []slog.Attr
+	group string
+}
+
+// NewConsoleHandler creates a ConsoleHandler writing records of at least level to w
+func NewConsoleHandler(w io.Writer, level slog.Leveler) *ConsoleHandler {
+	return &ConsoleHandler{mu: &sync.Mutex{}, w: w, level: level}
+}
+
+// Enabled implements slog.Handler
+func (h *ConsoleHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle implements slog.Handler
+func (h *ConsoleHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	label := h.label
+	var attrs []slog.Attr
+	r.Attrs(func(a slog.Attr) bool {
+		if a.Key == LabelKey && h.group == "" {
+			label = a.Value.String()
+		} else {
+			attrs = append(attrs, h.qualify(a))
+		}
+		return true
+	})
+	if label != "" {
+		b.WriteString("[" + label + "] ")
+	}
+	switch {
+	case r.Level >= slog.LevelError:
+		b.WriteString("Error: ")
+	case r.Level >= slog.LevelWarn:
+		b.WriteString("Warning: ")
+	}
+	b.WriteString(r.Message)
+	for _, a := range append(append([]slog.Attr{}, h.attrs...), attrs...) {
+		if a.Key == FileKey || a.Equal(slog.Attr{}) {
+			continue
+		}
+		b.WriteString(" " + a.Key + "=" + quote(a.Value.String()))
+	}
+	b.WriteString("\n")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, b.String())
+	return err
+}
+
+// WithAttrs implements slog.Handler
+func (h *ConsoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.attrs = append([]slog.Attr{}, h.attrs...)
+	for _, a := range attrs {
+		if a.Key == LabelKey && h.group == "" {
+			c.label = a.Value.String()
+			continue
+		}
+		c.attrs = append(c.attrs, h.qualify(a))
+	}
+	return &c
+}
+
+// WithGroup implements slog.Handler, keys of the group are prefixed with its name
+func (h *ConsoleHandler) WithGroup(name string) slog.Handler {
+	c := *h
+	c.group = h.group + name + "."
+	return &c
+}
+
+func (h *ConsoleHandler) qualify(a slog.Attr) slog.Attr {
+	a.Key = h.group + a.Key
+	return a
+}
+
+// quote quotes values that would be ambiguous in a key=value list
+func quote(s string) string {
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// multiHandler passes records to several handlers
+type multiHandler []slog.Handler
+
+func (m multiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range m {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m multiHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, h := range m {
+		if h.Enabled(ctx, r.Level) {
+			errs = append(errs, h.Handle(ctx, r.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m multiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(multiHandler, len(m))
+	for i, h := range m {
+		handlers[i] = h.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+func (m multiHandler) WithGroup(name string) slog.Handler {
+	handlers := make(multiHandler, len(m))
+	for i, h := range m {
+		handlers[i] = h.WithGroup(name)
+	}
+	return handlers
+}
@@ -0,0 +1,82 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConsoleHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewConsoleHandler(&buf, slog.LevelInfo))
+
+	logger.Info("Transcript saved", FileKey, "/videos/a.mp4", StageKey, "transcribe", "path", "/videos/a transcript.md")
+	logger.With(FileKey, "/videos/b.mp4", LabelKey, "b.mp4").Warn("Failed to index transcript", "error", "timeout")
+	logger.Debug("Not shown")
+
+	want := "Transcript saved stage=transcribe path=\"/videos/a transcript.md\"\n" +
+		"[b.mp4] Warning: Failed to index transcript error=timeout\n"
+	if buf.String() != want {
+		t.Errorf("unexpected console output:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestSetup(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr bool
+	}{
+		{name: "text", opts: Options{Format: FormatText}},
+		{name: "json", opts: Options{Format: FormatJSON}},
+		{name: "invalid format", opts: Options{Format: "xml"}, wantErr: true},
+		{name: "quiet and verbose", opts: Options{Quiet: true, Verbose: true}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Setup(tt.opts, &buf); (err != nil) != tt.wantErr {
+				t.Errorf("Setup() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSetupLevels(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+	logFile := filepath.Join(t.TempDir(), "mnote.log")
+
+	// Quiet keeps the console to warnings while the file gets the progress
+	var console bytes.Buffer
+	if err := Setup(Options{Quiet: true, Format: FormatJSON, File: logFile}, &console); err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+	slog.Info("Summary saved", FileKey, "a.mp4", StageKey, "summarize")
+	slog.Warn("Failed to update state")
+
+	if strings.Contains(console.String(), "Summary saved") || !strings.Contains(console.String(), "Failed to update state") {
+		t.Errorf("expected only the warning on the console, got %q", console.String())
+	}
+
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatalf("failed to read log file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 records in the log file, got %d: %s", len(lines), data)
+	}
+	var record map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatalf("invalid JSON record %q: %v", lines[0], err)
+	}
+	if record["msg"] != "Summary saved" || record[FileKey] != "a.mp4" || record[StageKey] != "summarize" || record["level"] != "INFO" {
+		t.Errorf("unexpected record: %v", record)
+	}
+}
@@ -0,0 +1,115 @@
+package process
+
+import (
+	"log/slog"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"github.com/giantswarm/mnote/internal/logging"
+)
+
+// Limits holds the maximum number of files in each stage at the same time, 0 means no limit
+type Limits struct {
+	Extract    int
+	Transcribe int
+	Summarize  int
+}
+
+// SetLimits limits how many files run through the ffmpeg, transcription and summarization stages at once
+func (p *Processor) SetLimits(limits Limits) {
+	p.extractSlots = newSlots(limits.Extract)
+	p.transcribeSlots = newSlots(limits.Transcribe)
+	p.summarizeSlots = newSlots(limits.Summarize)
+}
+
+// BatchOptions holds the options for processing several videos
+type BatchOptions struct {
+	// Jobs is the number of videos processed at the same time
+	Jobs int
+	// KeepGoing continues with the remaining videos after a failure
+	KeepGoing bool
+}
+
+// ProcessVideos processes the videos with up to batch.Jobs files at the same time and
+// returns a result per video in input order. Without KeepGoing no further files are
+// started once a file failed. With more than one job every log record is labelled with
+// the file name.
+func (p *Processor) ProcessVideos(paths []string, opts Options, batch BatchOptions) []*Result {
+	jobs := batch.Jobs
+	if jobs < 1 {
+		jobs = 1
+	}
+
+	labels := logLabels(paths)
+	results := make([]*Result, len(paths))
+	for i, path := range paths {
+		results[i] = &Result{Path: path}
+	}
+
+	p.addToProgress(paths)
+
+	var failed atomic.Bool
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < jobs && w < len(paths); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				if failed.Load() && !batch.KeepGoing {
+					continue
+				}
+				fileOpts := opts
+				if jobs > 1 {
+					fileOpts.logger = slog.With(logging.FileKey, paths[i], logging.LabelKey, labels[i])
+				}
+				if results[i] = p.ProcessVideoResult(paths[i], fileOpts); results[i].Err != nil {
+					failed.Store(true)
+				}
+			}
+		}()
+	}
+
+	for i := range paths {
+		if failed.Load() && !batch.KeepGoing {
+			break
+		}
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	return results
+}
+
+// logLabels returns the file names identifying the videos in log lines, or the
+// full paths if videos in different directories share a name
+func logLabels(paths []string) []string {
+	seen := map[string]bool{}
+	labels := make([]string, len(paths))
+	for i, path := range paths {
+		labels[i] = filepath.Base(path)
+		if seen[labels[i]] {
+			return append([]string{}, paths...)
+		}
+		seen[labels[i]] = true
+	}
+	return labels
+}
+
+func newSlots(n int) chan struct{} {
+	if n <= 0 {
+		return nil
+	}
+	return make(chan struct{}, n)
+}
+
+// acquire takes a slot and returns the function releasing it, a nil slot channel never blocks
+func acquire(slots chan struct{}) func() {
+	if slots == nil {
+		return func() {}
+	}
+	slots <- struct{}{}
+	return func() { <-slots }
+}
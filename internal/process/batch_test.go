@@ -0,0 +1,153 @@
+package process
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/mnote/internal/config"
+	"github.com/giantswarm/mnote/internal/transcribe"
+)
+
+// concurrentTranscriber records the highest number of concurrent transcriptions
+type concurrentTranscriber struct {
+	mu      sync.Mutex
+	active  int
+	max     int
+	failing string
+}
+
+func (m *concurrentTranscriber) TranscribeAudio(audioPath, language string) (*transcribe.TranscriptionResult, error) {
+	m.mu.Lock()
+	m.active++
+	if m.active > m.max {
+		m.max = m.active
+	}
+	m.mu.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+
+	m.mu.Lock()
+	m.active--
+	m.mu.Unlock()
+
+	name := strings.TrimSuffix(filepath.Base(audioPath), "_audio.mp3")
+	if name == m.failing {
+		return nil, fmt.Errorf("corrupt audio")
+	}
+	return &transcribe.TranscriptionResult{Text: "Transcript of " + name}, nil
+}
+
+// echoSummarizer returns the transcript as its summary
+type echoSummarizer struct{}
+
+func (echoSummarizer) SummarizeTranscript(transcript, promptName string, forceRebuild bool) (string, error) {
+	return "Summary: " + transcript, nil
+}
+
+// setupBatch creates videos with already extracted audio, so that ffmpeg is not needed
+func setupBatch(t *testing.T, n int) []string {
+	t.Helper()
+	dir := t.TempDir()
+	var paths []string
+	for i := 0; i < n; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("video%d.mp4", i))
+		os.WriteFile(path, []byte("video"), 0644)
+		os.WriteFile(strings.TrimSuffix(path, ".mp4")+"_audio.mp3", []byte("audio"), 0644)
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+func TestProcessVideos(t *testing.T) {
+	setupTestPrompt(t, "test prompt")
+	opts := Options{Language: "en", PromptName: "test"}
+
+	// Sequential run as reference
+	sequential := setupBatch(t, 6)
+	processor := NewProcessor(config.DefaultConfig(), &concurrentTranscriber{}, echoSummarizer{})
+	if failed := Failed(processor.ProcessVideos(sequential, opts, BatchOptions{Jobs: 1})); len(failed) > 0 {
+		t.Fatalf("ProcessVideos() sequential error = %v", failed[0].Err)
+	}
+
+	concurrent := setupBatch(t, 6)
+	transcriber := &concurrentTranscriber{}
+	processor = NewProcessor(config.DefaultConfig(), transcriber, echoSummarizer{})
+	processor.SetLimits(Limits{Transcribe: 2})
+	if failed := Failed(processor.ProcessVideos(concurrent, opts, BatchOptions{Jobs: 4})); len(failed) > 0 {
+		t.Fatalf("ProcessVideos() concurrent error = %v", failed[0].Err)
+	}
+
+	if transcriber.max > 2 {
+		t.Errorf("expected at most 2 concurrent transcriptions, got %d", transcriber.max)
+	}
+	for i := range concurrent {
+		want := readSummary(t, strings.TrimSuffix(sequential[i], ".mp4")+"_test.md")
+		got := readSummary(t, strings.TrimSuffix(concurrent[i], ".mp4")+"_test.md")
+		if got != want {
+			t.Errorf("summary %d differs from sequential run: got %q, want %q", i, got, want)
+		}
+	}
+}
+
+func TestProcessVideosKeepGoing(t *testing.T) {
+	setupTestPrompt(t, "test prompt")
+	opts := Options{Language: "en", PromptName: "test"}
+
+	paths := setupBatch(t, 4)
+	processor := NewProcessor(config.DefaultConfig(), &concurrentTranscriber{failing: "video1"}, echoSummarizer{})
+	results := processor.ProcessVideos(paths, opts, BatchOptions{Jobs: 1, KeepGoing: true})
+
+	want := []string{StatusSucceeded, StatusFailed, StatusSucceeded, StatusSucceeded}
+	for i, r := range results {
+		if r.Status() != want[i] {
+			t.Errorf("video %d: expected status %s, got %s", i, want[i], r.Status())
+		}
+	}
+	failed := Failed(results)
+	if len(failed) != 1 || !strings.Contains(failed[0].Err.Error(), "corrupt audio") {
+		t.Fatalf("expected one failure with the transcription error, got %v", failed)
+	}
+	last := failed[0].Stages[len(failed[0].Stages)-1]
+	if last.Stage != StageTranscribe || last.Status != StatusFailed {
+		t.Errorf("expected the transcribe stage to fail, got %+v", last)
+	}
+
+	// A second run skips the finished videos
+	results = processor.ProcessVideos(paths, opts, BatchOptions{Jobs: 2, KeepGoing: true})
+	if results[0].Status() != StatusSkipped {
+		t.Errorf("expected finished video to be skipped, got %s", results[0].Status())
+	}
+
+	var report strings.Builder
+	if err := WriteReport(&report, results); err != nil {
+		t.Fatalf("WriteReport() error = %v", err)
+	}
+	if !strings.Contains(report.String(), "0 succeeded, 3 skipped, 1 failed") {
+		t.Errorf("unexpected report totals:\n%s", report.String())
+	}
+	if !strings.Contains(report.String(), "transcribe:failed") {
+		t.Errorf("report does not show the failed stage:\n%s", report.String())
+	}
+}
+
+func TestProcessVideosStopOnError(t *testing.T) {
+	setupTestPrompt(t, "test prompt")
+
+	paths := setupBatch(t, 3)
+	processor := NewProcessor(config.DefaultConfig(), &concurrentTranscriber{failing: "video0"}, echoSummarizer{})
+	results := processor.ProcessVideos(paths, Options{Language: "en", PromptName: "test"}, BatchOptions{Jobs: 1})
+
+	if results[0].Status() != StatusFailed {
+		t.Errorf("expected first video to fail, got %s", results[0].Status())
+	}
+	for _, r := range results[1:] {
+		if r.Status() != StatusNotStarted {
+			t.Errorf("expected %s not to be started, got %s", r.Path, r.Status())
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package process
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/mnote/internal/logging"
+	"github.com/giantswarm/mnote/internal/progress"
+	"github.com/giantswarm/mnote/internal/prompts"
+	"github.com/giantswarm/mnote/internal/state"
+	"github.com/giantswarm/mnote/internal/transcribe"
+	"github.com/giantswarm/mnote/internal/transcript"
+	"github.com/giantswarm/mnote/internal/utils"
+	"github.com/giantswarm/mnote/internal/work"
+)
+
+// chunk is a part of the audio of a recording that is transcribed on its own
+type chunk struct {
+	start  time.Duration
+	length time.Duration
+}
+
+// audioChunks splits audio longer than the configured chunk length into chunks. A short
+// remainder is added to the last chunk. Audio of unknown duration is a single chunk.
+func (p *Processor) audioChunks(audioPath string, opts Options) []chunk {
+	length := time.Duration(p.config.TranscriptionChunkMinutes) * time.Minute
+	if length <= 0 {
+		return []chunk{{}}
+	}
+	duration, err := utils.MediaDuration(audioPath)
+	if err != nil {
+		opts.log().Debug("Transcribing audio of unknown duration at once", logging.StageKey, StageTranscribe, "error", err)
+		return []chunk{{}}
+	}
+	if duration <= length+length/10 {
+		return []chunk{{}}
+	}
+
+	var chunks []chunk
+	for start := time.Duration(0); start < duration; start += length {
+		if duration-start <= length+length/10 {
+			chunks = append(chunks, chunk{start: start, length: duration - start})
+			break
+		}
+		chunks = append(chunks, chunk{start: start, length: length})
+	}
+	return chunks
+}
+
+// transcribe transcribes the audio of a video. Long audio is transcribed in chunks whose
+// results are kept in the work directory, so an interrupted run resumes with the first
+// chunk that was not transcribed yet.
+func (p *Processor) transcribe(path, audioPath string, opts Options) (*transcribe.TranscriptionResult, error) {
+	chunks := p.audioChunks(audioPath, opts)
+	task := p.progress().Start(path, StageTranscribe, int64(len(chunks)), progress.UnitChunks)
+	defer task.Done()
+	if len(chunks) == 1 {
+		result, err := p.transcribeAudio(path, audioPath, opts)
+		task.Add(1)
+		return result, err
+	}
+
+	wd, err := p.openWork(path, opts)
+	if err != nil {
+		return nil, err
+	}
+	key := work.Key(p.config.GetWhisperModel(opts.Language), opts.Language, strconv.Itoa(p.config.TranscriptionChunkMinutes))
+	results := make([]*transcribe.TranscriptionResult, len(chunks))
+	for i, c := range chunks {
+		name := fmt.Sprintf("transcript-%s-%03d.json", key, i)
+		if !opts.Force.Has(ForceTranscript) {
+			var saved transcribe.TranscriptionResult
+			if found, err := wd.Load(name, &saved); err != nil {
+				opts.log().Warn("Ignoring saved chunk transcript", logging.StageKey, StageTranscribe, "chunk", i+1, "error", err)
+			} else if found {
+				opts.log().Debug("Resuming with saved chunk transcript", logging.StageKey, StageTranscribe, "chunk", i+1)
+				results[i] = &saved
+				task.Add(1)
+				continue
+			}
+		}
+
+		opts.log().Info("Transcribing chunk", logging.StageKey, StageTranscribe,
+			"chunk", fmt.Sprintf("%d/%d", i+1, len(chunks)), "start", c.start)
+		chunkPath := wd.Path(fmt.Sprintf("chunk-%03d.mp3", i))
+		if err := utils.CutAudio(audioPath, chunkPath, c.start, c.length); err != nil {
+			return nil, err
+		}
+		result, err := p.transcribeAudio(path, chunkPath, opts)
+		os.Remove(chunkPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to transcribe chunk %d of %d: %w", i+1, len(chunks), err)
+		}
+		if err := wd.Save(name, result); err != nil {
+			return nil, err
+		}
+		results[i] = result
+		task.Add(1)
+	}
+	return mergeChunks(chunks, results), nil
+}
+
+// mergeChunks joins the transcripts of the chunks, segment offsets are moved to the
+// position of their chunk in the recording
+func mergeChunks(chunks []chunk, results []*transcribe.TranscriptionResult) *transcribe.TranscriptionResult {
+	merged := &transcribe.TranscriptionResult{}
+	var texts []string
+	for i, result := range results {
+		if text := strings.TrimSpace(result.Text); text != "" {
+			texts = append(texts, text)
+		}
+		offset := chunks[i].start.Seconds()
+		for _, seg := range result.Segments {
+			merged.Segments = append(merged.Segments, transcribe.Segment{
+				Start: seg.Start + offset,
+				End:   seg.End + offset,
+				Text:  seg.Text,
+			})
+		}
+	}
+	merged.Text = strings.Join(texts, " ")
+	return merged
+}
+
+// summarizeParts summarizes a transcript. Transcripts longer than the configured size are
+// split into parts that are summarized first (the map step), then the summaries of the
+// parts are summarized together. The summaries of the parts are kept in the work
+// directory, so an interrupted run only summarizes the remaining parts.
+func (p *Processor) summarizeParts(path, transcriptText string, opts Options) (string, error) {
+	parts := transcript.Split(transcriptText, p.config.SummaryChunkChars)
+	if len(parts) == 1 {
+		return p.summarizeProgress(path, transcriptText, opts)
+	}
+
+	task := p.progress().Start(path, StageSummarize, int64(len(parts)+1), progress.UnitChunks)
+	defer task.Done()
+	wd, err := p.openWork(path, opts)
+	if err != nil {
+		return "", err
+	}
+	prompt, err := prompts.Load(opts.PromptName)
+	if err != nil {
+		return "", fmt.Errorf("failed to load prompt: %w", err)
+	}
+
+	summaries := make([]string, len(parts))
+	for i, part := range parts {
+		// Parts are named after their content, so a changed transcript or prompt is summarized again
+		name := fmt.Sprintf("summary-%s.json", work.Key(p.config.ChatGPTModel, prompt.Content, part))
+		if !opts.Force.Has(ForceSummary) {
+			if found, err := wd.Load(name, &summaries[i]); err != nil {
+				opts.log().Warn("Ignoring saved part summary", logging.StageKey, StageSummarize, "part", i+1, "error", err)
+			} else if found {
+				opts.log().Debug("Resuming with saved part summary", logging.StageKey, StageSummarize, "part", i+1)
+				task.Add(1)
+				continue
+			}
+		}
+
+		opts.log().Info("Summarizing part", logging.StageKey, StageSummarize, "part", fmt.Sprintf("%d/%d", i+1, len(parts)))
+		summary, err := p.summarizer.SummarizeTranscript(part, opts.PromptName, opts.Force.Has(ForceSummary))
+		if err != nil {
+			return "", fmt.Errorf("failed to summarize part %d of %d: %w", i+1, len(parts), err)
+		}
+		if err := wd.Save(name, summary); err != nil {
+			return "", err
+		}
+		summaries[i] = summary
+		task.Add(1)
+	}
+
+	opts.log().Info("Combining part summaries", logging.StageKey, StageSummarize, "parts", len(parts))
+	summary, err := p.combineSummaries(summaries, transcript.HasTimestamps(transcriptText), opts)
+	if err != nil {
+		return "", fmt.Errorf("failed to combine part summaries: %w", err)
+	}
+	task.Add(1)
+	return summary, nil
+}
+
+// combineSummaries summarizes the summaries of the parts. Summarizers that can combine
+// summaries are told to keep the timestamp references of the parts.
+func (p *Processor) combineSummaries(summaries []string, citations bool, opts Options) (string, error) {
+	if combiner, ok := p.summarizer.(interface {
+		CombineSummaries(summaries []string, promptName string, citations bool) (string, error)
+	}); ok {
+		return combiner.CombineSummaries(summaries, opts.PromptName, citations)
+	}
+	return p.summarizer.SummarizeTranscript(strings.Join(summaries, "\n\n"), opts.PromptName, opts.Force.Has(ForceSummary))
+}
+
+// workDir returns the work directory of a source in its state directory
+func (p *Processor) workDir(path string) string {
+	return filepath.Join(p.outputLayout().Dir(path), state.Dir, "work", filepath.Base(path))
+}
+
+// openWork opens the work directory of a source, results of a changed source are discarded
+func (p *Processor) openWork(path string, opts Options) (*work.Dir, error) {
+	wd, discarded, err := work.Open(p.workDir(path), path)
+	if err != nil {
+		return nil, err
+	}
+	if discarded {
+		opts.log().Info("Discarded intermediate results of a changed source", "path", p.workDir(path))
+	}
+	return wd, nil
+}
+
+// removeWork removes the work directory of a source once its outputs are written
+func (p *Processor) removeWork(path string, opts Options) {
+	dir := p.workDir(path)
+	if _, err := os.Stat(dir); err != nil {
+		return
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		opts.log().Warn("Failed to remove intermediate results", "path", dir, "error", err)
+		return
+	}
+	opts.log().Debug("Removed intermediate results", "path", dir)
+}
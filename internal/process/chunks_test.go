@@ -0,0 +1,214 @@
+package process
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/mnote/internal/config"
+	"github.com/giantswarm/mnote/internal/transcribe"
+	"github.com/giantswarm/mnote/internal/transcript"
+	"github.com/giantswarm/mnote/internal/utils"
+)
+
+// chunkTranscriber transcribes every chunk as one segment and fails the call failAt
+type chunkTranscriber struct {
+	calls  int
+	failAt int
+}
+
+func (m *chunkTranscriber) TranscribeAudio(audioPath, language string) (*transcribe.TranscriptionResult, error) {
+	m.calls++
+	if m.calls == m.failAt {
+		return nil, fmt.Errorf("connection reset")
+	}
+	data, err := os.ReadFile(audioPath)
+	if err != nil {
+		return nil, err
+	}
+	return &transcribe.TranscriptionResult{
+		Text:     string(data),
+		Segments: []transcribe.Segment{{Start: 1, End: 2, Text: string(data)}},
+	}, nil
+}
+
+// partSummarizer summarizes every input by its first line and fails the call failAt
+type partSummarizer struct {
+	inputs []string
+	failAt int
+}
+
+func (m *partSummarizer) SummarizeTranscript(transcript, promptName string, forceRebuild bool) (string, error) {
+	m.inputs = append(m.inputs, transcript)
+	if len(m.inputs) == m.failAt {
+		return "", fmt.Errorf("rate limited")
+	}
+	return "Summary of " + strings.SplitN(transcript, "\n", 2)[0], nil
+}
+
+func TestProcessVideoResumesChunks(t *testing.T) {
+	tmpDir := t.TempDir()
+	setupTestPrompt(t, "test prompt")
+
+	videoPath := filepath.Join(tmpDir, "test.mp4")
+	os.WriteFile(videoPath, []byte("dummy video content"), 0644)
+
+	// 25 minutes of audio are transcribed as chunks of 10, 10 and 5 minutes
+	mockFFmpeg := &utils.MockFFmpegRunner{Duration: 25 * time.Minute}
+	utils.SetFFmpegRunner(mockFFmpeg)
+	defer utils.SetFFmpegRunner(&utils.DefaultFFmpegRunner{})
+
+	cfg := config.DefaultConfig()
+	cfg.TranscriptionChunkMinutes = 10
+	transcriber := &chunkTranscriber{failAt: 2}
+	processor := NewProcessor(cfg, transcriber, &mockSummarizer{summary: "Test summary"})
+	opts := Options{Language: "en", PromptName: "test"}
+
+	// The second chunk fails, the first one is kept
+	if err := processor.ProcessVideo(videoPath, opts); err == nil || !strings.Contains(err.Error(), "chunk 2 of 3") {
+		t.Fatalf("expected the second chunk to fail, got %v", err)
+	}
+	workDir := filepath.Join(tmpDir, ".mnote", "work", "test.mp4")
+	if _, err := os.Stat(workDir); err != nil {
+		t.Fatalf("expected the work directory to be kept: %v", err)
+	}
+
+	// The next run resumes with the second chunk
+	transcriber.calls, transcriber.failAt = 0, 0
+	mockFFmpeg.Cuts = nil
+	if err := processor.ProcessVideo(videoPath, opts); err != nil {
+		t.Fatalf("ProcessVideo() error = %v", err)
+	}
+	if transcriber.calls != 2 {
+		t.Errorf("expected 2 chunks to be transcribed on resume, got %d", transcriber.calls)
+	}
+	if len(mockFFmpeg.Cuts) != 2 || mockFFmpeg.Cuts[0] != 10*time.Minute || mockFFmpeg.Cuts[1] != 20*time.Minute {
+		t.Errorf("unexpected cuts on resume: %v", mockFFmpeg.Cuts)
+	}
+
+	// Segments are placed at the position of their chunk
+	segments, err := transcript.LoadSegments(filepath.Join(tmpDir, "test_segments.json"))
+	if err != nil {
+		t.Fatalf("failed to load segments: %v", err)
+	}
+	var starts []float64
+	for _, seg := range segments {
+		starts = append(starts, seg.Start)
+	}
+	if fmt.Sprint(starts) != "[1 601 1201]" {
+		t.Errorf("unexpected segment starts: %v", starts)
+	}
+
+	// The intermediate results are removed once the outputs are written
+	if _, err := os.Stat(workDir); !os.IsNotExist(err) {
+		t.Errorf("expected the work directory to be removed, got %v", err)
+	}
+}
+
+func TestProcessVideoChangedSource(t *testing.T) {
+	tmpDir := t.TempDir()
+	setupTestPrompt(t, "test prompt")
+
+	videoPath := filepath.Join(tmpDir, "test.mp4")
+	os.WriteFile(videoPath, []byte("dummy video content"), 0644)
+
+	utils.SetFFmpegRunner(&utils.MockFFmpegRunner{Duration: 25 * time.Minute})
+	defer utils.SetFFmpegRunner(&utils.DefaultFFmpegRunner{})
+
+	cfg := config.DefaultConfig()
+	cfg.TranscriptionChunkMinutes = 10
+	transcriber := &chunkTranscriber{failAt: 3}
+	processor := NewProcessor(cfg, transcriber, &mockSummarizer{summary: "Test summary"})
+	opts := Options{Language: "en", PromptName: "test"}
+	if err := processor.ProcessVideo(videoPath, opts); err == nil {
+		t.Fatal("expected the third chunk to fail")
+	}
+
+	// The recording was replaced, so the saved chunks do not belong to it
+	os.WriteFile(videoPath, []byte("another recording"), 0644)
+	transcriber.calls, transcriber.failAt = 0, 0
+	if err := processor.ProcessVideo(videoPath, Options{Language: "en", PromptName: "test", Force: Force{ForceAudio: true}}); err != nil {
+		t.Fatalf("ProcessVideo() error = %v", err)
+	}
+	if transcriber.calls != 3 {
+		t.Errorf("expected all 3 chunks to be transcribed again, got %d", transcriber.calls)
+	}
+}
+
+func TestSummarizeFileResumesParts(t *testing.T) {
+	tmpDir := t.TempDir()
+	setupTestPrompt(t, "test prompt")
+
+	transcriptPath := filepath.Join(tmpDir, "meeting.md")
+	lines := []string{"First topic discussed.", "Second topic discussed.", "Third topic discussed."}
+	os.WriteFile(transcriptPath, []byte(strings.Join(lines, "\n")), 0644)
+	summaryPath := filepath.Join(tmpDir, "meeting_test.md")
+
+	cfg := config.DefaultConfig()
+	cfg.SummaryChunkChars = 30
+	summarizer := &partSummarizer{failAt: 4}
+	processor := NewProcessor(cfg, nil, summarizer)
+	opts := Options{PromptName: "test"}
+
+	// Combining the part summaries fails after all parts were summarized
+	if err := processor.SummarizeFile(transcriptPath, summaryPath, opts); err == nil {
+		t.Fatal("expected combining the part summaries to fail")
+	}
+
+	// The next run only combines them
+	summarizer.inputs, summarizer.failAt = nil, 0
+	if err := processor.SummarizeFile(transcriptPath, summaryPath, opts); err != nil {
+		t.Fatalf("SummarizeFile() error = %v", err)
+	}
+	if len(summarizer.inputs) != 1 {
+		t.Fatalf("expected only the combining request, got %q", summarizer.inputs)
+	}
+	want := "Summary of First topic discussed.\n\nSummary of Second topic discussed.\n\nSummary of Third topic discussed."
+	if summarizer.inputs[0] != want {
+		t.Errorf("unexpected combining input %q", summarizer.inputs[0])
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, ".mnote", "work", "meeting.md")); !os.IsNotExist(err) {
+		t.Errorf("expected the work directory to be removed, got %v", err)
+	}
+}
+
+// combiningSummarizer is a partSummarizer that combines the part summaries itself
+type combiningSummarizer struct {
+	partSummarizer
+	citations bool
+}
+
+func (m *combiningSummarizer) CombineSummaries(summaries []string, promptName string, citations bool) (string, error) {
+	m.citations = citations
+	return strings.Join(summaries, "\n"), nil
+}
+
+func TestSummarizeFileCombinesCitations(t *testing.T) {
+	tmpDir := t.TempDir()
+	setupTestPrompt(t, "test prompt")
+
+	transcriptPath := filepath.Join(tmpDir, "meeting_transcript.md")
+	lines := []string{"[00:00:01] First topic.", "[00:10:00] Second topic.", "[00:20:00] Third topic."}
+	os.WriteFile(transcriptPath, []byte(strings.Join(lines, "\n")), 0644)
+	summaryPath := filepath.Join(tmpDir, "meeting_test.md")
+
+	cfg := config.DefaultConfig()
+	cfg.SummaryChunkChars = 30
+	summarizer := &combiningSummarizer{}
+	processor := NewProcessor(cfg, nil, summarizer)
+	if err := processor.SummarizeFile(transcriptPath, summaryPath, Options{PromptName: "test"}); err != nil {
+		t.Fatalf("SummarizeFile() error = %v", err)
+	}
+
+	// The parts are combined with the references kept, which link to the transcript
+	if len(summarizer.inputs) != 3 || !summarizer.citations {
+		t.Fatalf("expected 3 parts combined with citations, got %q, %v", summarizer.inputs, summarizer.citations)
+	}
+	summary := readSummary(t, summaryPath)
+	if !strings.Contains(summary, "[00:20:00](meeting_transcript.md#t-00-20-00)") {
+		t.Errorf("expected linked references in the combined summary, got %q", summary)
+	}
+}
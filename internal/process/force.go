@@ -0,0 +1,94 @@
+package process
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Outputs that can be rebuilt with Options.Force
+const (
+	ForceAudio      = "audio"
+	ForceTranscript = "transcript"
+	ForceSummary    = "summary"
+	// ForceAll rebuilds every output
+	ForceAll = "all"
+)
+
+// ForceOutputs lists the outputs in pipeline order, every output is made from the ones before it
+var ForceOutputs = []string{ForceAudio, ForceTranscript, ForceSummary}
+
+// Force is the set of outputs that are rebuilt even if they exist
+type Force map[string]bool
+
+// ParseForce parses a list of outputs to rebuild, each may hold several comma separated names
+func ParseForce(values []string) (Force, error) {
+	force := Force{}
+	for _, value := range values {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			switch {
+			case name == "":
+			case name == ForceAll:
+				for _, output := range ForceOutputs {
+					force[output] = true
+				}
+			case isForceOutput(name):
+				force[name] = true
+			default:
+				return nil, fmt.Errorf("invalid output to rebuild: %s (supported: %s, %s)",
+					name, strings.Join(ForceOutputs, ", "), ForceAll)
+			}
+		}
+	}
+	return force, nil
+}
+
+// Has reports whether an output is rebuilt, because it was selected or because an output
+// it is made from is rebuilt
+func (f Force) Has(output string) bool {
+	for _, name := range ForceOutputs {
+		if f[name] {
+			return true
+		}
+		if name == output {
+			return false
+		}
+	}
+	return false
+}
+
+// reason explains why an output is rebuilt
+func (f Force) reason(output string) string {
+	if f[output] {
+		return "forced"
+	}
+	for _, name := range ForceOutputs {
+		if f[name] {
+			return name + " is rebuilt"
+		}
+	}
+	return ""
+}
+
+// String lists the selected outputs in pipeline order, or "none"
+func (f Force) String() string {
+	var names []string
+	for _, name := range ForceOutputs {
+		if f[name] {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ",")
+}
+
+func isForceOutput(name string) bool {
+	for _, output := range ForceOutputs {
+		if name == output {
+			return true
+		}
+	}
+	return false
+}
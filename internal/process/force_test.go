@@ -0,0 +1,111 @@
+package process
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/giantswarm/mnote/internal/config"
+	"github.com/giantswarm/mnote/internal/utils"
+)
+
+func TestParseForce(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  []string
+		want    string
+		wantErr bool
+	}{
+		{name: "none", values: nil, want: "none"},
+		{name: "single", values: []string{"summary"}, want: "summary"},
+		{name: "comma separated", values: []string{"transcript, summary"}, want: "transcript,summary"},
+		{name: "repeated", values: []string{"summary", "audio"}, want: "audio,summary"},
+		{name: "all", values: []string{"all"}, want: "audio,transcript,summary"},
+		{name: "invalid", values: []string{"summary,video"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseForce(tt.values)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseForce() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got.String() != tt.want {
+				t.Errorf("ParseForce() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestForceHas(t *testing.T) {
+	tests := []struct {
+		force Force
+		want  map[string]bool
+	}{
+		{Force{}, map[string]bool{ForceAudio: false, ForceTranscript: false, ForceSummary: false}},
+		{Force{ForceSummary: true}, map[string]bool{ForceAudio: false, ForceTranscript: false, ForceSummary: true}},
+		{Force{ForceTranscript: true}, map[string]bool{ForceAudio: false, ForceTranscript: true, ForceSummary: true}},
+		{Force{ForceAudio: true}, map[string]bool{ForceAudio: true, ForceTranscript: true, ForceSummary: true}},
+	}
+
+	for _, tt := range tests {
+		for output, want := range tt.want {
+			if got := tt.force.Has(output); got != want {
+				t.Errorf("Force{%s}.Has(%s) = %v, want %v", tt.force, output, got, want)
+			}
+		}
+	}
+}
+
+func TestProcessVideoForce(t *testing.T) {
+	tmpDir := t.TempDir()
+	setupTestPrompt(t, "test prompt")
+
+	videoPath := filepath.Join(tmpDir, "test.mp4")
+	os.WriteFile(videoPath, []byte("dummy video content"), 0644)
+
+	mockFFmpeg := &utils.MockFFmpegRunner{}
+	utils.SetFFmpegRunner(mockFFmpeg)
+	defer utils.SetFFmpegRunner(&utils.DefaultFFmpegRunner{})
+
+	processor := NewProcessor(config.DefaultConfig(), &mockTranscriber{transcript: "Test transcript"}, &mockSummarizer{summary: "Test summary"})
+	opts := Options{Language: "en", PromptName: "test"}
+	if err := processor.ProcessVideo(videoPath, opts); err != nil {
+		t.Fatalf("ProcessVideo() error = %v", err)
+	}
+
+	tests := []struct {
+		force Force
+		want  map[string]string
+	}{
+		{
+			force: Force{ForceSummary: true},
+			want:  map[string]string{StageExtract: StatusSkipped, StageTranscribe: StatusSkipped, StageSummarize: StatusDone},
+		},
+		{
+			force: Force{ForceTranscript: true},
+			want:  map[string]string{StageExtract: StatusSkipped, StageTranscribe: StatusDone, StageSummarize: StatusDone},
+		},
+		{
+			force: Force{ForceAudio: true},
+			want:  map[string]string{StageExtract: StatusDone, StageTranscribe: StatusDone, StageSummarize: StatusDone},
+		},
+	}
+
+	for _, tt := range tests {
+		mockFFmpeg.ExtractCalled = false
+		opts.Force = tt.force
+		res := processor.ProcessVideoResult(videoPath, opts)
+		if res.Err != nil {
+			t.Fatalf("ProcessVideoResult() with force %s error = %v", tt.force, res.Err)
+		}
+		for _, stage := range res.Stages {
+			if want, ok := tt.want[stage.Stage]; ok && stage.Status != want {
+				t.Errorf("force %s: stage %s = %s, want %s", tt.force, stage.Stage, stage.Status, want)
+			}
+		}
+		if mockFFmpeg.ExtractCalled != tt.force[ForceAudio] {
+			t.Errorf("force %s: ffmpeg called = %v", tt.force, mockFFmpeg.ExtractCalled)
+		}
+	}
+}
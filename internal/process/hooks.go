@@ -0,0 +1,54 @@
+package process
+
+import (
+	"github.com/giantswarm/mnote/internal/hooks"
+	"github.com/giantswarm/mnote/internal/transcript"
+	"github.com/giantswarm/mnote/internal/utils"
+	"github.com/giantswarm/mnote/internal/version"
+)
+
+// HookRunner runs user-defined commands around pipeline stages
+type HookRunner interface {
+	Run(event hooks.Event) error
+}
+
+// SetHooks sets the runner of the configured hooks
+func (p *Processor) SetHooks(runner HookRunner) {
+	p.hooks = runner
+}
+
+// runHook runs a hook for a video with the outputs that exist so far
+func (p *Processor) runHook(name, path, stage string, opts Options, stageErr error) error {
+	if p.hooks == nil {
+		return nil
+	}
+	transcriptPath := p.outputLayout().Transcript(path)
+	outputs := map[string]string{}
+	for kind, output := range map[string]string{
+		"audio":      p.outputLayout().Audio(path),
+		"transcript": transcriptPath,
+		"segments":   transcript.SegmentsPath(transcriptPath),
+		"summary":    p.outputLayout().Summary(path, opts.PromptName),
+	} {
+		if utils.FileExists(output) {
+			outputs[kind] = output
+		}
+	}
+
+	event := hooks.Event{
+		Hook:    name,
+		File:    path,
+		Stage:   stage,
+		Outputs: outputs,
+		Metadata: map[string]string{
+			"language": opts.Language,
+			"prompt":   opts.PromptName,
+			"model":    p.stageModel(stage, opts),
+			"version":  version.Version,
+		},
+	}
+	if stageErr != nil {
+		event.Error = stageErr.Error()
+	}
+	return p.hooks.Run(event)
+}
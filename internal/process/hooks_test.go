@@ -0,0 +1,92 @@
+package process
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/giantswarm/mnote/internal/config"
+	"github.com/giantswarm/mnote/internal/hooks"
+	"github.com/giantswarm/mnote/internal/utils"
+)
+
+// recordingHooks remembers the events and fails the hooks listed in fail
+type recordingHooks struct {
+	events []hooks.Event
+	fail   map[string]bool
+}
+
+func (r *recordingHooks) Run(event hooks.Event) error {
+	r.events = append(r.events, event)
+	if r.fail[event.Hook] {
+		return errors.New(event.Hook + " hook failed")
+	}
+	return nil
+}
+
+func (r *recordingHooks) names() string {
+	var names []string
+	for _, e := range r.events {
+		names = append(names, e.Hook)
+	}
+	return strings.Join(names, " ")
+}
+
+func TestProcessVideoHooks(t *testing.T) {
+	tmpDir := t.TempDir()
+	setupTestPrompt(t, "test prompt")
+	utils.SetFFmpegRunner(&utils.MockFFmpegRunner{})
+	defer utils.SetFFmpegRunner(&utils.DefaultFFmpegRunner{})
+
+	videoPath := filepath.Join(tmpDir, "standup.mp4")
+	os.WriteFile(videoPath, []byte("video"), 0644)
+
+	processor := &Processor{
+		config:      config.DefaultConfig(),
+		transcriber: &mockTranscriber{transcript: "transcript"},
+		summarizer:  &mockSummarizer{summary: "summary"},
+	}
+	runner := &recordingHooks{}
+	processor.SetHooks(runner)
+
+	opts := Options{Language: "en", PromptName: "test"}
+	if err := processor.ProcessVideo(videoPath, opts); err != nil {
+		t.Fatalf("ProcessVideo() error = %v", err)
+	}
+	if got := runner.names(); got != "pre_transcribe post_transcribe post_summarize" {
+		t.Fatalf("unexpected hooks: %s", got)
+	}
+	pre, post := runner.events[0], runner.events[2]
+	if pre.Outputs["audio"] == "" || pre.Outputs["transcript"] != "" {
+		t.Errorf("expected only the audio before transcription, got %v", pre.Outputs)
+	}
+	if post.Outputs["summary"] != filepath.Join(tmpDir, "standup_test.md") || post.Metadata["model"] != "gpt-4o" {
+		t.Errorf("unexpected post_summarize event: %+v", post)
+	}
+
+	// Skipped stages run no hooks
+	runner.events = nil
+	if err := processor.ProcessVideo(videoPath, opts); err != nil {
+		t.Fatalf("ProcessVideo() error = %v", err)
+	}
+	if len(runner.events) != 0 {
+		t.Errorf("expected no hooks for skipped stages, got %s", runner.names())
+	}
+
+	// A failing pre_transcribe hook fails the video and runs the error hook
+	runner.events = nil
+	runner.fail = map[string]bool{config.HookPreTranscribe: true, config.HookOnError: true}
+	opts.Force = Force{ForceTranscript: true}
+	res := processor.ProcessVideoResult(videoPath, opts)
+	if res.Err == nil || !strings.Contains(res.Err.Error(), "pre_transcribe hook failed") {
+		t.Fatalf("expected the hook failure, got %v", res.Err)
+	}
+	if got := runner.names(); got != "pre_transcribe on_error" {
+		t.Fatalf("unexpected hooks: %s", got)
+	}
+	if e := runner.events[1]; e.Stage != StageTranscribe || !strings.Contains(e.Error, "pre_transcribe") {
+		t.Errorf("unexpected on_error event: %+v", e)
+	}
+}
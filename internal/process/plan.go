@@ -0,0 +1,300 @@
+package process
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/giantswarm/mnote/internal/prompts"
+	"github.com/giantswarm/mnote/internal/transcript"
+	"github.com/giantswarm/mnote/internal/utils"
+)
+
+// Planned actions of a stage
+const (
+	ActionRun  = "run"
+	ActionSkip = "skip"
+)
+
+// Assumptions of the token estimates
+const (
+	// tokensPerMinute is the number of tokens of a minute of speech, about 150 words
+	tokensPerMinute = 200
+	// charsPerToken is the average number of characters of a token
+	charsPerToken = 4
+	// summaryTokens is the expected length of a generated summary
+	summaryTokens = 1000
+	// verifyTokens is the expected length of a verification answer
+	verifyTokens = 500
+)
+
+// PlannedStage is what a run would do in one pipeline stage and why
+type PlannedStage struct {
+	Stage  string
+	Action string
+	Reason string
+}
+
+// Plan describes what processing a video would do, with estimates of its size and cost
+type Plan struct {
+	Path   string
+	Stages []PlannedStage
+	// Duration is the length of the audio, zero if it could not be determined
+	Duration    time.Duration
+	DurationErr error
+	// TranscribeMinutes is the audio that would be sent for transcription
+	TranscribeMinutes float64
+	InputTokens       int
+	OutputTokens      int
+	// Cost is the estimated price in USD
+	Cost float64
+	Err  error
+}
+
+func (pl *Plan) add(stage, action, reason string) {
+	pl.Stages = append(pl.Stages, PlannedStage{Stage: stage, Action: action, Reason: reason})
+}
+
+// runs reports whether the stage would run
+func (pl *Plan) runs(stage string) bool {
+	for _, s := range pl.Stages {
+		if s.Stage == stage {
+			return s.Action == ActionRun
+		}
+	}
+	return false
+}
+
+// PlanVideos plans the processing of every video, in input order
+func (p *Processor) PlanVideos(paths []string, opts Options) []*Plan {
+	plans := make([]*Plan, len(paths))
+	for n, path := range paths {
+		plans[n] = p.PlanVideo(path, opts)
+	}
+	return plans
+}
+
+// PlanVideo reports which stages processing a video would run or skip and estimates the
+// audio minutes, tokens and cost. It only reads files and does not call any API.
+func (p *Processor) PlanVideo(path string, opts Options) *Plan {
+	plan := &Plan{Path: path}
+	if err := p.planVideo(path, opts, plan); err != nil {
+		plan.Err = err
+	}
+	return plan
+}
+
+func (p *Processor) planVideo(path string, opts Options, plan *Plan) error {
+	if !utils.IsMediaFile(path) {
+		return fmt.Errorf("not a supported video or audio file: %s", path)
+	}
+
+	// Audio is only extracted or converted for a new transcript, the duration is taken
+	// from the extracted audio if it is reused
+	audioPath := p.outputLayout().Audio(path)
+	transcriptPath := p.outputLayout().Transcript(path)
+	transcribing := opts.Force.Has(ForceTranscript) || !utils.FileExists(transcriptPath)
+	probePath := path
+	switch {
+	case !transcribing:
+		plan.add(StageExtract, ActionSkip, "transcript exists")
+		if utils.FileExists(audioPath) {
+			probePath = audioPath
+		}
+	case !utils.ProbeMedia(path).NeedsTranscode():
+		plan.add(StageExtract, ActionSkip, "audio file is transcribed as is")
+	case opts.Force.Has(ForceAudio):
+		plan.add(StageExtract, ActionRun, opts.Force.reason(ForceAudio))
+	case utils.FileExists(audioPath):
+		plan.add(StageExtract, ActionSkip, "audio file exists")
+		probePath = audioPath
+	case utils.IsAudioFile(path):
+		plan.add(StageExtract, ActionRun, "audio is converted")
+	default:
+		plan.add(StageExtract, ActionRun, "no audio file")
+	}
+	plan.Duration, plan.DurationErr = utils.MediaDuration(probePath)
+	minutes := plan.Duration.Minutes()
+
+	// Transcription
+	switch {
+	case opts.Force.Has(ForceTranscript):
+		plan.add(StageTranscribe, ActionRun, opts.Force.reason(ForceTranscript))
+	case !transcribing:
+		plan.add(StageTranscribe, ActionSkip, "transcript exists")
+	default:
+		plan.add(StageTranscribe, ActionRun, "no transcript")
+	}
+	if transcribing {
+		plan.TranscribeMinutes = minutes
+		plan.Cost += minutes * p.config.TranscriptionPricePerMinute
+	}
+
+	// Search index
+	if p.indexer != nil && opts.Redact {
+		plan.add(StageIndex, ActionSkip, "redacted transcripts are not indexed")
+	} else if p.indexer != nil {
+		if err := p.planIndex(transcriptPath, transcribing, plan); err != nil {
+			return err
+		}
+	}
+
+	// Summary, the existing transcript gives a better token estimate than the duration
+	var transcriptText string
+	transcriptTokens := int(minutes * tokensPerMinute)
+	if !transcribing {
+		text, _, err := transcript.ReadTimestamped(transcriptPath)
+		if err != nil {
+			return fmt.Errorf("failed to read transcript: %w", err)
+		}
+		transcriptText = text
+		transcriptTokens = estimateTokens(text)
+	}
+	if err := p.planSummary(path, transcriptText, transcribing, opts, plan); err != nil {
+		return err
+	}
+	if !plan.runs(StageSummarize) {
+		return nil
+	}
+
+	prompt, err := prompts.Load(opts.PromptName)
+	if err != nil {
+		return fmt.Errorf("failed to load prompt: %w", err)
+	}
+	// Long transcripts are summarized in parts whose summaries are combined in one more
+	// request, and judged in parts when verifying
+	parts := p.summaryParts(transcriptText, transcriptTokens)
+	promptTokens := estimateTokens(prompt.Content)
+	plan.InputTokens += transcriptTokens + parts*promptTokens
+	plan.OutputTokens += parts * summaryTokens
+	if parts > 1 {
+		plan.InputTokens += parts*summaryTokens + promptTokens
+		plan.OutputTokens += summaryTokens
+	}
+	if opts.Verify {
+		plan.InputTokens += transcriptTokens + parts*summaryTokens
+		plan.OutputTokens += parts * verifyTokens
+	}
+	plan.Cost += float64(plan.InputTokens)*p.config.ChatGPTInputPrice/1e6 +
+		float64(plan.OutputTokens)*p.config.ChatGPTOutputPrice/1e6
+	return nil
+}
+
+// summaryParts returns the number of parts a transcript is summarized in. Transcripts that
+// are not written yet are estimated from their tokens.
+func (p *Processor) summaryParts(transcriptText string, transcriptTokens int) int {
+	if transcriptText != "" {
+		return len(transcript.Split(transcriptText, p.config.SummaryChunkChars))
+	}
+	maxChars := p.config.SummaryChunkChars
+	chars := transcriptTokens * charsPerToken
+	if maxChars <= 0 || chars <= maxChars {
+		return 1
+	}
+	return (chars + maxChars - 1) / maxChars
+}
+
+// planIndex plans the index update, using the index to check existing transcripts if it can
+func (p *Processor) planIndex(transcriptPath string, transcribing bool, plan *Plan) error {
+	if transcribing {
+		plan.add(StageIndex, ActionRun, "new transcript")
+		return nil
+	}
+	checker, ok := p.indexer.(interface {
+		Indexed(transcriptPath string) (bool, error)
+	})
+	if !ok {
+		plan.add(StageIndex, ActionRun, "transcript is indexed if it changed")
+		return nil
+	}
+	indexed, err := checker.Indexed(transcriptPath)
+	if err != nil {
+		return fmt.Errorf("failed to check search index: %w", err)
+	}
+	if indexed {
+		plan.add(StageIndex, ActionSkip, "transcript is indexed")
+	} else {
+		plan.add(StageIndex, ActionRun, "transcript not indexed or changed")
+	}
+	return nil
+}
+
+// planSummary plans the summary like processVideo decides whether to keep an existing one
+func (p *Processor) planSummary(path, transcriptText string, transcribing bool, opts Options, plan *Plan) error {
+	summaryPath := p.outputLayout().Summary(path, opts.PromptName)
+	switch {
+	case opts.Force.Has(ForceSummary):
+		plan.add(StageSummarize, ActionRun, opts.Force.reason(ForceSummary))
+	case !utils.FileExists(summaryPath):
+		plan.add(StageSummarize, ActionRun, "no summary")
+	case !opts.RefreshStale:
+		plan.add(StageSummarize, ActionSkip, "summary exists")
+	case transcribing:
+		plan.add(StageSummarize, ActionRun, "summary is checked against the new transcript")
+	default:
+		expected, err := p.expectedProvenance(transcriptText, opts.PromptName)
+		if err != nil {
+			return err
+		}
+		changes, err := summaryChanges(summaryPath, expected)
+		if err != nil {
+			return err
+		}
+		if len(changes) == 0 {
+			plan.add(StageSummarize, ActionSkip, "summary is up to date")
+		} else {
+			plan.add(StageSummarize, ActionRun, "summary is stale: "+strings.Join(changes, ", "))
+		}
+	}
+	return nil
+}
+
+// estimateTokens estimates the number of tokens of a text
+func estimateTokens(text string) int {
+	return (len(text) + charsPerToken - 1) / charsPerToken
+}
+
+// WritePlan writes a table with the planned stages and estimates of every video followed by the totals
+func WritePlan(out io.Writer, plans []*Plan) error {
+	var minutes, cost float64
+	var inputTokens, outputTokens, failed int
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VIDEO\tAUDIO\tTOKENS\tCOST")
+	for _, pl := range plans {
+		if pl.Err != nil {
+			failed++
+			fmt.Fprintf(w, "%s\t-\t-\t-\n  error\t%v\n", pl.Path, pl.Err)
+			continue
+		}
+		audio := fmt.Sprintf("%.1f min", pl.Duration.Minutes())
+		if pl.DurationErr != nil {
+			audio = "unknown"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t$%.2f\n", pl.Path, audio, pl.InputTokens+pl.OutputTokens, pl.Cost)
+		for _, stage := range pl.Stages {
+			fmt.Fprintf(w, "  %s\t%s\t%s\n", stage.Stage, stage.Action, stage.Reason)
+		}
+		if pl.DurationErr != nil {
+			fmt.Fprintf(w, "  warning\t%v\n", pl.DurationErr)
+		}
+		minutes += pl.TranscribeMinutes
+		cost += pl.Cost
+		inputTokens += pl.InputTokens
+		outputTokens += pl.OutputTokens
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(out, "\nPlanned %d videos: %.1f minutes of audio to transcribe, %d input and %d output tokens, estimated cost $%.2f\n",
+		len(plans), minutes, inputTokens, outputTokens, cost)
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		_, err = fmt.Fprintf(out, "%d videos cannot be processed\n", failed)
+	}
+	return err
+}
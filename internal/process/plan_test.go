@@ -0,0 +1,128 @@
+package process
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/mnote/internal/config"
+	"github.com/giantswarm/mnote/internal/utils"
+)
+
+func TestPlanVideos(t *testing.T) {
+	tmpDir := t.TempDir()
+	setupTestPrompt(t, "test prompt")
+	utils.SetFFmpegRunner(&utils.MockFFmpegRunner{Duration: 10 * time.Minute})
+	defer utils.SetFFmpegRunner(&utils.DefaultFFmpegRunner{})
+
+	// new.mp4 was never processed, done.mp4 has all outputs
+	newPath := filepath.Join(tmpDir, "new.mp4")
+	donePath := filepath.Join(tmpDir, "done.mp4")
+	os.WriteFile(newPath, []byte("video"), 0644)
+	os.WriteFile(donePath, []byte("video"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "done_audio.mp3"), []byte("audio"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "done_transcript.md"), []byte(strings.Repeat("word ", 100)), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "done_test.md"), []byte("summary"), 0644)
+	before, _ := os.ReadDir(tmpDir)
+
+	processor := &Processor{
+		config:      config.DefaultConfig(),
+		transcriber: &mockTranscriber{err: os.ErrInvalid},
+		summarizer:  &mockSummarizer{err: os.ErrInvalid},
+	}
+	plans := processor.PlanVideos([]string{newPath, donePath}, Options{Language: "en", PromptName: "test"})
+
+	newPlan := plans[0]
+	if newPlan.Err != nil {
+		t.Fatalf("PlanVideo() error = %v", newPlan.Err)
+	}
+	for _, stage := range newPlan.Stages {
+		if stage.Action != ActionRun {
+			t.Errorf("expected %s to run for a new video, got %s (%s)", stage.Stage, stage.Action, stage.Reason)
+		}
+	}
+	if newPlan.TranscribeMinutes != 10 {
+		t.Errorf("expected 10 minutes to transcribe, got %v", newPlan.TranscribeMinutes)
+	}
+	// 200 tokens per minute and 3 prompt tokens in, one summary out
+	if newPlan.InputTokens != 2003 || newPlan.OutputTokens != 1000 {
+		t.Errorf("expected 2003 input and 1000 output tokens, got %d and %d", newPlan.InputTokens, newPlan.OutputTokens)
+	}
+	wantCost := 10*0.006 + 2003*2.5/1e6 + 1000*10.0/1e6
+	if math.Abs(newPlan.Cost-wantCost) > 1e-9 {
+		t.Errorf("expected cost %v, got %v", wantCost, newPlan.Cost)
+	}
+
+	donePlan := plans[1]
+	if donePlan.Err != nil {
+		t.Fatalf("PlanVideo() error = %v", donePlan.Err)
+	}
+	for _, stage := range donePlan.Stages {
+		if stage.Action != ActionSkip {
+			t.Errorf("expected %s to be skipped for a processed video, got %s (%s)", stage.Stage, stage.Action, stage.Reason)
+		}
+	}
+	if donePlan.Cost != 0 || donePlan.InputTokens != 0 {
+		t.Errorf("expected no cost for a processed video, got $%v and %d tokens", donePlan.Cost, donePlan.InputTokens)
+	}
+
+	// Forcing a rebuild estimates the tokens from the existing transcript
+	forced := processor.PlanVideo(donePath, Options{Language: "en", PromptName: "test", Force: Force{ForceAudio: true}})
+	if forced.InputTokens != 2003 {
+		t.Errorf("expected the transcript to be estimated from the duration when it is recreated, got %d", forced.InputTokens)
+	}
+	refresh := processor.PlanVideo(donePath, Options{Language: "en", PromptName: "test", RefreshStale: true})
+	if !refresh.runs(StageSummarize) || refresh.InputTokens != 125+3 {
+		t.Errorf("expected a stale summary to be regenerated from the existing transcript, got %+v", refresh)
+	}
+
+	// Nothing was written
+	after, _ := os.ReadDir(tmpDir)
+	if len(after) != len(before) {
+		t.Errorf("expected no files to be written, got %d files instead of %d", len(after), len(before))
+	}
+
+	var out bytes.Buffer
+	if err := WritePlan(&out, plans); err != nil {
+		t.Fatalf("WritePlan() error = %v", err)
+	}
+	for _, want := range []string{"new.mp4", "10.0 min", "no transcript", "summary exists", "Planned 2 videos: 10.0 minutes", "$0.08"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("plan does not contain %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestPlanSummaryParts(t *testing.T) {
+	tmpDir := t.TempDir()
+	setupTestPrompt(t, "test prompt")
+	utils.SetFFmpegRunner(&utils.MockFFmpegRunner{Duration: 10 * time.Minute})
+	defer utils.SetFFmpegRunner(&utils.DefaultFFmpegRunner{})
+
+	videoPath := filepath.Join(tmpDir, "long.mp4")
+	os.WriteFile(videoPath, []byte("video"), 0644)
+
+	cfg := config.DefaultConfig()
+	cfg.SummaryChunkChars = 3000
+	processor := &Processor{config: cfg}
+
+	// 2000 tokens of about 8000 characters are summarized in 3 parts, each sent with the
+	// prompt, and the part summaries are combined with one more request
+	plan := processor.PlanVideo(videoPath, Options{Language: "en", PromptName: "test"})
+	if plan.Err != nil {
+		t.Fatalf("PlanVideo() error = %v", plan.Err)
+	}
+	if plan.InputTokens != 2000+3*3+3*1000+3 || plan.OutputTokens != 4*1000 {
+		t.Errorf("expected 5012 input and 4000 output tokens, got %d and %d", plan.InputTokens, plan.OutputTokens)
+	}
+
+	// Verification judges every part
+	plan = processor.PlanVideo(videoPath, Options{Language: "en", PromptName: "test", Verify: true})
+	if plan.InputTokens != 5012+2000+3*1000 || plan.OutputTokens != 4000+3*500 {
+		t.Errorf("expected 10012 input and 5500 output tokens with verification, got %d and %d", plan.InputTokens, plan.OutputTokens)
+	}
+}
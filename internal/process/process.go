@@ -1,19 +1,80 @@
 package process
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
+	"os"
+	"strings"
 
 	"github.com/giantswarm/mnote/internal/config"
+	"github.com/giantswarm/mnote/internal/layout"
+	"github.com/giantswarm/mnote/internal/lock"
+	"github.com/giantswarm/mnote/internal/logging"
+	"github.com/giantswarm/mnote/internal/progress"
+	"github.com/giantswarm/mnote/internal/prompts"
+	"github.com/giantswarm/mnote/internal/provenance"
+	"github.com/giantswarm/mnote/internal/redact"
 	"github.com/giantswarm/mnote/internal/summarize"
 	"github.com/giantswarm/mnote/internal/transcribe"
+	"github.com/giantswarm/mnote/internal/transcript"
 	"github.com/giantswarm/mnote/internal/utils"
+	"github.com/giantswarm/mnote/internal/verify"
 )
 
 // Options holds the processing options
 type Options struct {
 	Language     string
 	PromptName   string
-	ForceRebuild bool
+	Force        Force
+	Verify       bool
+	MediaLinks   bool
+	Redact       bool
+	RefreshStale bool
+	// KeepAudio keeps cached audio after the transcript was written
+	KeepAudio bool
+	// WaitLocked waits for videos locked by another mnote process instead of skipping them
+	WaitLocked bool
+
+	// logger logs with the attributes of the file being processed
+	logger *slog.Logger
+}
+
+// log returns the logger for the file being processed
+func (o Options) log() *slog.Logger {
+	if o.logger == nil {
+		return slog.Default()
+	}
+	return o.logger
+}
+
+// Summary states reported by SummaryStatus
+const (
+	SummaryMissing = "missing"
+	SummaryCurrent = "current"
+	SummaryStale   = "stale"
+)
+
+// SummaryStatus describes whether a summary matches the current prompt, model and transcript
+type SummaryStatus struct {
+	SummaryPath string
+	State       string
+	Changes     []string
+}
+
+// Indexer updates a search index with new or changed transcripts
+type Indexer interface {
+	IndexTranscript(transcriptPath string) (bool, error)
+}
+
+// Verifier checks a summary against its transcript
+type Verifier interface {
+	Verify(summary, transcript string, judge bool) (*verify.Report, error)
+}
+
+// Redactor replaces sensitive values with placeholders before text leaves the machine
+type Redactor interface {
+	Redact(text string, mapping *redact.Mapping) (string, map[string]int)
 }
 
 // Processor handles the complete video processing workflow
@@ -21,6 +82,17 @@ type Processor struct {
 	config      *config.Config
 	transcriber transcribe.Transcriber
 	summarizer  summarize.Summarizer
+	indexer     Indexer
+	verifier    Verifier
+	redactor    Redactor
+	hooks       HookRunner
+	layout      *layout.Layout
+	tracker     *progress.Tracker
+
+	// Slots limiting how many files are in each stage at the same time, nil means unlimited
+	extractSlots    chan struct{}
+	transcribeSlots chan struct{}
+	summarizeSlots  chan struct{}
 }
 
 // NewProcessor creates a new Processor instance
@@ -32,63 +104,394 @@ func NewProcessor(cfg *config.Config, transcriber transcribe.Transcriber, summar
 	}
 }
 
+// SetLayout sets where outputs and intermediate audio are written
+func (p *Processor) SetLayout(l *layout.Layout) {
+	p.layout = l
+}
+
+// outputLayout returns the layout of the outputs, the default one if none was set
+func (p *Processor) outputLayout() *layout.Layout {
+	if p.layout == nil {
+		return layout.Default()
+	}
+	return p.layout
+}
+
+// SetProgress sets the tracker the progress of the stages is reported to
+func (p *Processor) SetProgress(tracker *progress.Tracker) {
+	p.tracker = tracker
+}
+
+// progress returns the progress tracker, the default one if none was set
+func (p *Processor) progress() *progress.Tracker {
+	if p.tracker == nil {
+		return progress.Default()
+	}
+	return p.tracker
+}
+
+// SetIndexer enables updating the search index with every processed transcript
+func (p *Processor) SetIndexer(indexer Indexer) {
+	p.indexer = indexer
+}
+
+// SetVerifier sets the verifier used when Options.Verify is set
+func (p *Processor) SetVerifier(verifier Verifier) {
+	p.verifier = verifier
+}
+
+// SetRedactor sets the redactor used when Options.Redact is set
+func (p *Processor) SetRedactor(redactor Redactor) {
+	p.redactor = redactor
+}
+
 // ProcessVideo processes a video file, generating transcription and summary
 func (p *Processor) ProcessVideo(path string, opts Options) error {
-	// Validate video file
-	if !utils.IsVideoFile(path) {
-		return fmt.Errorf("not a supported video file: %s", path)
+	return p.ProcessVideoResult(path, opts).Err
+}
+
+// ProcessVideoResult processes a video file like ProcessVideo and reports the outcome of every stage
+func (p *Processor) ProcessVideoResult(path string, opts Options) *Result {
+	res := &Result{Path: path}
+	if opts.logger == nil {
+		opts.logger = slog.With(logging.FileKey, path)
+	}
+	var locked *lock.LockedError
+	unlock, err := p.lockSource(path, opts)
+	switch {
+	case errors.As(err, &locked):
+		res.LockedBy = locked.Holder.String()
+		opts.log().Info("Skipping file being processed by another mnote process", "holder", res.LockedBy)
+		return res
+	case err != nil:
+		opts.log().Warn("Processing without a lock", "error", err)
+	default:
+		defer unlock()
 	}
 
-	// Extract audio
-	audioPath, err := utils.ExtractAudio(path, opts.ForceRebuild)
+	source, err := p.startState(path)
 	if err != nil {
-		return fmt.Errorf("failed to extract audio: %w", err)
+		opts.log().Warn("Failed to update state", "error", err)
+	}
+	if err := p.processVideo(path, opts, res); err != nil {
+		res.Err = err
+		res.record(res.stage, StatusFailed, err)
+		// The video failed already, a failing error hook cannot change that
+		if err := p.runHook(config.HookOnError, path, res.stage, opts, err); err != nil {
+			opts.log().Warn("Hook failed", "error", err)
+		}
+	}
+	if err := p.saveState(res, source, opts); err != nil {
+		opts.log().Warn("Failed to update state", "error", err)
+	}
+	if !opts.KeepAudio {
+		p.cleanAudio(path, opts)
+	}
+	// Intermediate results are kept until all outputs are written, a failed video resumes with them
+	if res.Err == nil {
+		p.removeWork(path, opts)
+	}
+	p.progress().Finish(path)
+	return res
+}
+
+func (p *Processor) processVideo(path string, opts Options, res *Result) error {
+	// Validate media file
+	res.enter(StageExtract)
+	if !utils.IsMediaFile(path) {
+		return fmt.Errorf("not a supported video or audio file: %s", path)
 	}
 
 	// Get output paths
-	transcriptPath := utils.GetOutputPath(path, "transcript")
-	summaryPath := utils.GetOutputPath(path, opts.PromptName)
+	transcriptPath := p.outputLayout().Transcript(path)
+	summaryPath := p.outputLayout().Summary(path, opts.PromptName)
+	transcribing := opts.Force.Has(ForceTranscript) || !utils.FileExists(transcriptPath)
+
+	// Extract the audio of videos, audio files are transcribed as they are if the
+	// transcription backend accepts them and converted otherwise
+	audioPath := p.outputLayout().Audio(path)
+	if audioPath == path {
+		return fmt.Errorf("audio output would overwrite the source: %s", path)
+	}
+	var media utils.MediaInfo
+	if transcribing {
+		media = utils.ProbeMedia(path)
+	}
+	switch {
+	case !transcribing:
+		opts.log().Debug("Audio is not needed, the transcript exists", logging.StageKey, StageExtract)
+		p.progress().Skip(path, StageExtract)
+		res.record(StageExtract, StatusSkipped, nil)
+	case !media.NeedsTranscode():
+		opts.log().Info("Transcribing audio file as is", logging.StageKey, StageExtract, "format", media.Ext, "codec", media.AudioCodec)
+		audioPath = path
+		p.progress().Skip(path, StageExtract)
+		res.record(StageExtract, StatusSkipped, nil)
+	case !opts.Force.Has(ForceAudio) && utils.FileExists(audioPath):
+		opts.log().Info("Audio file already exists", logging.StageKey, StageExtract, "path", audioPath)
+		p.progress().Skip(path, StageExtract)
+		res.record(StageExtract, StatusSkipped, nil)
+	default:
+		if media.Kind == utils.MediaAudio {
+			opts.log().Info("Converting audio", logging.StageKey, StageExtract, "path", audioPath, "format", media.Ext, "codec", media.AudioCodec)
+		} else {
+			opts.log().Info("Extracting audio", logging.StageKey, StageExtract, "path", audioPath)
+		}
+		release := acquire(p.extractSlots)
+		err := p.extractAudio(path, audioPath, opts)
+		release()
+		if err != nil {
+			return fmt.Errorf("failed to extract audio: %w", err)
+		}
+		res.record(StageExtract, StatusDone, nil)
+	}
 
 	// Skip transcription if file exists and not forcing rebuild
-	if !opts.ForceRebuild && utils.FileExists(transcriptPath) {
-		fmt.Printf("Transcript file already exists: %s\n", transcriptPath)
+	res.enter(StageTranscribe)
+	if !opts.Force.Has(ForceTranscript) && utils.FileExists(transcriptPath) {
+		opts.log().Info("Transcript file already exists", logging.StageKey, StageTranscribe, "path", transcriptPath)
+		p.progress().Skip(path, stageUpload)
+		p.progress().Skip(path, StageTranscribe)
+		res.record(StageTranscribe, StatusSkipped, nil)
 	} else {
+		if err := p.runHook(config.HookPreTranscribe, path, StageTranscribe, opts, nil); err != nil {
+			return err
+		}
+
 		// Perform transcription
-		result, err := p.transcriber.TranscribeAudio(audioPath, opts.Language)
+		opts.log().Info("Transcribing audio", logging.StageKey, StageTranscribe,
+			"model", p.config.GetWhisperModel(opts.Language), "language", opts.Language)
+		release := acquire(p.transcribeSlots)
+		result, err := p.transcribe(path, audioPath, opts)
+		release()
 		if err != nil {
 			return fmt.Errorf("transcription failed: %w", err)
 		}
 
-		// Save transcript
-		if err := utils.WriteFile(transcriptPath, []byte(result.Text)); err != nil {
-			return fmt.Errorf("failed to save transcript: %w", err)
+		if err := SaveTranscript(transcriptPath, result); err != nil {
+			return err
+		}
+		opts.log().Info("Transcript saved", logging.StageKey, StageTranscribe, "path", transcriptPath)
+		if err := p.runHook(config.HookPostTranscribe, path, StageTranscribe, opts, nil); err != nil {
+			return err
 		}
-		fmt.Printf("Transcript saved to: %s\n", transcriptPath)
+		res.record(StageTranscribe, StatusDone, nil)
 	}
 
-	// Skip summarization if file exists and not forcing rebuild
-	if !opts.ForceRebuild && utils.FileExists(summaryPath) {
-		fmt.Printf("Summary file already exists: %s\n", summaryPath)
-		return nil
+	// Update search index, a failure here should not prevent the summary. Redacted
+	// transcripts are not indexed, the index would send and store them unredacted.
+	if p.indexer != nil && opts.Redact {
+		opts.log().Info("Not indexing transcript, redaction is on", logging.StageKey, StageIndex)
+		res.record(StageIndex, StatusSkipped, nil)
+	} else if p.indexer != nil {
+		res.enter(StageIndex)
+		if indexed, err := p.indexer.IndexTranscript(transcriptPath); err != nil {
+			opts.log().Warn("Failed to index transcript", logging.StageKey, StageIndex, "error", err)
+			res.record(StageIndex, StatusFailed, err)
+		} else if indexed {
+			opts.log().Info("Transcript indexed", logging.StageKey, StageIndex, "path", transcriptPath)
+			res.record(StageIndex, StatusDone, nil)
+		} else {
+			res.record(StageIndex, StatusSkipped, nil)
+		}
 	}
 
-	// Read transcript for summarization
-	transcript, err := utils.ReadFile(transcriptPath)
+	// Read transcript for summarization, timestamped if segments are known
+	res.enter(StageSummarize)
+	transcriptText, starts, err := transcript.ReadTimestamped(transcriptPath)
 	if err != nil {
 		return fmt.Errorf("failed to read transcript: %w", err)
 	}
+	expected, err := p.expectedProvenance(transcriptText, opts.PromptName)
+	if err != nil {
+		return err
+	}
 
-	// Generate summary
-	summary, err := p.summarizer.SummarizeTranscript(string(transcript), opts.PromptName, opts.ForceRebuild)
+	// Skip summarization if file exists and not forcing rebuild, unless it is stale and refreshing
+	if !opts.Force.Has(ForceSummary) && utils.FileExists(summaryPath) {
+		if !opts.RefreshStale {
+			opts.log().Info("Summary file already exists", logging.StageKey, StageSummarize, "path", summaryPath)
+			p.progress().Skip(path, StageSummarize)
+			res.record(StageSummarize, StatusSkipped, nil)
+			return nil
+		}
+		changes, err := summaryChanges(summaryPath, expected)
+		if err != nil {
+			return err
+		}
+		if len(changes) == 0 {
+			opts.log().Info("Summary is up to date", logging.StageKey, StageSummarize, "path", summaryPath)
+			p.progress().Skip(path, StageSummarize)
+			res.record(StageSummarize, StatusSkipped, nil)
+			return nil
+		}
+		opts.log().Info("Summary is stale", logging.StageKey, StageSummarize, "path", summaryPath, "changes", strings.Join(changes, ", "))
+	}
+
+	// Redact sensitive values before the transcript is sent to the chat model
+	var mapping *redact.Mapping
+	if opts.Redact {
+		if transcriptText, mapping, err = p.redact(p.outputLayout().Redaction(path), transcriptText, opts); err != nil {
+			return err
+		}
+	}
+
+	// Generate and check the summary
+	opts.log().Info("Summarizing transcript", logging.StageKey, StageSummarize,
+		"model", p.config.ChatGPTModel, "prompt", opts.PromptName)
+	summary, report, err := p.summarize(path, transcriptText, opts)
 	if err != nil {
-		return fmt.Errorf("summarization failed: %w", err)
+		return err
+	}
+	if report != nil {
+		if unsupported := report.Unsupported(); len(unsupported) > 0 {
+			opts.log().Warn("Statements in the summary are not supported by the transcript",
+				logging.StageKey, StageSummarize, "unsupported", len(unsupported))
+		}
 	}
 
-	// Save summary
+	// Link timestamp references to the transcript and optionally the video
+	if len(starts) > 0 {
+		summary = transcript.LinkCitations(summary, starts,
+			utils.RelativeLink(summaryPath, transcriptPath), mediaLink(summaryPath, path, opts.MediaLinks))
+	}
+	if report != nil {
+		summary = verify.AppendSection(summary, report)
+	}
+
+	// Put the original values back into the summary
+	if mapping != nil {
+		summary = mapping.Restore(summary)
+	}
+
+	// Save summary with its provenance
+	summary = provenance.Add(summary, expected)
 	if err := utils.WriteFile(summaryPath, []byte(summary)); err != nil {
 		return fmt.Errorf("failed to save summary: %w", err)
 	}
-	fmt.Printf("Summary saved to: %s\n", summaryPath)
+	opts.log().Info("Summary saved", logging.StageKey, StageSummarize, "path", summaryPath)
+	if err := p.runHook(config.HookPostSummarize, path, StageSummarize, opts, nil); err != nil {
+		return err
+	}
+	res.record(StageSummarize, StatusDone, nil)
 
 	return nil
 }
+
+// redact replaces sensitive values in a transcript with placeholders. The mapping at
+// mappingPath is extended and saved, so placeholders stay the same across runs.
+func (p *Processor) redact(mappingPath, transcriptText string, opts Options) (string, *redact.Mapping, error) {
+	if p.redactor == nil {
+		return "", nil, fmt.Errorf("redaction requested but no redactor configured")
+	}
+	mapping, err := redact.LoadMapping(mappingPath)
+	if err != nil {
+		return "", nil, err
+	}
+	redacted, counts := p.redactor.Redact(transcriptText, mapping)
+	if err := mapping.Save(mappingPath); err != nil {
+		return "", nil, fmt.Errorf("failed to save redaction mapping: %w", err)
+	}
+	if len(counts) > 0 {
+		opts.log().Info("Redacted transcript", logging.StageKey, StageSummarize, "redacted", redact.FormatCounts(counts))
+	} else {
+		opts.log().Info("Nothing to redact in transcript", logging.StageKey, StageSummarize)
+	}
+	return redacted, mapping, nil
+}
+
+// cleanAudio removes the cached audio of a video once its transcript exists
+func (p *Processor) cleanAudio(path string, opts Options) {
+	audioPath := p.outputLayout().Audio(path)
+	if !p.outputLayout().CachesAudio() || !utils.FileExists(audioPath) || !utils.FileExists(p.outputLayout().Transcript(path)) {
+		return
+	}
+	if err := os.Remove(audioPath); err != nil {
+		opts.log().Warn("Failed to remove cached audio", "path", audioPath, "error", err)
+		return
+	}
+	opts.log().Debug("Removed cached audio", "path", audioPath)
+}
+
+// summarize generates the summary and, if requested, checks it against the transcript
+func (p *Processor) summarize(path, transcriptText string, opts Options) (string, *verify.Report, error) {
+	release := acquire(p.summarizeSlots)
+	defer release()
+
+	summary, err := p.summarizeParts(path, transcriptText, opts)
+	if err != nil {
+		return "", nil, fmt.Errorf("summarization failed: %w", err)
+	}
+	if !opts.Verify {
+		return summary, nil, nil
+	}
+	if p.verifier == nil {
+		return "", nil, fmt.Errorf("verification requested but no verifier configured")
+	}
+	// A summary that could not be checked is still kept, just without the verification section
+	report, err := p.verifier.Verify(summary, transcriptText, true)
+	if err != nil {
+		opts.log().Warn("Failed to verify summary, saving it without verification", logging.StageKey, StageSummarize, "error", err)
+		return summary, nil, nil
+	}
+	return summary, report, nil
+}
+
+// SummaryStatus reports whether the summary of a video for the given prompt exists and
+// whether its provenance matches the current prompt, model and transcript
+func (p *Processor) SummaryStatus(path string, opts Options) (*SummaryStatus, error) {
+	status := &SummaryStatus{
+		SummaryPath: p.outputLayout().Summary(path, opts.PromptName),
+		State:       SummaryMissing,
+	}
+	transcriptPath := p.outputLayout().Transcript(path)
+	if !utils.FileExists(status.SummaryPath) || !utils.FileExists(transcriptPath) {
+		return status, nil
+	}
+
+	transcriptText, _, err := transcript.ReadTimestamped(transcriptPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcript: %w", err)
+	}
+	expected, err := p.expectedProvenance(transcriptText, opts.PromptName)
+	if err != nil {
+		return nil, err
+	}
+	status.Changes, err = summaryChanges(status.SummaryPath, expected)
+	if err != nil {
+		return nil, err
+	}
+
+	status.State = SummaryCurrent
+	if len(status.Changes) > 0 {
+		status.State = SummaryStale
+	}
+	return status, nil
+}
+
+// expectedProvenance returns the provenance a summary created now would have
+func (p *Processor) expectedProvenance(transcriptText, promptName string) (provenance.Provenance, error) {
+	prompt, err := prompts.Load(promptName)
+	if err != nil {
+		return provenance.Provenance{}, fmt.Errorf("failed to load prompt: %w", err)
+	}
+	// The prompt sent includes the instructions added for the transcript
+	return provenance.New(promptName, summarize.SystemPrompt(prompt.Content, transcriptText), p.config.ChatGPTModel, transcriptText), nil
+}
+
+// summaryChanges compares the provenance recorded in a summary file with the expected one
+func summaryChanges(summaryPath string, expected provenance.Provenance) ([]string, error) {
+	existing, err := utils.ReadFile(summaryPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read summary: %w", err)
+	}
+	recorded, _ := provenance.Parse(string(existing))
+	return recorded.Changes(expected), nil
+}
+
+func mediaLink(summaryPath, videoPath string, enabled bool) string {
+	if !enabled {
+		return ""
+	}
+	return utils.RelativeLink(summaryPath, videoPath)
+}
@@ -1,44 +1,94 @@
 package process
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/giantswarm/mnote/internal/config"
+	"github.com/giantswarm/mnote/internal/layout"
+	"github.com/giantswarm/mnote/internal/provenance"
+	"github.com/giantswarm/mnote/internal/redact"
+	"github.com/giantswarm/mnote/internal/summarize"
 	"github.com/giantswarm/mnote/internal/transcribe"
+	"github.com/giantswarm/mnote/internal/transcript"
 	"github.com/giantswarm/mnote/internal/utils"
+	"github.com/giantswarm/mnote/internal/verify"
+	"github.com/giantswarm/mnote/internal/walk"
 )
 
 // mockTranscriber implements transcribe.Transcriber interface
 type mockTranscriber struct {
 	transcript string
-	err       error
+	segments   []transcribe.Segment
+	err        error
 }
 
 func (m *mockTranscriber) TranscribeAudio(audioPath, language string) (*transcribe.TranscriptionResult, error) {
 	if m.err != nil {
 		return nil, m.err
 	}
-	return &transcribe.TranscriptionResult{Text: m.transcript}, nil
+	return &transcribe.TranscriptionResult{Text: m.transcript, Segments: m.segments}, nil
 }
 
 // mockSummarizer implements summarize.Summarizer interface
 type mockSummarizer struct {
 	summary string
-	err    error
+	input   string
+	err     error
 }
 
 func (m *mockSummarizer) SummarizeTranscript(transcript, promptName string, forceRebuild bool) (string, error) {
+	m.input = transcript
 	if m.err != nil {
 		return "", m.err
 	}
 	return m.summary, nil
 }
 
+// mockIndexer implements Indexer interface
+type mockIndexer struct {
+	indexed []string
+}
+
+func (m *mockIndexer) IndexTranscript(transcriptPath string) (bool, error) {
+	m.indexed = append(m.indexed, transcriptPath)
+	return true, nil
+}
+
+// mockVerifier implements Verifier interface
+type mockVerifier struct {
+	report *verify.Report
+	err    error
+}
+
+func (m *mockVerifier) Verify(summary, transcript string, judge bool) (*verify.Report, error) {
+	return m.report, m.err
+}
+
+// setupTestPrompt creates the "test" prompt in a temporary HOME directory
+func setupTestPrompt(t *testing.T, content string) {
+	t.Helper()
+	home := t.TempDir()
+	oldHome := os.Getenv("HOME")
+	os.Setenv("HOME", home)
+	t.Cleanup(func() { os.Setenv("HOME", oldHome) })
+
+	promptDir := filepath.Join(home, ".config", "mnote", "prompts")
+	if err := os.MkdirAll(promptDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(promptDir, "test"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestProcessVideo(t *testing.T) {
 	// Create temporary directory
 	tmpDir := t.TempDir()
+	setupTestPrompt(t, "test prompt")
 
 	// Create test video file
 	videoPath := filepath.Join(tmpDir, "test.mp4")
@@ -53,7 +103,10 @@ func TestProcessVideo(t *testing.T) {
 
 	// Create mock dependencies
 	cfg := config.DefaultConfig()
-	transcriber := &mockTranscriber{transcript: "Test transcript"}
+	transcriber := &mockTranscriber{
+		transcript: "Test transcript",
+		segments:   []transcribe.Segment{{Start: 0, End: 2, Text: "Test transcript"}},
+	}
 	summarizer := &mockSummarizer{summary: "Test summary"}
 
 	// Create processor
@@ -63,11 +116,14 @@ func TestProcessVideo(t *testing.T) {
 		summarizer:  summarizer,
 	}
 
+	indexer := &mockIndexer{}
+	processor.SetIndexer(indexer)
+
 	// Test processing
 	opts := Options{
-		Language:     "en",
-		PromptName:   "test",
-		ForceRebuild: true,
+		Language:   "en",
+		PromptName: "test",
+		Force:      Force{ForceAudio: true},
 	}
 
 	err := processor.ProcessVideo(videoPath, opts)
@@ -90,9 +146,345 @@ func TestProcessVideo(t *testing.T) {
 	if !fileExists(summaryPath) {
 		t.Error("Summary file not created")
 	}
+	if !fileExists(filepath.Join(tmpDir, "test_segments.json")) {
+		t.Error("Segments file not created")
+	}
+	if len(indexer.indexed) != 1 || indexer.indexed[0] != transcriptPath {
+		t.Errorf("expected transcript to be indexed, got %v", indexer.indexed)
+	}
+}
+
+// readSummary returns the summary without its provenance header
+func readSummary(t *testing.T, path string) string {
+	t.Helper()
+	return provenance.Strip(string(mustRead(t, path)))
 }
 
 func fileExists(path string) bool {
 	_, err := os.Stat(path)
 	return err == nil
 }
+
+func TestProcessVideoVerify(t *testing.T) {
+	tmpDir := t.TempDir()
+	setupTestPrompt(t, "test prompt")
+	videoPath := filepath.Join(tmpDir, "test.mp4")
+	if err := os.WriteFile(videoPath, []byte("dummy video content"), 0644); err != nil {
+		t.Fatalf("Failed to create test video file: %v", err)
+	}
+
+	utils.SetFFmpegRunner(&utils.MockFFmpegRunner{})
+	defer utils.SetFFmpegRunner(&utils.DefaultFFmpegRunner{})
+
+	processor := NewProcessor(config.DefaultConfig(),
+		&mockTranscriber{transcript: "Test transcript"},
+		&mockSummarizer{summary: "Test summary"})
+	opts := Options{Language: "en", PromptName: "test", Verify: true}
+
+	// Verification needs a verifier
+	if err := processor.ProcessVideo(videoPath, opts); err == nil {
+		t.Error("ProcessVideo() should fail without verifier")
+	}
+
+	processor.SetVerifier(&mockVerifier{report: &verify.Report{
+		Names: []verify.Finding{{Text: "Mallory", Supported: false}},
+	}})
+	if err := processor.ProcessVideo(videoPath, opts); err != nil {
+		t.Fatalf("ProcessVideo() error = %v", err)
+	}
+
+	summary, err := os.ReadFile(filepath.Join(tmpDir, "test_test.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(summary), "## Verification") || !strings.Contains(string(summary), "Mallory") {
+		t.Errorf("expected verification section in summary, got %q", summary)
+	}
+
+	// A failed verification keeps the summary without the section
+	processor.SetVerifier(&mockVerifier{err: errors.New("judge unavailable")})
+	opts.Force = Force{ForceSummary: true}
+	if err := processor.ProcessVideo(videoPath, opts); err != nil {
+		t.Fatalf("ProcessVideo() with a failing verifier error = %v", err)
+	}
+	summary, err = os.ReadFile(filepath.Join(tmpDir, "test_test.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(summary), "Test summary") || strings.Contains(string(summary), "## Verification") {
+		t.Errorf("expected the summary without verification section, got %q", summary)
+	}
+}
+
+func TestProcessVideoCitations(t *testing.T) {
+	tmpDir := t.TempDir()
+	setupTestPrompt(t, "test prompt")
+	videoPath := filepath.Join(tmpDir, "Team Standup.mp4")
+	if err := os.WriteFile(videoPath, []byte("dummy video content"), 0644); err != nil {
+		t.Fatalf("Failed to create test video file: %v", err)
+	}
+
+	utils.SetFFmpegRunner(&utils.MockFFmpegRunner{})
+	defer utils.SetFFmpegRunner(&utils.DefaultFFmpegRunner{})
+
+	summarizer := &mockSummarizer{summary: "- We ship on Friday [00:12:35]"}
+	processor := NewProcessor(config.DefaultConfig(),
+		&mockTranscriber{
+			transcript: "Hello. We ship on Friday.",
+			segments: []transcribe.Segment{
+				{Start: 0, End: 2, Text: " Hello."},
+				{Start: 754, End: 757, Text: " We ship on Friday."},
+			},
+		},
+		summarizer)
+
+	// Links to recordings with spaces in their name are escaped
+	opts := Options{Language: "en", PromptName: "test", MediaLinks: true}
+	if err := processor.ProcessVideo(videoPath, opts); err != nil {
+		t.Fatalf("ProcessVideo() error = %v", err)
+	}
+
+	if summarizer.input != "[00:00:00] Hello.\n[00:12:34] We ship on Friday.\n" {
+		t.Errorf("expected timestamped transcript as summarizer input, got %q", summarizer.input)
+	}
+
+	transcriptContent, _ := os.ReadFile(filepath.Join(tmpDir, "Team Standup_transcript.md"))
+	if !strings.Contains(string(transcriptContent), `<a id="t-00-12-34"></a>[00:12:34] We ship on Friday.`) {
+		t.Errorf("expected anchored transcript, got %q", transcriptContent)
+	}
+
+	summary := readSummary(t, filepath.Join(tmpDir, "Team Standup_test.md"))
+	want := "- We ship on Friday [00:12:35](Team%20Standup_transcript.md#t-00-12-34) ([▶](Team%20Standup.mp4#t=755))"
+	if summary != want {
+		t.Errorf("summary = %q, want %q", summary, want)
+	}
+}
+
+func TestProcessVideoRedact(t *testing.T) {
+	tmpDir := t.TempDir()
+	setupTestPrompt(t, "test prompt")
+	videoPath := filepath.Join(tmpDir, "test.mp4")
+	if err := os.WriteFile(videoPath, []byte("dummy video content"), 0644); err != nil {
+		t.Fatalf("Failed to create test video file: %v", err)
+	}
+
+	utils.SetFFmpegRunner(&utils.MockFFmpegRunner{})
+	defer utils.SetFFmpegRunner(&utils.DefaultFFmpegRunner{})
+
+	summarizer := &mockSummarizer{summary: "Send the offer to <EMAIL_1>."}
+	processor := NewProcessor(config.DefaultConfig(),
+		&mockTranscriber{transcript: "Please write to jane@example.com."},
+		summarizer)
+	processor.SetRedactor(redact.NewRedactorWithDetectors(redact.BuiltinDetectors()))
+	indexer := &mockIndexer{}
+	processor.SetIndexer(indexer)
+
+	opts := Options{Language: "en", PromptName: "test", Redact: true}
+	if err := processor.ProcessVideo(videoPath, opts); err != nil {
+		t.Fatalf("ProcessVideo() error = %v", err)
+	}
+
+	if summarizer.input != "Please write to <EMAIL_1>." {
+		t.Errorf("expected redacted summarizer input, got %q", summarizer.input)
+	}
+	summary := readSummary(t, filepath.Join(tmpDir, "test_test.md"))
+	if summary != "Send the offer to jane@example.com." {
+		t.Errorf("expected de-redacted summary, got %q", summary)
+	}
+	if !fileExists(filepath.Join(tmpDir, "test_redaction.json")) {
+		t.Error("Redaction mapping not saved")
+	}
+	// The unredacted transcript is not sent for embedding
+	if len(indexer.indexed) != 0 {
+		t.Errorf("expected no indexing with redaction, got %v", indexer.indexed)
+	}
+}
+
+func TestProcessVideoRefreshStale(t *testing.T) {
+	tmpDir := t.TempDir()
+	setupTestPrompt(t, "test prompt")
+	videoPath := filepath.Join(tmpDir, "test.mp4")
+	if err := os.WriteFile(videoPath, []byte("dummy video content"), 0644); err != nil {
+		t.Fatalf("Failed to create test video file: %v", err)
+	}
+
+	utils.SetFFmpegRunner(&utils.MockFFmpegRunner{})
+	defer utils.SetFFmpegRunner(&utils.DefaultFFmpegRunner{})
+
+	summarizer := &mockSummarizer{summary: "First summary"}
+	processor := NewProcessor(config.DefaultConfig(), &mockTranscriber{transcript: "Test transcript"}, summarizer)
+	opts := Options{Language: "en", PromptName: "test"}
+	summaryPath := filepath.Join(tmpDir, "test_test.md")
+
+	status, err := processor.SummaryStatus(videoPath, opts)
+	if err != nil || status.State != SummaryMissing {
+		t.Fatalf("SummaryStatus() = %+v, %v, want missing", status, err)
+	}
+
+	if err := processor.ProcessVideo(videoPath, opts); err != nil {
+		t.Fatalf("ProcessVideo() error = %v", err)
+	}
+	recorded, _ := provenance.Parse(string(mustRead(t, summaryPath)))
+	if recorded == nil || recorded.PromptHash != provenance.Hash("test prompt") || recorded.Model != "gpt-4o" {
+		t.Fatalf("unexpected provenance: %+v", recorded)
+	}
+
+	// Up-to-date summaries are kept
+	summarizer.summary = "Second summary"
+	opts.RefreshStale = true
+	if err := processor.ProcessVideo(videoPath, opts); err != nil {
+		t.Fatalf("ProcessVideo() error = %v", err)
+	}
+	if got := readSummary(t, summaryPath); got != "First summary" {
+		t.Errorf("expected up-to-date summary to be kept, got %q", got)
+	}
+
+	// Changing the prompt makes the summary stale
+	setupTestPrompt(t, "improved test prompt")
+	status, err = processor.SummaryStatus(videoPath, opts)
+	if err != nil || status.State != SummaryStale || status.Changes[0] != "prompt content changed" {
+		t.Fatalf("SummaryStatus() = %+v, %v, want stale", status, err)
+	}
+
+	// Stale summaries are only regenerated when refreshing
+	opts.RefreshStale = false
+	processor.ProcessVideo(videoPath, opts)
+	if got := readSummary(t, summaryPath); got != "First summary" {
+		t.Errorf("expected stale summary to be kept without refresh, got %q", got)
+	}
+	opts.RefreshStale = true
+	if err := processor.ProcessVideo(videoPath, opts); err != nil {
+		t.Fatalf("ProcessVideo() error = %v", err)
+	}
+	if got := readSummary(t, summaryPath); got != "Second summary" {
+		t.Errorf("expected stale summary to be regenerated, got %q", got)
+	}
+	status, _ = processor.SummaryStatus(videoPath, opts)
+	if status.State != SummaryCurrent {
+		t.Errorf("expected current summary after refresh, got %+v", status)
+	}
+}
+
+func TestSummaryStatusCitationPrompt(t *testing.T) {
+	tmpDir := t.TempDir()
+	setupTestPrompt(t, "test prompt")
+	videoPath := filepath.Join(tmpDir, "test.mp4")
+	transcriptPath := filepath.Join(tmpDir, "test_transcript.md")
+	os.WriteFile(videoPath, []byte("dummy video content"), 0644)
+	os.WriteFile(transcriptPath, []byte("[00:00:01] Hello.\n"), 0644)
+
+	// A summary recorded with the bare prompt was not asked for timestamp references
+	text, _, err := transcript.ReadTimestamped(transcriptPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary := provenance.Add("Old summary", provenance.New("test", "test prompt", "gpt-4o", text))
+	os.WriteFile(filepath.Join(tmpDir, "test_test.md"), []byte(summary), 0644)
+
+	processor := NewProcessor(config.DefaultConfig(), nil, &mockSummarizer{})
+	status, err := processor.SummaryStatus(videoPath, Options{PromptName: "test"})
+	if err != nil || status.State != SummaryStale || status.Changes[0] != "prompt content changed" {
+		t.Fatalf("SummaryStatus() = %+v, %v, want stale", status, err)
+	}
+
+	// The prompt actually sent is recorded
+	summary = provenance.Add("New summary", provenance.New("test", summarize.SystemPrompt("test prompt", text), "gpt-4o", text))
+	os.WriteFile(filepath.Join(tmpDir, "test_test.md"), []byte(summary), 0644)
+	if status, _ := processor.SummaryStatus(videoPath, Options{PromptName: "test"}); status.State != SummaryCurrent {
+		t.Errorf("expected current summary, got %+v", status)
+	}
+}
+
+func mustRead(t *testing.T, path string) []byte {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return content
+}
+
+// pathTranscriber records the audio files it transcribes
+type pathTranscriber struct {
+	paths []string
+}
+
+func (m *pathTranscriber) TranscribeAudio(audioPath, language string) (*transcribe.TranscriptionResult, error) {
+	m.paths = append(m.paths, filepath.Base(audioPath))
+	return &transcribe.TranscriptionResult{Text: "Test transcript"}, nil
+}
+
+func TestProcessVideoAudioInputs(t *testing.T) {
+	tmpDir := t.TempDir()
+	setupTestPrompt(t, "test prompt")
+
+	// talk.mp3 next to the video talk.mp4 must not be replaced by its extracted audio
+	for _, name := range []string{"memo.m4a", "voice.opus", "talk.mp4", "talk.mp3"} {
+		os.WriteFile(filepath.Join(tmpDir, name), []byte(name), 0644)
+	}
+
+	mockFFmpeg := &utils.MockFFmpegRunner{}
+	utils.SetFFmpegRunner(mockFFmpeg)
+	defer utils.SetFFmpegRunner(&utils.DefaultFFmpegRunner{})
+
+	transcriber := &pathTranscriber{}
+	processor := NewProcessor(config.DefaultConfig(), transcriber, &mockSummarizer{summary: "Test summary"})
+	opts := Options{Language: "en", PromptName: "test"}
+
+	// Audio the backend accepts is transcribed as is
+	if err := processor.ProcessVideo(filepath.Join(tmpDir, "memo.m4a"), opts); err != nil {
+		t.Fatalf("ProcessVideo() error = %v", err)
+	}
+	if mockFFmpeg.ExtractCalled {
+		t.Error("expected m4a audio not to be converted")
+	}
+
+	// Other audio is converted, videos are extracted, and neither replaces an input
+	for _, name := range []string{"voice.opus", "talk.mp4", "talk.mp3"} {
+		if err := processor.ProcessVideo(filepath.Join(tmpDir, name), opts); err != nil {
+			t.Fatalf("ProcessVideo(%s) error = %v", name, err)
+		}
+	}
+	// talk.mp3 shares the outputs of talk.mp4, so its transcript exists
+	want := "memo.m4a voice_audio.mp3 talk_audio.mp3"
+	if got := strings.Join(transcriber.paths, " "); got != want {
+		t.Errorf("expected %s to be transcribed, got %s", want, got)
+	}
+	if content, _ := os.ReadFile(filepath.Join(tmpDir, "talk.mp3")); string(content) != "talk.mp3" {
+		t.Errorf("expected talk.mp3 to be kept, got %q", content)
+	}
+	for _, name := range []string{"memo_transcript.md", "voice_test.md", "talk_transcript.md"} {
+		if !utils.FileExists(filepath.Join(tmpDir, name)) {
+			t.Errorf("expected output %s", name)
+		}
+	}
+}
+
+func TestProcessVideoTemplateAudio(t *testing.T) {
+	tmpDir := t.TempDir()
+	setupTestPrompt(t, "test prompt")
+	videoPath := filepath.Join(tmpDir, "standup.mp4")
+	os.WriteFile(videoPath, []byte("video"), 0644)
+
+	utils.SetFFmpegRunner(&utils.MockFFmpegRunner{})
+	defer utils.SetFFmpegRunner(&utils.DefaultFFmpegRunner{})
+
+	outputs, err := layout.New(layout.Options{Template: "{{.Date}}-{{.Title}}/{{.Kind}}.md"})
+	if err != nil {
+		t.Fatalf("layout.New() error = %v", err)
+	}
+	processor := NewProcessor(config.DefaultConfig(), &mockTranscriber{transcript: "Test transcript"}, &mockSummarizer{summary: "Test summary"})
+	processor.SetLayout(outputs)
+	if err := processor.ProcessVideo(videoPath, Options{Language: "en", PromptName: "test"}); err != nil {
+		t.Fatalf("ProcessVideo() error = %v", err)
+	}
+
+	// The extracted audio is not taken for a new recording by a recursive search
+	found, err := walk.Find(tmpDir, walk.Options{Recursive: true})
+	if err != nil {
+		t.Fatalf("walk.Find() error = %v", err)
+	}
+	if len(found) != 1 || found[0] != videoPath {
+		t.Errorf("expected only the video to be found, got %v", found)
+	}
+}
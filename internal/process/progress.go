@@ -0,0 +1,88 @@
+package process
+
+import (
+	"time"
+
+	"github.com/giantswarm/mnote/internal/progress"
+	"github.com/giantswarm/mnote/internal/transcribe"
+	"github.com/giantswarm/mnote/internal/utils"
+)
+
+// stageUpload is the upload of the audio, reported separately from the transcription
+const stageUpload = "upload"
+
+// extractAudio extracts the audio of a video and reports the extracted time of the
+// audio against the duration of the video
+func (p *Processor) extractAudio(path, audioPath string, opts Options) error {
+	tracker := p.progress()
+	var total time.Duration
+	if tracker.Enabled() {
+		// Without a duration the extracted time is reported without a bar
+		total, _ = utils.MediaDuration(path)
+	}
+	task := tracker.Start(path, StageExtract, int64(total.Seconds()), progress.UnitSeconds)
+	defer task.Done()
+	_, err := utils.ExtractAudioProgress(path, audioPath, opts.Force.Has(ForceAudio), func(d time.Duration) {
+		task.Set(int64(d.Seconds()))
+	})
+	return err
+}
+
+// transcribeAudio transcribes an audio file of a video, the bytes sent are reported while
+// the audio is uploaded if the transcriber supports it
+func (p *Processor) transcribeAudio(path, audioPath string, opts Options) (*transcribe.TranscriptionResult, error) {
+	tracker := p.progress()
+	uploader, ok := p.transcriber.(interface {
+		TranscribeAudioProgress(audioPath, language string, uploaded func(sent, total int64)) (*transcribe.TranscriptionResult, error)
+	})
+	if !ok || !tracker.Enabled() {
+		tracker.Skip(path, stageUpload)
+		return p.transcriber.TranscribeAudio(audioPath, opts.Language)
+	}
+
+	upload := tracker.Start(path, stageUpload, 0, progress.UnitBytes)
+	defer upload.Done()
+	return uploader.TranscribeAudioProgress(audioPath, opts.Language, func(sent, total int64) {
+		upload.SetTotal(total)
+		upload.Set(sent)
+		if sent == total {
+			// The server transcribes once the whole request was received
+			upload.Done()
+		}
+	})
+}
+
+// summarizeProgress summarizes a transcript and reports the received tokens of the summary
+// if the summarizer can stream them
+func (p *Processor) summarizeProgress(path, transcriptText string, opts Options) (string, error) {
+	tracker := p.progress()
+	streamer, ok := p.summarizer.(interface {
+		SummarizeTranscriptProgress(transcript, promptName string, forceRebuild bool, tokens func(n int)) (string, error)
+	})
+	if !ok || !tracker.Enabled() {
+		task := tracker.Start(path, StageSummarize, 0, progress.UnitTokens)
+		defer task.Done()
+		return p.summarizer.SummarizeTranscript(transcriptText, opts.PromptName, opts.Force.Has(ForceSummary))
+	}
+
+	task := tracker.Start(path, StageSummarize, summaryTokens, progress.UnitTokens)
+	defer task.Done()
+	return streamer.SummarizeTranscriptProgress(transcriptText, opts.PromptName, opts.Force.Has(ForceSummary), func(n int) {
+		task.Set(int64(n))
+	})
+}
+
+// addToProgress adds the videos of a batch with their durations to the progress tracker,
+// so the overall ETA covers the whole batch
+func (p *Processor) addToProgress(paths []string) {
+	tracker := p.progress()
+	if !tracker.Enabled() {
+		return
+	}
+	durations := make(map[string]time.Duration, len(paths))
+	for _, path := range paths {
+		// Unknown durations are estimated from the other videos
+		durations[path], _ = utils.MediaDuration(path)
+	}
+	tracker.Add(durations)
+}
@@ -0,0 +1,77 @@
+package process
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/mnote/internal/config"
+	"github.com/giantswarm/mnote/internal/progress"
+	"github.com/giantswarm/mnote/internal/transcribe"
+	"github.com/giantswarm/mnote/internal/utils"
+)
+
+// uploadingTranscriber reports the upload of the audio
+type uploadingTranscriber struct {
+	mockTranscriber
+	sizes []int64
+}
+
+func (m *uploadingTranscriber) TranscribeAudioProgress(audioPath, language string, uploaded func(sent, total int64)) (*transcribe.TranscriptionResult, error) {
+	for _, sent := range m.sizes {
+		uploaded(sent, m.sizes[len(m.sizes)-1])
+	}
+	return m.TranscribeAudio(audioPath, language)
+}
+
+// streamingSummarizer reports the tokens of the summary
+type streamingSummarizer struct {
+	mockSummarizer
+}
+
+func (m *streamingSummarizer) SummarizeTranscriptProgress(transcript, promptName string, forceRebuild bool, tokens func(n int)) (string, error) {
+	for n := range strings.Fields(m.summary) {
+		tokens(n + 1)
+	}
+	return m.SummarizeTranscript(transcript, promptName, forceRebuild)
+}
+
+func TestProcessVideoProgress(t *testing.T) {
+	tmpDir := t.TempDir()
+	setupTestPrompt(t, "test prompt")
+
+	videoPath := filepath.Join(tmpDir, "test.mp4")
+	os.WriteFile(videoPath, []byte("dummy video content"), 0644)
+
+	utils.SetFFmpegRunner(&utils.MockFFmpegRunner{Duration: 90 * time.Second})
+	defer utils.SetFFmpegRunner(&utils.DefaultFFmpegRunner{})
+
+	var buf bytes.Buffer
+	tracker := progress.New(&buf, progress.ModeBars)
+	defer tracker.Close()
+
+	processor := NewProcessor(config.DefaultConfig(),
+		&uploadingTranscriber{mockTranscriber: mockTranscriber{transcript: "Test transcript"}, sizes: []int64{1024, 2048}},
+		&streamingSummarizer{mockSummarizer{summary: "Test summary"}})
+	processor.SetProgress(tracker)
+
+	results := processor.ProcessVideos([]string{videoPath}, Options{Language: "en", PromptName: "test"}, BatchOptions{})
+	if results[0].Err != nil {
+		t.Fatalf("ProcessVideos() error = %v", results[0].Err)
+	}
+
+	// Every stage is drawn when it starts and removed once it is done
+	for _, stage := range []string{"extract", "upload", "transcribe", "summarize"} {
+		if !strings.Contains(buf.String(), "test.mp4 "+stage) {
+			t.Errorf("expected a bar for stage %s, got %q", stage, buf.String())
+		}
+	}
+	buf.Reset()
+	tracker.Write(nil)
+	if buf.Len() != 0 {
+		t.Errorf("expected no bars after processing, got %q", buf.String())
+	}
+}
@@ -0,0 +1,126 @@
+package process
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Stages of the processing pipeline
+const (
+	StageExtract    = "extract"
+	StageTranscribe = "transcribe"
+	StageIndex      = "index"
+	StageSummarize  = "summarize"
+)
+
+// Outcomes of a stage or a whole file
+const (
+	StatusDone       = "done"
+	StatusSkipped    = "skipped"
+	StatusFailed     = "failed"
+	StatusSucceeded  = "succeeded"
+	StatusNotStarted = "not started"
+)
+
+// StageResult is the outcome of one pipeline stage
+type StageResult struct {
+	Stage     string
+	Status    string
+	Err       error
+	StartedAt time.Time
+	Duration  time.Duration
+}
+
+// Result is the outcome of processing one video
+type Result struct {
+	Path   string
+	Stages []StageResult
+	Err    error
+	// LockedBy is the process that held the lock of the video if it was skipped for that
+	LockedBy string
+
+	// stage is the stage currently running, it is blamed if processing fails
+	stage        string
+	stageStarted time.Time
+}
+
+func (r *Result) enter(stage string) {
+	r.stage = stage
+	r.stageStarted = time.Now()
+}
+
+func (r *Result) record(stage, status string, err error) {
+	result := StageResult{Stage: stage, Status: status, Err: err, StartedAt: time.Now()}
+	if stage == r.stage {
+		result.StartedAt = r.stageStarted
+		result.Duration = time.Since(r.stageStarted)
+	}
+	r.Stages = append(r.Stages, result)
+}
+
+// Status returns failed, succeeded if any stage produced output, skipped if all
+// outputs existed already or another process held the lock, or not started
+func (r *Result) Status() string {
+	if r.Err != nil {
+		return StatusFailed
+	}
+	if r.LockedBy != "" {
+		return StatusSkipped
+	}
+	if len(r.Stages) == 0 {
+		return StatusNotStarted
+	}
+	for _, stage := range r.Stages {
+		if stage.Status == StatusDone {
+			return StatusSucceeded
+		}
+	}
+	return StatusSkipped
+}
+
+// Failed returns the results of the videos that failed
+func Failed(results []*Result) []*Result {
+	var failed []*Result
+	for _, r := range results {
+		if r.Err != nil {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
+
+// WriteReport writes a table with the outcome of every video and its stages followed by the totals
+func WriteReport(out io.Writer, results []*Result) error {
+	counts := map[string]int{}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VIDEO\tRESULT\tSTAGES\tERROR")
+	for _, r := range results {
+		var stages []string
+		for _, stage := range r.Stages {
+			stages = append(stages, stage.Stage+":"+stage.Status)
+		}
+		errText := ""
+		if r.Err != nil {
+			errText = r.Err.Error()
+		} else if r.LockedBy != "" {
+			errText = "being processed by " + r.LockedBy
+		}
+		status := r.Status()
+		counts[status]++
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.Path, status, strings.Join(stages, " "), errText)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	totals := fmt.Sprintf("%d succeeded, %d skipped, %d failed",
+		counts[StatusSucceeded], counts[StatusSkipped], counts[StatusFailed])
+	if n := counts[StatusNotStarted]; n > 0 {
+		totals += fmt.Sprintf(", %d not started", n)
+	}
+	_, err := fmt.Fprintf(out, "\nProcessed %d videos: %s\n", len(results), totals)
+	return err
+}
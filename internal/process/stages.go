@@ -0,0 +1,94 @@
+package process
+
+import (
+	"fmt"
+
+	"github.com/giantswarm/mnote/internal/layout"
+	"github.com/giantswarm/mnote/internal/logging"
+	"github.com/giantswarm/mnote/internal/provenance"
+	"github.com/giantswarm/mnote/internal/redact"
+	"github.com/giantswarm/mnote/internal/transcribe"
+	"github.com/giantswarm/mnote/internal/transcript"
+	"github.com/giantswarm/mnote/internal/utils"
+	"github.com/giantswarm/mnote/internal/verify"
+)
+
+// SaveTranscript writes a transcription result, as anchored timestamped paragraphs with a
+// segments file for citations if the backend returned segments
+func SaveTranscript(transcriptPath string, result *transcribe.TranscriptionResult) error {
+	content := result.Text
+	if len(result.Segments) > 0 {
+		content = transcript.FormatMarkdown(result.Segments)
+	}
+	if err := utils.WriteFile(transcriptPath, []byte(content)); err != nil {
+		return fmt.Errorf("failed to save transcript: %w", err)
+	}
+	if len(result.Segments) > 0 {
+		if err := transcript.SaveSegments(transcript.SegmentsPath(transcriptPath), result.Segments); err != nil {
+			return fmt.Errorf("failed to save segments: %w", err)
+		}
+	}
+	return nil
+}
+
+// LockSource takes the lock of a source that mnote run takes while processing it, so a
+// single stage run on the source does not race with a run. It fails with a
+// *lock.LockedError if another process holds the lock, unless opts.WaitLocked is set. It
+// returns the function releasing the lock.
+func (p *Processor) LockSource(path string, opts Options) (func(), error) {
+	return p.lockSource(path, opts)
+}
+
+// SummarizeFile summarizes a transcript file of any origin, plain text or markdown, with the
+// prompt of opts and saves the summary with its provenance. Timestamp references are linked
+// to the transcript if it has timestamps. With opts.Redact the transcript is redacted before
+// it is sent, transcripts written by a run use the redaction mapping of their video.
+func (p *Processor) SummarizeFile(transcriptPath, summaryPath string, opts Options) error {
+	transcriptText, starts, err := transcript.ReadTimestamped(transcriptPath)
+	if err != nil {
+		return fmt.Errorf("failed to read transcript: %w", err)
+	}
+	expected, err := p.expectedProvenance(transcriptText, opts.PromptName)
+	if err != nil {
+		return err
+	}
+
+	// Transcripts written by a run share the redaction mapping of their video
+	var mapping *redact.Mapping
+	if opts.Redact {
+		mappingPath, ok := p.outputLayout().Sibling(transcriptPath, layout.KindTranscript, layout.KindRedaction, ".json")
+		if !ok {
+			mappingPath = p.outputLayout().Redaction(transcriptPath)
+		}
+		if transcriptText, mapping, err = p.redact(mappingPath, transcriptText, opts); err != nil {
+			return err
+		}
+	}
+
+	summary, report, err := p.summarize(transcriptPath, transcriptText, opts)
+	if err != nil {
+		return err
+	}
+	if report != nil {
+		if unsupported := report.Unsupported(); len(unsupported) > 0 {
+			opts.log().Warn("Statements in the summary are not supported by the transcript",
+				logging.StageKey, StageSummarize, "unsupported", len(unsupported))
+		}
+	}
+	if len(starts) > 0 {
+		summary = transcript.LinkCitations(summary, starts, utils.RelativeLink(summaryPath, transcriptPath), "")
+	}
+	if report != nil {
+		summary = verify.AppendSection(summary, report)
+	}
+	if mapping != nil {
+		summary = mapping.Restore(summary)
+	}
+
+	summary = provenance.Add(summary, expected)
+	if err := utils.WriteFile(summaryPath, []byte(summary)); err != nil {
+		return fmt.Errorf("failed to save summary: %w", err)
+	}
+	p.removeWork(transcriptPath, opts)
+	return nil
+}
@@ -0,0 +1,74 @@
+package process
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/giantswarm/mnote/internal/config"
+	"github.com/giantswarm/mnote/internal/provenance"
+	"github.com/giantswarm/mnote/internal/transcribe"
+	"github.com/giantswarm/mnote/internal/transcript"
+	"github.com/giantswarm/mnote/internal/utils"
+)
+
+func TestSaveTranscript(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	plainPath := filepath.Join(tmpDir, "plain_transcript.md")
+	if err := SaveTranscript(plainPath, &transcribe.TranscriptionResult{Text: "Hello"}); err != nil {
+		t.Fatalf("SaveTranscript() error = %v", err)
+	}
+	if content, _ := os.ReadFile(plainPath); string(content) != "Hello" {
+		t.Errorf("expected plain transcript, got %q", content)
+	}
+	if utils.FileExists(transcript.SegmentsPath(plainPath)) {
+		t.Error("expected no segments file without segments")
+	}
+
+	timedPath := filepath.Join(tmpDir, "timed_transcript.md")
+	result := &transcribe.TranscriptionResult{
+		Text:     "Hello",
+		Segments: []transcribe.Segment{{Start: 61, End: 62, Text: "Hello"}},
+	}
+	if err := SaveTranscript(timedPath, result); err != nil {
+		t.Fatalf("SaveTranscript() error = %v", err)
+	}
+	if content, _ := os.ReadFile(timedPath); !strings.Contains(string(content), "00:01:01") {
+		t.Errorf("expected timestamped transcript, got %q", content)
+	}
+	if !utils.FileExists(transcript.SegmentsPath(timedPath)) {
+		t.Error("expected segments file")
+	}
+}
+
+func TestSummarizeFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	setupTestPrompt(t, "test prompt")
+
+	notesPath := filepath.Join(tmpDir, "notes.txt")
+	os.WriteFile(notesPath, []byte("# Notes\n\nWe agreed to ship on Friday."), 0644)
+
+	summarizer := &mockSummarizer{summary: "Ship on Friday."}
+	processor := &Processor{config: config.DefaultConfig(), summarizer: summarizer}
+	summaryPath := filepath.Join(tmpDir, "notes_test.md")
+	if err := processor.SummarizeFile(notesPath, summaryPath, Options{PromptName: "test"}); err != nil {
+		t.Fatalf("SummarizeFile() error = %v", err)
+	}
+
+	if !strings.Contains(summarizer.input, "We agreed to ship on Friday.") {
+		t.Errorf("expected the transcript to be summarized, got %q", summarizer.input)
+	}
+	content, err := os.ReadFile(summaryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, body := provenance.Parse(string(content))
+	if p == nil || p.Prompt != "test" {
+		t.Errorf("expected provenance for the test prompt, got %+v", p)
+	}
+	if body != "Ship on Friday." {
+		t.Errorf("unexpected summary body: %q", body)
+	}
+}
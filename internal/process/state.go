@@ -0,0 +1,153 @@
+package process
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/mnote/internal/lock"
+	"github.com/giantswarm/mnote/internal/state"
+)
+
+// startState marks the video as running in the state manifest of its directory and
+// returns the source file as it was when processing started
+func (p *Processor) startState(path string) (os.FileInfo, error) {
+	source, err := os.Stat(path)
+	if err != nil {
+		return nil, nil
+	}
+	return source, state.Update(p.outputLayout().Dir(path), func(m *state.Manifest) error {
+		f := m.Files[filepath.Base(path)]
+		if f == nil {
+			f = &state.FileState{}
+			m.Files[filepath.Base(path)] = f
+		}
+		f.Status = state.StatusRunning
+		f.StartedAt = time.Now()
+		f.FinishedAt = time.Time{}
+		f.Error = ""
+		return nil
+	})
+}
+
+// saveState records the outcome of every stage in the state manifest. Skipped stages keep
+// the details of the run that produced their output.
+func (p *Processor) saveState(res *Result, source os.FileInfo, opts Options) error {
+	if source == nil {
+		return nil
+	}
+	dir := p.outputLayout().Dir(res.Path)
+	name := filepath.Base(res.Path)
+
+	var hash string
+	if current, err := os.Stat(res.Path); err == nil && current.Size() == source.Size() && current.ModTime().Equal(source.ModTime()) {
+		if previous, err := state.Load(dir); err == nil {
+			if f := previous.Files[name]; f != nil && f.Matches(source) {
+				hash = f.SourceHash
+			}
+		}
+		if hash == "" {
+			hash, _ = state.HashFile(res.Path)
+		}
+	}
+
+	return state.Update(dir, func(m *state.Manifest) error {
+		f := m.Files[name]
+		if f == nil {
+			f = &state.FileState{}
+			m.Files[name] = f
+		}
+		f.SourceHash = hash
+		f.SourceSize = source.Size()
+		f.SourceModTime = source.ModTime()
+		f.FinishedAt = time.Now()
+		f.Error = ""
+		switch res.Status() {
+		case StatusFailed:
+			f.Status = state.StatusFailed
+			f.Error = res.Err.Error()
+		case StatusSkipped:
+			f.Status = state.StatusSkipped
+		default:
+			f.Status = state.StatusSucceeded
+		}
+
+		if f.Stages == nil {
+			f.Stages = map[string]*state.StageState{}
+		}
+		for _, stage := range res.Stages {
+			key := stage.Stage
+			if key == StageSummarize {
+				key += ":" + opts.PromptName
+			}
+			if stage.Status == StatusSkipped && f.Stages[key] != nil {
+				continue
+			}
+			s := &state.StageState{
+				Status:     stage.Status,
+				StartedAt:  stage.StartedAt,
+				DurationMS: stage.Duration.Milliseconds(),
+				Model:      p.stageModel(stage.Stage, opts),
+				Output:     p.stageOutput(dir, res.Path, stage.Stage, opts),
+			}
+			if stage.Err != nil {
+				s.Error = stage.Err.Error()
+			}
+			f.Stages[key] = s
+		}
+		return nil
+	})
+}
+
+// stageModel returns the model used by a stage
+func (p *Processor) stageModel(stage string, opts Options) string {
+	switch stage {
+	case StageTranscribe:
+		return p.config.GetWhisperModel(opts.Language)
+	case StageIndex:
+		return p.config.EmbeddingModel
+	case StageSummarize:
+		return p.config.ChatGPTModel
+	}
+	return ""
+}
+
+// stageOutput returns the file written by a stage relative to the directory of the video
+func (p *Processor) stageOutput(dir, path, stage string, opts Options) string {
+	var output string
+	switch stage {
+	case StageExtract:
+		output = p.outputLayout().Audio(path)
+	case StageTranscribe:
+		output = p.outputLayout().Transcript(path)
+	case StageSummarize:
+		output = p.outputLayout().Summary(path, opts.PromptName)
+	default:
+		return ""
+	}
+	if rel, err := filepath.Rel(dir, output); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return output
+}
+
+// lockSource takes the lock of a video in its state directory, so other mnote processes
+// skip or wait for it. It returns the function releasing the lock.
+func (p *Processor) lockSource(path string, opts Options) (func(), error) {
+	lockPath := filepath.Join(p.outputLayout().Dir(path), state.Dir, filepath.Base(path)+".lock")
+	acquire := lock.Acquire
+	if opts.WaitLocked {
+		acquire = lock.Wait
+	}
+	l, err := acquire(lockPath, lock.DefaultStaleAfter)
+	if err != nil {
+		return nil, err
+	}
+	opts.log().Debug("Acquired lock", "path", lockPath)
+	return func() {
+		if err := l.Release(); err != nil {
+			opts.log().Warn("Failed to release lock", "error", err)
+		}
+	}, nil
+}
@@ -0,0 +1,118 @@
+package process
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/mnote/internal/config"
+	"github.com/giantswarm/mnote/internal/lock"
+	"github.com/giantswarm/mnote/internal/state"
+	"github.com/giantswarm/mnote/internal/utils"
+)
+
+func TestProcessVideoState(t *testing.T) {
+	tmpDir := t.TempDir()
+	setupTestPrompt(t, "test prompt")
+
+	videoPath := filepath.Join(tmpDir, "test.mp4")
+	os.WriteFile(videoPath, []byte("dummy video content"), 0644)
+
+	utils.SetFFmpegRunner(&utils.MockFFmpegRunner{})
+	defer utils.SetFFmpegRunner(&utils.DefaultFFmpegRunner{})
+
+	cfg := config.DefaultConfig()
+	summarizer := &mockSummarizer{summary: "Test summary"}
+	processor := NewProcessor(cfg, &mockTranscriber{transcript: "Test transcript"}, summarizer)
+	opts := Options{Language: "en", PromptName: "test"}
+
+	if err := processor.ProcessVideo(videoPath, opts); err != nil {
+		t.Fatalf("ProcessVideo() error = %v", err)
+	}
+
+	m, err := state.Load(tmpDir)
+	if err != nil {
+		t.Fatalf("state.Load() error = %v", err)
+	}
+	f := m.Files["test.mp4"]
+	if f == nil {
+		t.Fatal("expected video in state manifest")
+	}
+	hash, _ := state.HashFile(videoPath)
+	if f.Status != state.StatusSucceeded || f.SourceHash != hash || f.SourceSize != 19 {
+		t.Errorf("unexpected file state: %+v", f)
+	}
+	transcribe := f.Stages[StageTranscribe]
+	if transcribe == nil || transcribe.Status != StatusDone || transcribe.Model != cfg.GetWhisperModel("en") || transcribe.Output != "test_transcript.md" {
+		t.Errorf("unexpected transcribe state: %+v", transcribe)
+	}
+	summary := f.Stages[StageSummarize+":test"]
+	if summary == nil || summary.Model != cfg.ChatGPTModel || summary.Output != "test_test.md" {
+		t.Errorf("unexpected summarize state: %+v", summary)
+	}
+
+	// A failing run keeps the details of the skipped stages and records the error
+	os.Remove(filepath.Join(tmpDir, "test_test.md"))
+	summarizer.err = fmt.Errorf("rate limited")
+	if err := processor.ProcessVideo(videoPath, opts); err == nil {
+		t.Fatal("expected summarization error")
+	}
+	m, _ = state.Load(tmpDir)
+	f = m.Files["test.mp4"]
+	if f.Status != state.StatusFailed || f.Error == "" {
+		t.Errorf("expected failed state with error, got %+v", f)
+	}
+	if f.Stages[StageTranscribe].Status != StatusDone {
+		t.Errorf("expected transcribe stage to keep its details, got %+v", f.Stages[StageTranscribe])
+	}
+	if s := f.Stages[StageSummarize+":test"]; s.Status != StatusFailed || s.Error == "" {
+		t.Errorf("expected failed summarize stage, got %+v", s)
+	}
+	if failed, _ := state.Failed(tmpDir); len(failed) != 1 || failed[0] != videoPath {
+		t.Errorf("expected video to be listed as failed, got %v", failed)
+	}
+}
+
+func TestProcessVideoLocked(t *testing.T) {
+	tmpDir := t.TempDir()
+	setupTestPrompt(t, "test prompt")
+
+	videoPath := filepath.Join(tmpDir, "test.mp4")
+	os.WriteFile(videoPath, []byte("dummy video content"), 0644)
+
+	utils.SetFFmpegRunner(&utils.MockFFmpegRunner{})
+	defer utils.SetFFmpegRunner(&utils.DefaultFFmpegRunner{})
+
+	// Another mnote process holds the lock of the video
+	held, err := lock.Acquire(filepath.Join(tmpDir, state.Dir, "test.mp4.lock"), time.Minute)
+	if err != nil {
+		t.Fatalf("lock.Acquire() error = %v", err)
+	}
+
+	processor := NewProcessor(config.DefaultConfig(), &mockTranscriber{transcript: "Test transcript"}, &mockSummarizer{summary: "Test summary"})
+	opts := Options{Language: "en", PromptName: "test"}
+	res := processor.ProcessVideoResult(videoPath, opts)
+	if res.Status() != StatusSkipped || res.LockedBy == "" {
+		t.Errorf("expected the locked video to be skipped, got %s", res.Status())
+	}
+	if utils.FileExists(filepath.Join(tmpDir, "test_transcript.md")) {
+		t.Error("expected no transcript for the locked video")
+	}
+
+	// With WaitLocked the video is processed once the lock is released
+	lock.PollInterval = 10 * time.Millisecond
+	defer func() { lock.PollInterval = time.Second }()
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		held.Release()
+	}()
+	opts.WaitLocked = true
+	if res := processor.ProcessVideoResult(videoPath, opts); res.Status() != StatusSucceeded {
+		t.Errorf("expected the video to be processed after waiting, got %s: %v", res.Status(), res.Err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, state.Dir, "test.mp4.lock")); !os.IsNotExist(err) {
+		t.Errorf("expected the lock to be released, got %v", err)
+	}
+}
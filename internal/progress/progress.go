@@ -0,0 +1,505 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/mnote/internal/logging"
+)
+
+// Modes of a Tracker
+const (
+	// ModeOff reports nothing
+	ModeOff = iota
+	// ModeBars redraws progress bars on a terminal
+	ModeBars
+	// ModeLog logs the progress periodically
+	ModeLog
+)
+
+// Units of the amount of work of a task
+const (
+	UnitSeconds = "seconds"
+	UnitBytes   = "bytes"
+	UnitChunks  = "chunks"
+	UnitTokens  = "tokens"
+)
+
+// Stages reported by the pipeline, with their share of the work on a file for the ETA
+var stageWeights = map[string]float64{
+	"extract":    0.1,
+	"upload":     0.2,
+	"transcribe": 0.4,
+	"summarize":  0.3,
+}
+
+// LogInterval is how often the progress is logged in ModeLog
+var LogInterval = 30 * time.Second
+
+// redrawInterval limits how often bars are redrawn on updates
+const redrawInterval = 100 * time.Millisecond
+
+// barWidth is the number of characters of a progress bar
+const barWidth = 24
+
+// Tracker collects the progress of the stages of every file and reports it as bars on a
+// terminal or as periodic log lines. The overall ETA of a batch is based on the audio
+// duration of its files. A Tracker is safe for concurrent use.
+type Tracker struct {
+	mu    sync.Mutex
+	out   io.Writer
+	mode  int
+	now   func() time.Time
+	start time.Time
+
+	files map[string]*file
+	tasks []*Task
+
+	// lines is the number of bar lines currently drawn
+	lines    int
+	lastDraw time.Time
+	stop     chan struct{}
+	stopped  chan struct{}
+}
+
+// file is the progress of one file of the batch
+type file struct {
+	// batch is set for files added with Add, only they are part of the overall progress
+	batch    bool
+	duration time.Duration
+	// stages holds the completed share of every stage
+	stages map[string]float64
+	done   bool
+}
+
+// New creates a Tracker reporting to out in the given mode
+func New(out io.Writer, mode int) *Tracker {
+	return &Tracker{out: out, mode: mode, now: time.Now, files: map[string]*file{}}
+}
+
+var defaultTracker = New(io.Discard, ModeOff)
+
+// Default returns the tracker set with SetDefault, one reporting nothing otherwise
+func Default() *Tracker {
+	return defaultTracker
+}
+
+// SetDefault makes t the default tracker
+func SetDefault(t *Tracker) {
+	defaultTracker = t
+}
+
+// Enabled reports whether the tracker reports anything, callers can skip measuring otherwise
+func (t *Tracker) Enabled() bool {
+	return t.mode != ModeOff
+}
+
+// Add adds files with their audio duration to the batch, the ETA covers all added files.
+// A zero duration is replaced by the average of the known ones.
+func (t *Tracker) Add(durations map[string]time.Duration) {
+	if !t.Enabled() {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for path, d := range durations {
+		t.files[path] = &file{batch: true, duration: d, stages: map[string]float64{}}
+	}
+	if t.start.IsZero() {
+		t.start = t.now()
+	}
+	t.run()
+}
+
+// Start starts reporting a stage of a file with the total amount of work in unit
+func (t *Tracker) Start(path, stage string, total int64, unit string) *Task {
+	task := &Task{tracker: t, path: path, stage: stage, total: total, unit: unit}
+	if !t.Enabled() {
+		return task
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.start.IsZero() {
+		t.start = t.now()
+	}
+	task.started = t.now()
+	t.tasks = append(t.tasks, task)
+	t.run()
+	t.draw(true)
+	return task
+}
+
+// Skip marks a stage of a file as complete without work
+func (t *Tracker) Skip(path, stage string) {
+	if !t.Enabled() {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.setStage(path, stage, 1)
+}
+
+// Finish marks all stages of a file as complete
+func (t *Tracker) Finish(path string) {
+	if !t.Enabled() {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if f := t.files[path]; f != nil && f.batch {
+		f.done = true
+	} else {
+		delete(t.files, path)
+	}
+	t.draw(true)
+}
+
+// Close stops reporting and removes the bars from the terminal
+func (t *Tracker) Close() {
+	t.mu.Lock()
+	stop, stopped := t.stop, t.stopped
+	t.stop = nil
+	t.clear()
+	t.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		<-stopped
+	}
+}
+
+// Write writes log output above the bars, so log lines and bars do not overwrite each other
+func (t *Tracker) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.clear()
+	n, err := t.out.Write(p)
+	t.draw(true)
+	return n, err
+}
+
+// Task is the progress of one stage of a file
+type Task struct {
+	tracker *Tracker
+	path    string
+	stage   string
+	unit    string
+	total   int64
+	done    int64
+	started time.Time
+}
+
+// Set sets the amount of work done
+func (task *Task) Set(done int64) {
+	t := task.tracker
+	if !t.Enabled() {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	task.done = done
+	t.setStage(task.path, task.stage, task.fraction())
+	t.draw(false)
+}
+
+// SetTotal sets the total amount of work once it is known
+func (task *Task) SetTotal(total int64) {
+	t := task.tracker
+	if !t.Enabled() {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	task.total = total
+}
+
+// Add adds to the amount of work done
+func (task *Task) Add(n int64) {
+	t := task.tracker
+	if !t.Enabled() {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	task.done += n
+	t.setStage(task.path, task.stage, task.fraction())
+	t.draw(false)
+}
+
+// Done completes the task, failed or not, and stops reporting it
+func (task *Task) Done() {
+	t := task.tracker
+	if !t.Enabled() {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i, other := range t.tasks {
+		if other == task {
+			t.tasks = append(t.tasks[:i], t.tasks[i+1:]...)
+			break
+		}
+	}
+	t.setStage(task.path, task.stage, 1)
+	t.draw(true)
+}
+
+// fraction returns the completed share of the task, it stays below 1 until Done as the
+// total of some tasks is an estimate
+func (task *Task) fraction() float64 {
+	if task.total <= 0 {
+		return 0
+	}
+	f := float64(task.done) / float64(task.total)
+	if f > 0.99 {
+		f = 0.99
+	}
+	return f
+}
+
+func (t *Tracker) setStage(path, stage string, fraction float64) {
+	f := t.files[path]
+	if f == nil {
+		f = &file{stages: map[string]float64{}}
+		t.files[path] = f
+	}
+	f.stages[stage] = fraction
+}
+
+// batchSize returns the number of files added with Add
+func (t *Tracker) batchSize() int {
+	var n int
+	for _, f := range t.files {
+		if f.batch {
+			n++
+		}
+	}
+	return n
+}
+
+// overall returns the completed share of the batch weighted by audio duration, the
+// number of finished files and the estimated remaining time
+func (t *Tracker) overall() (float64, int, time.Duration) {
+	var known time.Duration
+	var n int
+	for _, f := range t.files {
+		if f.batch && f.duration > 0 {
+			known += f.duration
+			n++
+		}
+	}
+	average := time.Minute
+	if n > 0 {
+		average = known / time.Duration(n)
+	}
+
+	var total, done float64
+	var finished int
+	for _, f := range t.files {
+		if !f.batch {
+			continue
+		}
+		weight := f.duration.Seconds()
+		if f.duration <= 0 {
+			weight = average.Seconds()
+		}
+		share := 1.0
+		if !f.done {
+			share = 0
+			for stage, fraction := range f.stages {
+				share += stageWeights[stage] * fraction
+			}
+		} else {
+			finished++
+		}
+		total += weight
+		done += weight * share
+	}
+	if total == 0 {
+		return 0, finished, 0
+	}
+	fraction := done / total
+	var eta time.Duration
+	if fraction > 0 && fraction < 1 {
+		elapsed := t.now().Sub(t.start)
+		eta = time.Duration(float64(elapsed) * (1 - fraction) / fraction)
+	}
+	return fraction, finished, eta
+}
+
+// run starts the ticker that redraws the bars or logs the progress while there is no update
+func (t *Tracker) run() {
+	if t.stop != nil {
+		return
+	}
+	interval := time.Second
+	if t.mode == ModeLog {
+		interval = LogInterval
+	}
+	t.stop = make(chan struct{})
+	t.stopped = make(chan struct{})
+	go func(stop, stopped chan struct{}) {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if t.mode == ModeLog {
+					// The log may be written through the tracker, so it is not held while logging
+					t.mu.Lock()
+					records := t.records()
+					t.mu.Unlock()
+					for _, r := range records {
+						slog.Info(r.msg, r.attrs...)
+					}
+					continue
+				}
+				t.mu.Lock()
+				t.draw(true)
+				t.mu.Unlock()
+			}
+		}
+	}(t.stop, t.stopped)
+}
+
+// record is a log record of the progress
+type record struct {
+	msg   string
+	attrs []interface{}
+}
+
+// records returns the log records of the running tasks and the batch
+func (t *Tracker) records() []record {
+	var records []record
+	for _, task := range t.tasks {
+		attrs := []interface{}{logging.FileKey, task.path, logging.LabelKey, filepath.Base(task.path),
+			logging.StageKey, task.stage, "done", task.amount()}
+		if task.total > 0 {
+			attrs = append(attrs, "percent", int(task.fraction()*100))
+		}
+		records = append(records, record{"Progress", attrs})
+	}
+	if n := t.batchSize(); n > 1 {
+		fraction, finished, eta := t.overall()
+		records = append(records, record{"Overall progress", []interface{}{"percent", int(fraction * 100),
+			"files", fmt.Sprintf("%d/%d", finished, n), "eta", formatDuration(eta)}})
+	}
+	return records
+}
+
+// draw redraws the bars, updates are throttled unless force is set
+func (t *Tracker) draw(force bool) {
+	if t.mode != ModeBars {
+		return
+	}
+	now := t.now()
+	if !force && now.Sub(t.lastDraw) < redrawInterval {
+		return
+	}
+	t.lastDraw = now
+	t.clear()
+
+	var lines []string
+	for _, task := range t.tasks {
+		lines = append(lines, task.line(now))
+	}
+	if n := t.batchSize(); n > 1 {
+		fraction, finished, eta := t.overall()
+		line := fmt.Sprintf("%-32s %s %3d%%  %d/%d files", "overall", bar(fraction), int(fraction*100), finished, n)
+		if eta > 0 {
+			line += "  ETA " + formatDuration(eta)
+		}
+		lines = append(lines, line)
+	}
+	for _, line := range lines {
+		fmt.Fprintln(t.out, line)
+	}
+	t.lines = len(lines)
+}
+
+// clear removes the drawn bars, the cursor ends up where the first bar was
+func (t *Tracker) clear() {
+	if t.mode != ModeBars {
+		return
+	}
+	for ; t.lines > 0; t.lines-- {
+		fmt.Fprint(t.out, "\033[1A\033[2K")
+	}
+}
+
+// line renders the bar of a task
+func (task *Task) line(now time.Time) string {
+	label := filepath.Base(task.path) + " " + task.stage
+	if len(label) > 32 {
+		label = "…" + label[len(label)-31:]
+	}
+	if task.total <= 0 {
+		return fmt.Sprintf("%-32s %s  %s", label, task.amount(), formatDuration(now.Sub(task.started)))
+	}
+	fraction := task.fraction()
+	line := fmt.Sprintf("%-32s %s %3d%%  %s", label, bar(fraction), int(fraction*100), task.amount())
+	if elapsed := now.Sub(task.started); fraction > 0.01 && elapsed > time.Second {
+		line += "  ETA " + formatDuration(time.Duration(float64(elapsed)*(1-fraction)/fraction))
+	}
+	return line
+}
+
+// amount formats the work done and the total of a task
+func (task *Task) amount() string {
+	switch task.unit {
+	case UnitSeconds:
+		done := formatDuration(time.Duration(task.done) * time.Second)
+		if task.total <= 0 {
+			return done
+		}
+		return done + "/" + formatDuration(time.Duration(task.total)*time.Second)
+	case UnitBytes:
+		if task.total <= 0 {
+			return formatBytes(task.done)
+		}
+		return formatBytes(task.done) + "/" + formatBytes(task.total)
+	default:
+		if task.total <= 0 || task.unit == UnitTokens {
+			return fmt.Sprintf("%d %s", task.done, task.unit)
+		}
+		return fmt.Sprintf("%d/%d %s", task.done, task.total, task.unit)
+	}
+}
+
+func bar(fraction float64) string {
+	filled := int(fraction * barWidth)
+	if filled > barWidth {
+		filled = barWidth
+	}
+	return "[" + strings.Repeat("=", filled) + strings.Repeat(" ", barWidth-filled) + "]"
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
+
+// formatDuration formats a duration as m:ss or h:mm:ss
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	h, m, s := int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%d:%02d", m, s)
+}
@@ -0,0 +1,144 @@
+package progress
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClock returns a clock advanced by hand
+func fakeClock(tracker *Tracker) *time.Time {
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	tracker.now = func() time.Time { return now }
+	return &now
+}
+
+func TestOverall(t *testing.T) {
+	tracker := New(io.Discard, ModeLog)
+	defer tracker.Close()
+	now := fakeClock(tracker)
+
+	tracker.Add(map[string]time.Duration{
+		"a.mp4": time.Minute,
+		"b.mp4": 3 * time.Minute,
+		"c.mp4": 0,
+	})
+	*now = now.Add(30 * time.Second)
+	tracker.Finish("a.mp4")
+
+	// c.mp4 counts with the average duration of 2 minutes, 1 of 6 minutes took 30 seconds
+	fraction, finished, eta := tracker.overall()
+	if !near(fraction, 1.0/6) || finished != 1 {
+		t.Errorf("overall() = %v, %d files, want 1/6, 1 file", fraction, finished)
+	}
+	if eta != 150*time.Second {
+		t.Errorf("overall() ETA = %v, want 2m30s", eta)
+	}
+
+	// Stages count with their weight
+	tracker.Skip("b.mp4", "extract")
+	task := tracker.Start("b.mp4", "upload", 100, UnitBytes)
+	task.Set(50)
+	want := (60 + 180*(0.1+0.2*0.5)) / 360
+	if fraction, _, _ := tracker.overall(); !near(fraction, want) {
+		t.Errorf("overall() = %v after progress on b.mp4, want %v", fraction, want)
+	}
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestRecords(t *testing.T) {
+	tracker := New(io.Discard, ModeLog)
+	defer tracker.Close()
+	fakeClock(tracker)
+
+	tracker.Add(map[string]time.Duration{"a.mp4": time.Minute, "b.mp4": time.Minute})
+	task := tracker.Start("a.mp4", "extract", 60, UnitSeconds)
+	task.Set(30)
+
+	records := tracker.records()
+	if len(records) != 2 || records[0].msg != "Progress" || records[1].msg != "Overall progress" {
+		t.Fatalf("unexpected records: %+v", records)
+	}
+	attrs := records[0].attrs
+	if got := attrs[len(attrs)-3]; got != "0:30/1:00" {
+		t.Errorf("done = %v, want 0:30/1:00", got)
+	}
+	if got := attrs[len(attrs)-1]; got != 50 {
+		t.Errorf("percent = %v, want 50", got)
+	}
+
+	// Completed tasks are no longer reported
+	task.Done()
+	if records := tracker.records(); len(records) != 1 {
+		t.Errorf("expected only the overall record, got %+v", records)
+	}
+}
+
+func TestBars(t *testing.T) {
+	var buf bytes.Buffer
+	tracker := New(&buf, ModeBars)
+	defer tracker.Close()
+	fakeClock(tracker)
+
+	task := tracker.Start("/videos/meeting.mp4", "upload", 2<<20, UnitBytes)
+	task.Set(1 << 20)
+	buf.Reset()
+
+	// Log lines are written above the bar, which is drawn again below them
+	tracker.Write([]byte("Transcribing audio\n"))
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], "Transcribing audio") {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+	if !strings.HasPrefix(lines[0], "\033[1A\033[2K") {
+		t.Errorf("expected the bar to be cleared first, got %q", lines[0])
+	}
+	want := "meeting.mp4 upload               [============            ]  50%  1.0 MB/2.0 MB"
+	if lines[1] != want {
+		t.Errorf("bar = %q, want %q", lines[1], want)
+	}
+
+	// Closing removes the bar
+	buf.Reset()
+	tracker.Close()
+	if buf.String() != "\033[1A\033[2K" {
+		t.Errorf("expected the bar to be cleared on close, got %q", buf.String())
+	}
+}
+
+func TestOff(t *testing.T) {
+	var buf bytes.Buffer
+	tracker := New(&buf, ModeOff)
+	task := tracker.Start("a.mp4", "extract", 60, UnitSeconds)
+	task.Set(30)
+	task.Done()
+	tracker.Write([]byte("log line\n"))
+	tracker.Close()
+
+	if buf.String() != "log line\n" {
+		t.Errorf("expected only the log line, got %q", buf.String())
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "0:00"},
+		{59 * time.Second, "0:59"},
+		{61*time.Minute + 5*time.Second, "1:01:05"},
+		{1500 * time.Millisecond, "0:02"},
+	}
+	for _, tt := range tests {
+		if got := formatDuration(tt.d); got != tt.want {
+			t.Errorf("formatDuration(%v) = %s, want %s", tt.d, got, tt.want)
+		}
+	}
+}
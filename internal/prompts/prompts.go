@@ -0,0 +1,217 @@
+package prompts
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SourceBuiltin and SourceUser describe where a prompt was loaded from
+const (
+	SourceBuiltin = "builtin"
+	SourceUser    = "user"
+)
+
+//go:embed builtin/*
+var builtinFS embed.FS
+
+// Prompt is a summarization prompt
+type Prompt struct {
+	Name        string
+	Description string
+	Content     string
+	Source      string
+	Path        string // Path of the user file, empty for built-in prompts
+	Overrides   bool   // Whether a user file overrides a built-in prompt of the same name
+}
+
+// Dir returns the directory that holds the user prompts
+func Dir() string {
+	return filepath.Join(os.Getenv("HOME"), ".config", "mnote", "prompts")
+}
+
+// Path returns the path of the user prompt file with the given name
+func Path(name string) string {
+	return filepath.Join(Dir(), name)
+}
+
+// ValidateName checks if the given name can be used as a prompt file name
+func ValidateName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return fmt.Errorf("invalid prompt name: %q", name)
+	}
+	return nil
+}
+
+// Parse splits raw prompt file content into its description and prompt text.
+// The description is read from an optional front matter block:
+//
+//	---
+//	description: Short description of the prompt
+//	---
+func Parse(raw string) (description, content string, err error) {
+	normalized := strings.ReplaceAll(raw, "\r\n", "\n")
+	if !strings.HasPrefix(normalized, "---\n") {
+		return "", strings.TrimSpace(normalized), nil
+	}
+
+	rest := strings.TrimPrefix(normalized, "---\n")
+	end := strings.Index(rest, "\n---")
+	if end < 0 {
+		return "", "", fmt.Errorf("front matter is not terminated by ---")
+	}
+
+	for _, line := range strings.Split(rest[:end], "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return "", "", fmt.Errorf("invalid front matter line: %q", line)
+		}
+		switch strings.TrimSpace(key) {
+		case "description":
+			description = strings.TrimSpace(value)
+		default:
+			return "", "", fmt.Errorf("unknown front matter key: %q", strings.TrimSpace(key))
+		}
+	}
+
+	content = strings.TrimPrefix(rest[end+len("\n---"):], "\n")
+	return description, strings.TrimSpace(content), nil
+}
+
+// Load returns the prompt with the given name. User files take precedence over built-in prompts.
+func Load(name string) (*Prompt, error) {
+	if err := ValidateName(name); err != nil {
+		return nil, err
+	}
+
+	builtin, builtinErr := loadBuiltin(name)
+
+	path := Path(name)
+	raw, err := os.ReadFile(path)
+	if err == nil {
+		description, content, err := Parse(string(raw))
+		if err != nil {
+			return nil, fmt.Errorf("invalid prompt file %s: %w", path, err)
+		}
+		return &Prompt{
+			Name:        name,
+			Description: description,
+			Content:     content,
+			Source:      SourceUser,
+			Path:        path,
+			Overrides:   builtinErr == nil,
+		}, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read prompt file: %w", err)
+	}
+
+	if builtinErr != nil {
+		return nil, fmt.Errorf("prompt does not exist: %s (create it in %s)", name, Dir())
+	}
+	return builtin, nil
+}
+
+// Builtin returns the built-in prompt with the given name, ignoring user files
+func Builtin(name string) (*Prompt, error) {
+	if err := ValidateName(name); err != nil {
+		return nil, err
+	}
+	return loadBuiltin(name)
+}
+
+func loadBuiltin(name string) (*Prompt, error) {
+	raw, err := builtinFS.ReadFile("builtin/" + name)
+	if err != nil {
+		return nil, fmt.Errorf("no built-in prompt named %s", name)
+	}
+	description, content, err := Parse(string(raw))
+	if err != nil {
+		return nil, fmt.Errorf("invalid built-in prompt %s: %w", name, err)
+	}
+	return &Prompt{
+		Name:        name,
+		Description: description,
+		Content:     content,
+		Source:      SourceBuiltin,
+	}, nil
+}
+
+// RawBuiltin returns the unparsed content of a built-in prompt, front matter included
+func RawBuiltin(name string) (string, error) {
+	if err := ValidateName(name); err != nil {
+		return "", err
+	}
+	raw, err := builtinFS.ReadFile("builtin/" + name)
+	if err != nil {
+		return "", fmt.Errorf("no built-in prompt named %s", name)
+	}
+	return string(raw), nil
+}
+
+// List returns all built-in and user prompts sorted by name
+func List() ([]*Prompt, error) {
+	names := make(map[string]bool)
+
+	entries, err := builtinFS.ReadDir("builtin")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read built-in prompts: %w", err)
+	}
+	for _, entry := range entries {
+		names[entry.Name()] = true
+	}
+
+	entries, err = os.ReadDir(Dir())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read prompts directory: %w", err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && ValidateName(entry.Name()) == nil {
+			names[entry.Name()] = true
+		}
+	}
+
+	var list []*Prompt
+	for name := range names {
+		p, err := Load(name)
+		if err != nil {
+			// Show broken user prompts instead of hiding them
+			p = &Prompt{Name: name, Source: SourceUser, Path: Path(name), Description: "invalid: " + err.Error()}
+		}
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list, nil
+}
+
+// Validate checks a prompt file's content. Errors make the prompt unusable,
+// warnings point out missing optional information.
+func Validate(raw string) (errs, warnings []string) {
+	description, content, err := Parse(raw)
+	if err != nil {
+		return []string{err.Error()}, nil
+	}
+	if content == "" {
+		errs = append(errs, "prompt text is empty")
+	}
+	if description == "" {
+		warnings = append(warnings, "description is missing")
+	}
+	return errs, warnings
+}
+
+// Template returns the content of a new prompt file
+func Template(description string) string {
+	if description == "" {
+		description = "Describe what this prompt is for"
+	}
+	return fmt.Sprintf("---\ndescription: %s\n---\nCreate a summary of the following meeting transcript.\n", description)
+}
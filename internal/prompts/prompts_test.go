@@ -0,0 +1,137 @@
+package prompts
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func setHome(t *testing.T) string {
+	t.Helper()
+	tmpDir := t.TempDir()
+	oldHome := os.Getenv("HOME")
+	os.Setenv("HOME", tmpDir)
+	t.Cleanup(func() { os.Setenv("HOME", oldHome) })
+	return tmpDir
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name            string
+		raw             string
+		wantDescription string
+		wantContent     string
+		wantErr         bool
+	}{
+		{
+			name:        "plain prompt",
+			raw:         "Summarize this.\n",
+			wantContent: "Summarize this.",
+		},
+		{
+			name:            "front matter",
+			raw:             "---\ndescription: Short notes\n---\nSummarize this.\n",
+			wantDescription: "Short notes",
+			wantContent:     "Summarize this.",
+		},
+		{
+			name:    "unterminated front matter",
+			raw:     "---\ndescription: Short notes\nSummarize this.\n",
+			wantErr: true,
+		},
+		{
+			name:    "unknown key",
+			raw:     "---\nmodel: gpt-4o\n---\nSummarize this.\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			description, content, err := Parse(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if description != tt.wantDescription || content != tt.wantContent {
+				t.Errorf("Parse() = %q, %q, want %q, %q", description, content, tt.wantDescription, tt.wantContent)
+			}
+		})
+	}
+}
+
+func TestBuiltinPrompts(t *testing.T) {
+	setHome(t)
+
+	for _, name := range []string{"summarize", "digest", "standup", "retrospective", "interview", "customer-call", "lecture"} {
+		p, err := Load(name)
+		if err != nil {
+			t.Errorf("Load(%s) error = %v", name, err)
+			continue
+		}
+		if p.Source != SourceBuiltin || p.Description == "" || p.Content == "" {
+			t.Errorf("Load(%s) = %+v, want built-in prompt with description and content", name, p)
+		}
+		raw, _ := RawBuiltin(name)
+		if errs, warnings := Validate(raw); len(errs)+len(warnings) > 0 {
+			t.Errorf("built-in prompt %s has problems: %v %v", name, errs, warnings)
+		}
+	}
+}
+
+func TestLoadUserOverride(t *testing.T) {
+	setHome(t)
+
+	if err := os.MkdirAll(Dir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(Path("standup"), []byte("My standup prompt"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(Path("custom"), []byte("---\ndescription: Mine\n---\nCustom prompt"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := Load("standup")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if p.Source != SourceUser || !p.Overrides || p.Content != "My standup prompt" {
+		t.Errorf("expected user prompt overriding built-in, got %+v", p)
+	}
+
+	if _, err := Load("missing"); err == nil {
+		t.Error("Load() should fail for missing prompt")
+	}
+	if _, err := Load("../config"); err == nil {
+		t.Error("Load() should reject path names")
+	}
+
+	list, err := List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	found := make(map[string]*Prompt)
+	for _, p := range list {
+		found[p.Name] = p
+	}
+	if found["custom"] == nil || found["custom"].Description != "Mine" {
+		t.Errorf("expected custom prompt in list, got %+v", found["custom"])
+	}
+	if found["lecture"] == nil || found["lecture"].Source != SourceBuiltin {
+		t.Errorf("expected built-in lecture prompt in list")
+	}
+	if filepath.Dir(found["standup"].Path) != Dir() {
+		t.Errorf("expected standup prompt path in prompts directory, got %s", found["standup"].Path)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	errs, warnings := Validate("Summarize")
+	if len(errs) != 0 || len(warnings) != 1 {
+		t.Errorf("Validate() = %v, %v, want a missing description warning", errs, warnings)
+	}
+	errs, _ = Validate("---\ndescription: Empty\n---\n")
+	if len(errs) != 1 {
+		t.Errorf("Validate() = %v, want empty prompt error", errs)
+	}
+}
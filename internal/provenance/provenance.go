@@ -0,0 +1,103 @@
+package provenance
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/mnote/internal/version"
+)
+
+const (
+	headerPrefix = "<!-- mnote-provenance "
+	headerSuffix = " -->"
+)
+
+// Provenance records how a summary was created
+type Provenance struct {
+	Prompt         string    `json:"prompt"`
+	PromptHash     string    `json:"prompt_hash"`
+	Model          string    `json:"model"`
+	TranscriptHash string    `json:"transcript_hash"`
+	Version        string    `json:"version"`
+	Created        time.Time `json:"created"`
+}
+
+// New creates the provenance of a summary created now with the current mnote version
+func New(promptName, promptContent, model, transcript string) Provenance {
+	return Provenance{
+		Prompt:         promptName,
+		PromptHash:     Hash(promptContent),
+		Model:          model,
+		TranscriptHash: Hash(transcript),
+		Version:        version.Version,
+		Created:        time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+// Hash returns the hex encoded SHA-256 hash of the content
+func Hash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// Header renders the provenance as an HTML comment that markdown renderers hide
+func (p Provenance) Header() string {
+	data, _ := json.Marshal(p)
+	return headerPrefix + string(data) + headerSuffix + "\n"
+}
+
+// Add puts the provenance header in front of the summary, replacing an existing one
+func Add(summary string, p Provenance) string {
+	_, body := Parse(summary)
+	return p.Header() + body
+}
+
+// Parse splits a summary into its provenance and body. The provenance is nil if the
+// summary has no valid provenance header.
+func Parse(summary string) (*Provenance, string) {
+	if !strings.HasPrefix(summary, headerPrefix) {
+		return nil, summary
+	}
+	end := strings.Index(summary, headerSuffix)
+	if end < 0 {
+		return nil, summary
+	}
+
+	var p Provenance
+	if err := json.Unmarshal([]byte(summary[len(headerPrefix):end]), &p); err != nil {
+		return nil, summary
+	}
+	body := strings.TrimPrefix(summary[end+len(headerSuffix):], "\n")
+	return &p, body
+}
+
+// Strip removes the provenance header from a summary
+func Strip(summary string) string {
+	_, body := Parse(summary)
+	return body
+}
+
+// Changes lists the reasons why a summary with this provenance is stale compared to
+// the expected provenance. The mnote version and creation time are informational only.
+func (p *Provenance) Changes(expected Provenance) []string {
+	if p == nil {
+		return []string{"no provenance recorded"}
+	}
+	var changes []string
+	if p.Prompt != expected.Prompt {
+		changes = append(changes, fmt.Sprintf("prompt changed from %s to %s", p.Prompt, expected.Prompt))
+	} else if p.PromptHash != expected.PromptHash {
+		changes = append(changes, "prompt content changed")
+	}
+	if p.Model != expected.Model {
+		changes = append(changes, fmt.Sprintf("model changed from %s to %s", p.Model, expected.Model))
+	}
+	if p.TranscriptHash != expected.TranscriptHash {
+		changes = append(changes, "transcript changed")
+	}
+	return changes
+}
@@ -0,0 +1,59 @@
+package provenance
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAddAndParse(t *testing.T) {
+	p := New("summarize", "prompt text", "gpt-4o", "transcript text")
+	summary := Add("# Summary\n\nAll good.", p)
+
+	if !strings.HasPrefix(summary, "<!-- mnote-provenance {") {
+		t.Errorf("expected provenance header, got %q", summary)
+	}
+
+	parsed, body := Parse(summary)
+	if parsed == nil {
+		t.Fatal("Parse() returned no provenance")
+	}
+	if body != "# Summary\n\nAll good." {
+		t.Errorf("unexpected body: %q", body)
+	}
+	if parsed.PromptHash != Hash("prompt text") || parsed.Model != "gpt-4o" || parsed.Version == "" {
+		t.Errorf("unexpected provenance: %+v", parsed)
+	}
+
+	// Adding again replaces the header
+	if got := Add(summary, p); strings.Count(got, "mnote-provenance") != 1 {
+		t.Errorf("expected a single header, got %q", got)
+	}
+
+	if parsed, body := Parse("# Plain summary"); parsed != nil || body != "# Plain summary" {
+		t.Errorf("Parse() of summary without header = %v, %q", parsed, body)
+	}
+	if got := Strip(summary); got != "# Summary\n\nAll good." {
+		t.Errorf("Strip() = %q", got)
+	}
+}
+
+func TestChanges(t *testing.T) {
+	recorded := New("summarize", "prompt text", "gpt-4o", "transcript text")
+
+	expected := recorded
+	expected.Version = "other"
+	if changes := recorded.Changes(expected); len(changes) != 0 {
+		t.Errorf("expected no changes for version difference, got %v", changes)
+	}
+
+	expected = New("summarize", "better prompt", "gpt-4.1", "transcript text")
+	changes := recorded.Changes(expected)
+	if len(changes) != 2 || changes[0] != "prompt content changed" || !strings.Contains(changes[1], "gpt-4.1") {
+		t.Errorf("unexpected changes: %v", changes)
+	}
+
+	var missing *Provenance
+	if changes := missing.Changes(expected); len(changes) != 1 {
+		t.Errorf("expected missing provenance to be stale, got %v", changes)
+	}
+}
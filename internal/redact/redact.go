@@ -0,0 +1,368 @@
+package redact
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/giantswarm/mnote/internal/config"
+	"github.com/giantswarm/mnote/internal/utils"
+)
+
+// Labels of the built-in detectors
+const (
+	LabelEmail  = "EMAIL"
+	LabelPhone  = "PHONE"
+	LabelIBAN   = "IBAN"
+	LabelPerson = "PERSON"
+)
+
+// Detector finds sensitive values of one kind
+type Detector struct {
+	Label    string
+	Pattern  *regexp.Regexp
+	Validate func(match string) bool
+}
+
+// BuiltinDetectors returns the regular expression detectors that are always active
+func BuiltinDetectors() []Detector {
+	return []Detector{
+		{
+			Label:   LabelEmail,
+			Pattern: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+		},
+		{
+			Label:    LabelIBAN,
+			Pattern:  regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]{4}){2,7}(?: ?[A-Z0-9]{1,3})?\b`),
+			Validate: validIBAN,
+		},
+		{
+			Label:    LabelPhone,
+			Pattern:  regexp.MustCompile(`(?:\+|\b0)\d[\d \-/()]{6,}\d\b`),
+			Validate: validPhone,
+		},
+	}
+}
+
+// DefaultDictionaryPath returns the default location of the dictionary file
+func DefaultDictionaryPath() string {
+	return filepath.Join(os.Getenv("HOME"), ".config", "mnote", "redact", "dictionary")
+}
+
+// DefaultPatternsPath returns the default location of the custom patterns file
+func DefaultPatternsPath() string {
+	return filepath.Join(os.Getenv("HOME"), ".config", "mnote", "redact", "patterns")
+}
+
+// Redactor replaces sensitive values with placeholders
+type Redactor struct {
+	detectors []Detector
+}
+
+// NewRedactor creates a Redactor with the built-in detectors, the dictionary and the
+// custom patterns configured in cfg. Missing dictionary and pattern files are ignored.
+func NewRedactor(cfg *config.Config) (*Redactor, error) {
+	detectors := BuiltinDetectors()
+
+	dictionaryPath := cfg.RedactDictionaryFile
+	if dictionaryPath == "" {
+		dictionaryPath = DefaultDictionaryPath()
+	}
+	dictionary, err := LoadDictionary(dictionaryPath)
+	if err != nil {
+		return nil, err
+	}
+
+	patternsPath := cfg.RedactPatternsFile
+	if patternsPath == "" {
+		patternsPath = DefaultPatternsPath()
+	}
+	patterns, err := LoadPatterns(patternsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Custom patterns and dictionary terms take precedence over the built-in detectors
+	return &Redactor{detectors: append(append(patterns, dictionary...), detectors...)}, nil
+}
+
+// NewRedactorWithDetectors creates a Redactor with the given detectors only
+func NewRedactorWithDetectors(detectors []Detector) *Redactor {
+	return &Redactor{detectors: detectors}
+}
+
+// LoadDictionary reads a dictionary file with one term per line. A line of the form
+// "LABEL: term" assigns a label, other terms are labelled PERSON. Terms match case-insensitively.
+func LoadDictionary(path string) ([]Detector, error) {
+	byLabel := make(map[string][]string)
+	var labels []string
+	err := readLines(path, func(line string) error {
+		label, term := LabelPerson, line
+		if l, t, ok := strings.Cut(line, ":"); ok && isLabel(strings.TrimSpace(l)) {
+			label, term = strings.TrimSpace(l), strings.TrimSpace(t)
+		}
+		if term == "" {
+			return nil
+		}
+		if _, ok := byLabel[label]; !ok {
+			labels = append(labels, label)
+		}
+		byLabel[label] = append(byLabel[label], term)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dictionary: %w", err)
+	}
+
+	var detectors []Detector
+	for _, label := range labels {
+		terms := byLabel[label]
+		// Match longer terms first so "Alice Smith" wins over "Alice"
+		sort.SliceStable(terms, func(i, j int) bool { return len(terms[i]) > len(terms[j]) })
+		quoted := make([]string, len(terms))
+		for i, term := range terms {
+			quoted[i] = strings.ReplaceAll(regexp.QuoteMeta(term), " ", `\s+`)
+		}
+		detectors = append(detectors, Detector{
+			Label:   label,
+			Pattern: regexp.MustCompile(`(?i)\b(?:` + strings.Join(quoted, "|") + `)\b`),
+		})
+	}
+	return detectors, nil
+}
+
+// LoadPatterns reads a custom patterns file with one "LABEL regular-expression" per line
+func LoadPatterns(path string) ([]Detector, error) {
+	var detectors []Detector
+	err := readLines(path, func(line string) error {
+		label, pattern, ok := strings.Cut(line, " ")
+		pattern = strings.TrimSpace(pattern)
+		if !ok || !isLabel(label) || pattern == "" {
+			return fmt.Errorf("invalid pattern line %q (expected: LABEL regular-expression)", line)
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern for %s: %w", label, err)
+		}
+		detectors = append(detectors, Detector{Label: label, Pattern: re})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read custom patterns: %w", err)
+	}
+	return detectors, nil
+}
+
+// readLines calls fn for every non-empty line that is not a # comment. Missing files are ignored.
+func readLines(path string, fn func(line string) error) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := fn(line); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+var labelPattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+
+func isLabel(s string) bool {
+	return labelPattern.MatchString(s)
+}
+
+type match struct {
+	start, end int
+	label      string
+}
+
+// Redact replaces every detected value with a placeholder such as <PERSON_1>. Placeholders are
+// taken from the mapping so the same value always gets the same placeholder. It returns the
+// redacted text and the number of replaced values per label.
+func (r *Redactor) Redact(text string, mapping *Mapping) (string, map[string]int) {
+	var matches []match
+	for _, d := range r.detectors {
+		for _, loc := range d.Pattern.FindAllStringIndex(text, -1) {
+			value := text[loc[0]:loc[1]]
+			if d.Validate != nil && !d.Validate(value) {
+				continue
+			}
+			matches = append(matches, match{start: loc[0], end: loc[1], label: d.Label})
+		}
+	}
+
+	// Overlapping matches are merged into one placeholder covering all of them, so no part of
+	// a longer match is left in the text. It is labelled by the longest match.
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].start < matches[j].start })
+	var merged []match
+	longest := 0
+	for _, m := range matches {
+		n := len(merged) - 1
+		if n < 0 || m.start >= merged[n].end {
+			merged = append(merged, m)
+			longest = m.end - m.start
+			continue
+		}
+		if m.end-m.start > longest {
+			merged[n].label = m.label
+			longest = m.end - m.start
+		}
+		if m.end > merged[n].end {
+			merged[n].end = m.end
+		}
+	}
+
+	var b strings.Builder
+	counts := make(map[string]int)
+	last := 0
+	for _, m := range merged {
+		b.WriteString(text[last:m.start])
+		b.WriteString(mapping.Placeholder(m.label, text[m.start:m.end]))
+		counts[m.label]++
+		last = m.end
+	}
+	b.WriteString(text[last:])
+	return b.String(), counts
+}
+
+// FormatCounts renders redaction counts as "2 EMAIL, 1 PERSON"
+func FormatCounts(counts map[string]int) string {
+	labels := make([]string, 0, len(counts))
+	for label := range counts {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	parts := make([]string, len(labels))
+	for i, label := range labels {
+		parts[i] = fmt.Sprintf("%d %s", counts[label], label)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Mapping maps placeholders to the original values. It is stored locally so that
+// summaries can be de-redacted and placeholders stay stable across runs.
+type Mapping struct {
+	Values map[string]string `json:"values"` // placeholder to original value
+}
+
+// NewMapping creates an empty mapping
+func NewMapping() *Mapping {
+	return &Mapping{Values: make(map[string]string)}
+}
+
+// LoadMapping reads a mapping file, returning an empty mapping if it does not exist
+func LoadMapping(path string) (*Mapping, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return NewMapping(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read redaction mapping: %w", err)
+	}
+	m := NewMapping()
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("failed to decode redaction mapping: %w", err)
+	}
+	if m.Values == nil {
+		m.Values = make(map[string]string)
+	}
+	return m, nil
+}
+
+// Save writes the mapping readable only by the current user
+func (m *Mapping) Save(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode redaction mapping: %w", err)
+	}
+	return utils.WriteFileMode(path, data, 0600)
+}
+
+// Placeholder returns the placeholder for a value, creating a new one if needed
+func (m *Mapping) Placeholder(label, value string) string {
+	key := normalize(value)
+	next := 1
+	prefix := "<" + label + "_"
+	for placeholder, original := range m.Values {
+		if !strings.HasPrefix(placeholder, prefix) {
+			continue
+		}
+		if normalize(original) == key {
+			return placeholder
+		}
+		var n int
+		if _, err := fmt.Sscanf(strings.TrimPrefix(placeholder, prefix), "%d>", &n); err == nil && n >= next {
+			next = n + 1
+		}
+	}
+	placeholder := fmt.Sprintf("%s%d>", prefix, next)
+	m.Values[placeholder] = value
+	return placeholder
+}
+
+var placeholderPattern = regexp.MustCompile(`<[A-Z][A-Z0-9_]*_\d+>`)
+
+// Restore replaces the placeholders in text with their original values
+func (m *Mapping) Restore(text string) string {
+	return placeholderPattern.ReplaceAllStringFunc(text, func(placeholder string) string {
+		if original, ok := m.Values[placeholder]; ok {
+			return original
+		}
+		return placeholder
+	})
+}
+
+func normalize(value string) string {
+	return strings.ToLower(strings.Join(strings.Fields(value), " "))
+}
+
+// validIBAN checks the IBAN checksum
+func validIBAN(value string) bool {
+	iban := strings.ReplaceAll(value, " ", "")
+	if len(iban) < 15 || len(iban) > 34 {
+		return false
+	}
+	rearranged := iban[4:] + iban[:4]
+	var digits strings.Builder
+	for _, r := range rearranged {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r >= 'A' && r <= 'Z':
+			fmt.Fprintf(&digits, "%d", r-'A'+10)
+		default:
+			return false
+		}
+	}
+	n, ok := new(big.Int).SetString(digits.String(), 10)
+	return ok && new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+// validPhone requires enough digits to avoid matching short numbers
+func validPhone(value string) bool {
+	digits := 0
+	for _, r := range value {
+		if r >= '0' && r <= '9' {
+			digits++
+		}
+	}
+	return digits >= 8 && digits <= 15
+}
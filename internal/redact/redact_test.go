@@ -0,0 +1,143 @@
+package redact
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/giantswarm/mnote/internal/config"
+)
+
+func TestRedactBuiltin(t *testing.T) {
+	r := NewRedactorWithDetectors(BuiltinDetectors())
+	mapping := NewMapping()
+
+	text := "Mail jane.doe@example.com or jane.doe@example.com, call +49 170 1234567, " +
+		"pay to DE89 3704 0044 0532 0130 00 and not DE00 1234 5678 9012 3456 78. Meeting at 10:30, ticket 4711."
+	redacted, counts := r.Redact(text, mapping)
+
+	want := "Mail <EMAIL_1> or <EMAIL_1>, call <PHONE_1>, pay to <IBAN_1> and not DE00 1234 5678 9012 3456 78. Meeting at 10:30, ticket 4711."
+	if redacted != want {
+		t.Errorf("Redact() = %q, want %q", redacted, want)
+	}
+	if counts[LabelEmail] != 2 || counts[LabelPhone] != 1 || counts[LabelIBAN] != 1 {
+		t.Errorf("unexpected counts: %v", counts)
+	}
+	if got := FormatCounts(counts); got != "2 EMAIL, 1 IBAN, 1 PHONE" {
+		t.Errorf("FormatCounts() = %q", got)
+	}
+	if restored := mapping.Restore(redacted); restored != text {
+		t.Errorf("Restore() = %q, want original text", restored)
+	}
+}
+
+func TestRedactDictionaryAndPatterns(t *testing.T) {
+	tmpDir := t.TempDir()
+	dictionaryPath := filepath.Join(tmpDir, "dictionary")
+	patternsPath := filepath.Join(tmpDir, "patterns")
+	os.WriteFile(dictionaryPath, []byte("# people\nAlice Smith\nAlice\nORG: Acme Corp\n"), 0644)
+	os.WriteFile(patternsPath, []byte("CUSTOMER_ID CUST-\\d{6}\n"), 0644)
+
+	cfg := config.DefaultConfig()
+	cfg.RedactDictionaryFile = dictionaryPath
+	cfg.RedactPatternsFile = patternsPath
+	r, err := NewRedactor(cfg)
+	if err != nil {
+		t.Fatalf("NewRedactor() error = %v", err)
+	}
+
+	mapping := NewMapping()
+	redacted, counts := r.Redact("alice  smith from ACME corp opened CUST-123456. Alice agreed.", mapping)
+	want := "<PERSON_1> from <ORG_1> opened <CUSTOMER_ID_1>. <PERSON_2> agreed."
+	if redacted != want {
+		t.Errorf("Redact() = %q, want %q", redacted, want)
+	}
+	if counts[LabelPerson] != 2 {
+		t.Errorf("expected 2 persons, got %v", counts)
+	}
+
+	// Placeholders are stable across runs with a saved mapping
+	mappingPath := filepath.Join(tmpDir, "meeting_redaction.json")
+	if err := mapping.Save(mappingPath); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	info, _ := os.Stat(mappingPath)
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected mapping file mode 0600, got %v", info.Mode().Perm())
+	}
+	loaded, err := LoadMapping(mappingPath)
+	if err != nil {
+		t.Fatalf("LoadMapping() error = %v", err)
+	}
+	redacted, _ = r.Redact("Alice met Bob.", loaded)
+	if redacted != "<PERSON_2> met Bob." {
+		t.Errorf("expected stable placeholder, got %q", redacted)
+	}
+	if restored := loaded.Restore("Decision by <PERSON_1>, unknown <PERSON_9>"); restored != "Decision by alice  smith, unknown <PERSON_9>" {
+		t.Errorf("Restore() = %q", restored)
+	}
+}
+
+func TestRedactOverlaps(t *testing.T) {
+	dictionary := filepath.Join(t.TempDir(), "dictionary")
+	os.WriteFile(dictionary, []byte("Jane\n"), 0644)
+	names, err := LoadDictionary(dictionary)
+	if err != nil {
+		t.Fatalf("LoadDictionary() error = %v", err)
+	}
+	r := NewRedactorWithDetectors(append(names, BuiltinDetectors()...))
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{
+			name: "name in email",
+			text: "Write to jane.doe@example.com today.",
+			want: "Write to <EMAIL_1> today.",
+		},
+		{
+			name: "phone running into an iban",
+			text: "Pay to DE89 3704 1144 1532 1130 05 123 4567 now.",
+			want: "Pay to <IBAN_1> now.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapping := NewMapping()
+			redacted, counts := r.Redact(tt.text, mapping)
+			if redacted != tt.want {
+				t.Errorf("Redact() = %q, want %q", redacted, tt.want)
+			}
+			if len(counts) != 1 {
+				t.Errorf("expected one replaced value, got %v", counts)
+			}
+			if restored := mapping.Restore(redacted); restored != tt.text {
+				t.Errorf("Restore() = %q, want original text", restored)
+			}
+		})
+	}
+}
+
+func TestLoadPatternsInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "patterns")
+	for _, content := range []string{"lowercase \\d+", "NOPATTERN", "BROKEN ([a-z"} {
+		os.WriteFile(path, []byte(content), 0644)
+		if _, err := LoadPatterns(path); err == nil {
+			t.Errorf("LoadPatterns() should fail for %q", content)
+		}
+	}
+	if detectors, err := LoadPatterns(filepath.Join(t.TempDir(), "missing")); err != nil || len(detectors) != 0 {
+		t.Errorf("LoadPatterns() of missing file = %v, %v", detectors, err)
+	}
+}
+
+func TestValidIBAN(t *testing.T) {
+	if !validIBAN("GB82 WEST 1234 5698 7654 32") {
+		t.Error("expected valid IBAN")
+	}
+	if validIBAN("GB00 WEST 1234 5698 7654 32") {
+		t.Error("expected invalid IBAN checksum")
+	}
+}
@@ -0,0 +1,184 @@
+package state
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	filelock "github.com/giantswarm/mnote/internal/lock"
+	"github.com/giantswarm/mnote/internal/utils"
+)
+
+// Dir is the directory next to the videos where mnote keeps its state
+const Dir = ".mnote"
+
+// FileName is the name of the manifest in the state directory
+const FileName = "state.json"
+
+// File and stage states
+const (
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusSkipped   = "skipped"
+	StatusFailed    = "failed"
+)
+
+// Manifest records the pipeline state of every video in a directory, by file name
+type Manifest struct {
+	Files map[string]*FileState `json:"files"`
+}
+
+// FileState is the state of one video
+type FileState struct {
+	SourceHash    string                 `json:"source_hash,omitempty"`
+	SourceSize    int64                  `json:"source_size"`
+	SourceModTime time.Time              `json:"source_mod_time"`
+	Status        string                 `json:"status"`
+	StartedAt     time.Time              `json:"started_at"`
+	FinishedAt    time.Time              `json:"finished_at"`
+	Error         string                 `json:"error,omitempty"`
+	Stages        map[string]*StageState `json:"stages,omitempty"`
+}
+
+// StageState is the state of one pipeline stage of a video
+type StageState struct {
+	Status     string    `json:"status"`
+	StartedAt  time.Time `json:"started_at"`
+	DurationMS int64     `json:"duration_ms"`
+	Model      string    `json:"model,omitempty"`
+	Output     string    `json:"output,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Duration returns how long the stage took
+func (s *StageState) Duration() time.Duration {
+	return time.Duration(s.DurationMS) * time.Millisecond
+}
+
+// Path returns the path of the manifest of a directory
+func Path(dir string) string {
+	return filepath.Join(dir, Dir, FileName)
+}
+
+// Load reads the manifest of a directory, an empty manifest is returned if there is none
+func Load(dir string) (*Manifest, error) {
+	m := &Manifest{Files: map[string]*FileState{}}
+	data, err := os.ReadFile(Path(dir))
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state: %w", err)
+	}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("failed to parse state %s: %w", Path(dir), err)
+	}
+	if m.Files == nil {
+		m.Files = map[string]*FileState{}
+	}
+	return m, nil
+}
+
+// locks serializes updates of the same manifest within this process
+var (
+	locksMu sync.Mutex
+	locks   = map[string]*sync.Mutex{}
+)
+
+func lock(dir string) func() {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		abs = dir
+	}
+	locksMu.Lock()
+	l, ok := locks[abs]
+	if !ok {
+		l = &sync.Mutex{}
+		locks[abs] = l
+	}
+	locksMu.Unlock()
+	l.Lock()
+	return l.Unlock
+}
+
+// Update loads the manifest of a directory, applies fn and saves it atomically. The
+// manifest is locked while it is updated, so mnote processes sharing a directory do not
+// lose each other's updates.
+func Update(dir string, fn func(m *Manifest) error) error {
+	unlock := lock(dir)
+	defer unlock()
+
+	return filelock.With(Path(dir)+".lock", func() error {
+		return update(dir, fn)
+	})
+}
+
+func update(dir string, fn func(m *Manifest) error) error {
+	m, err := Load(dir)
+	if err != nil {
+		return err
+	}
+	if err := fn(m); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+	if err := utils.WriteFile(Path(dir), data); err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+	return nil
+}
+
+// Failed returns the paths of the videos in dir whose last run failed, sorted
+func Failed(dir string) ([]string, error) {
+	m, err := Load(dir)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for name, f := range m.Files {
+		if f.Status == StatusFailed {
+			paths = append(paths, filepath.Join(dir, name))
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// Names returns the file names of the manifest, sorted
+func (m *Manifest) Names() []string {
+	names := make([]string, 0, len(m.Files))
+	for name := range m.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Matches reports whether the recorded source has the size and modification time of info
+func (f *FileState) Matches(info os.FileInfo) bool {
+	return f.SourceSize == info.Size() && f.SourceModTime.Equal(info.ModTime())
+}
+
+// HashFile returns the hex encoded SHA-256 of a file
+func HashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
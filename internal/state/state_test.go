@@ -0,0 +1,114 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	filelock "github.com/giantswarm/mnote/internal/lock"
+)
+
+func TestUpdate(t *testing.T) {
+	dir := t.TempDir()
+
+	m, err := Load(dir)
+	if err != nil || len(m.Files) != 0 {
+		t.Fatalf("expected empty manifest, got %v, %v", m, err)
+	}
+
+	// Concurrent updates of the same manifest are serialized
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			status := StatusSucceeded
+			if i%2 == 0 {
+				status = StatusFailed
+			}
+			err := Update(dir, func(m *Manifest) error {
+				m.Files[fmt.Sprintf("video%02d.mp4", i)] = &FileState{Status: status}
+				return nil
+			})
+			if err != nil {
+				t.Errorf("Update() error = %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	m, err = Load(dir)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(m.Files) != 20 || m.Names()[0] != "video00.mp4" {
+		t.Errorf("expected 20 files, got %v", m.Names())
+	}
+	failed, err := Failed(dir)
+	if err != nil || len(failed) != 10 || failed[0] != filepath.Join(dir, "video00.mp4") {
+		t.Errorf("expected 10 failed videos, got %v, %v", failed, err)
+	}
+
+	// No temporary files are left behind
+	entries, _ := os.ReadDir(filepath.Join(dir, Dir))
+	if len(entries) != 1 {
+		t.Errorf("expected only the manifest in the state directory, got %d entries", len(entries))
+	}
+
+	// An error from the update function leaves the manifest unchanged
+	if err := Update(dir, func(m *Manifest) error {
+		m.Files = nil
+		return fmt.Errorf("abort")
+	}); err == nil {
+		t.Error("expected error from update function")
+	}
+	if m, _ := Load(dir); len(m.Files) != 20 {
+		t.Errorf("expected manifest to be unchanged, got %d files", len(m.Files))
+	}
+}
+
+func TestUpdateLocked(t *testing.T) {
+	dir := t.TempDir()
+
+	// Another process holds the lock of the manifest
+	held, err := filelock.Acquire(Path(dir)+".lock", time.Minute)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	done := make(chan error)
+	go func() {
+		done <- Update(dir, func(m *Manifest) error {
+			m.Files["video.mp4"] = &FileState{Status: StatusSucceeded}
+			return nil
+		})
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("expected Update() to wait for the lock, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	held.Release()
+	if err := <-done; err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if m, _ := Load(dir); m.Files["video.mp4"] == nil {
+		t.Error("expected the update to be saved")
+	}
+}
+
+func TestHashFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "video.mp4")
+	os.WriteFile(path, []byte("abc"), 0644)
+
+	hash, err := HashFile(path)
+	if err != nil {
+		t.Fatalf("HashFile() error = %v", err)
+	}
+	if hash != "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad" {
+		t.Errorf("unexpected hash %s", hash)
+	}
+}
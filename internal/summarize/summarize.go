@@ -2,14 +2,29 @@ package summarize
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
-	"path/filepath"
+	"strings"
 
 	"github.com/giantswarm/mnote/internal/config"
+	"github.com/giantswarm/mnote/internal/prompts"
+	"github.com/giantswarm/mnote/internal/transcript"
 	"github.com/sashabaranov/go-openai"
 )
 
+// citationInstruction is added to the prompt for transcripts with timestamps
+const citationInstruction = `Each line of the transcript starts with a timestamp in the format [hh:mm:ss].
+Support every point of the summary with the timestamps of the transcript lines it is based on, in the same format, for example [00:12:34].
+Place the references at the end of the point they support and cite several timestamps as [00:12:34, 00:15:02].`
+
+// combineInstruction is added to the prompt when the summaries of the parts of a transcript
+// with timestamps are combined
+const combineInstruction = `The text consists of the summaries of consecutive parts of one transcript, each point ends with timestamp references in the format [hh:mm:ss].
+Keep the references of every point you take over, in the same format, and when you merge points cite the timestamps of all of them, for example [00:12:34, 00:15:02].
+Do not invent timestamps that are not in the summaries.`
+
 // Summarizer interface defines the contract for transcript summarization
 type Summarizer interface {
 	SummarizeTranscript(transcript, promptName string, forceRebuild bool) (string, error)
@@ -28,6 +43,18 @@ type OpenAIClient interface {
 
 // NewSummarizer creates a new Summarizer instance
 func NewSummarizer(cfg *config.Config) (Summarizer, error) {
+	client, err := NewOpenAIClient()
+	if err != nil {
+		return nil, err
+	}
+	return &SummarizerImpl{
+		client: client,
+		config: cfg,
+	}, nil
+}
+
+// NewOpenAIClient creates a chat client using the OPENAI_API_KEY environment variable
+func NewOpenAIClient() (OpenAIClient, error) {
 	apiKey := os.Getenv("OPENAI_API_KEY")
 	if apiKey == "" {
 		return nil, fmt.Errorf("OPENAI_API_KEY environment variable not set")
@@ -35,43 +62,74 @@ func NewSummarizer(cfg *config.Config) (Summarizer, error) {
 
 	// Use mock client in test environment
 	if os.Getenv("TEST_ENV") == "true" {
-		return &SummarizerImpl{client: &MockOpenAIClient{}, config: cfg}, nil
+		return &MockOpenAIClient{}, nil
 	}
 
-	client := openai.NewClient(apiKey)
-	return &SummarizerImpl{
-		client: client,
-		config: cfg,
-	}, nil
+	return openai.NewClient(apiKey), nil
 }
 
 // SummarizeTranscript generates a summary of the transcript using the specified prompt
-func (s *SummarizerImpl) SummarizeTranscript(transcript, promptName string, forceRebuild bool) (string, error) {
-	// Read prompt file
-	promptDir := filepath.Join(os.Getenv("HOME"), ".config", "mnote", "prompts")
-	promptFile := filepath.Join(promptDir, promptName)
-	promptContent, err := os.ReadFile(promptFile)
+func (s *SummarizerImpl) SummarizeTranscript(transcriptText, promptName string, forceRebuild bool) (string, error) {
+	return s.SummarizeTranscriptProgress(transcriptText, promptName, forceRebuild, nil)
+}
+
+// SummarizeTranscriptProgress generates a summary like SummarizeTranscript. If tokens is set
+// and the client can stream, the summary is streamed and tokens is called with the number
+// of tokens received so far.
+func (s *SummarizerImpl) SummarizeTranscriptProgress(transcriptText, promptName string, forceRebuild bool, tokens func(n int)) (string, error) {
+	// Load prompt, user files override built-in prompts
+	prompt, err := prompts.Load(promptName)
 	if err != nil {
-		return "", fmt.Errorf("failed to read prompt file: %w", err)
+		return "", fmt.Errorf("failed to load prompt: %w", err)
 	}
 
-	// Create chat completion request
-	resp, err := s.client.CreateChatCompletion(
-		context.Background(),
-		openai.ChatCompletionRequest{
-			Model: s.config.ChatGPTModel,
-			Messages: []openai.ChatCompletionMessage{
-				{
-					Role:    openai.ChatMessageRoleSystem,
-					Content: string(promptContent),
-				},
-				{
-					Role:    openai.ChatMessageRoleUser,
-					Content: transcript,
-				},
+	return s.complete(SystemPrompt(prompt.Content, transcriptText), transcriptText, tokens)
+}
+
+// SystemPrompt returns the prompt sent with a transcript, the prompt content with the
+// instruction to cite timestamps if the transcript has them
+func SystemPrompt(promptContent, transcriptText string) string {
+	if transcript.HasTimestamps(transcriptText) {
+		return promptContent + "\n\n" + citationInstruction
+	}
+	return promptContent
+}
+
+// CombineSummaries summarizes the summaries of the parts of a long transcript into one
+// summary with the prompt. With citations, the timestamp references of the parts are kept.
+func (s *SummarizerImpl) CombineSummaries(summaries []string, promptName string, citations bool) (string, error) {
+	prompt, err := prompts.Load(promptName)
+	if err != nil {
+		return "", fmt.Errorf("failed to load prompt: %w", err)
+	}
+	systemPrompt := prompt.Content
+	if citations {
+		systemPrompt += "\n\n" + combineInstruction
+	}
+	return s.complete(systemPrompt, strings.Join(summaries, "\n\n"), nil)
+}
+
+// complete sends text with the system prompt and returns the answer
+func (s *SummarizerImpl) complete(systemPrompt, text string, tokens func(n int)) (string, error) {
+	req := openai.ChatCompletionRequest{
+		Model: s.config.ChatGPTModel,
+		Messages: []openai.ChatCompletionMessage{
+			{
+				Role:    openai.ChatMessageRoleSystem,
+				Content: systemPrompt,
+			},
+			{
+				Role:    openai.ChatMessageRoleUser,
+				Content: text,
 			},
 		},
-	)
+	}
+
+	if streamer, ok := s.client.(StreamingClient); ok && tokens != nil {
+		return streamSummary(streamer, req, tokens)
+	}
+
+	resp, err := s.client.CreateChatCompletion(context.Background(), req)
 	if err != nil {
 		return "", fmt.Errorf("failed to create chat completion: %w", err)
 	}
@@ -83,6 +141,44 @@ func (s *SummarizerImpl) SummarizeTranscript(transcript, promptName string, forc
 	return resp.Choices[0].Message.Content, nil
 }
 
+// StreamingClient is implemented by clients that can stream chat completions
+type StreamingClient interface {
+	CreateChatCompletionStream(context.Context, openai.ChatCompletionRequest) (*openai.ChatCompletionStream, error)
+}
+
+// streamSummary streams a chat completion, every received delta counts as a token
+func streamSummary(client StreamingClient, req openai.ChatCompletionRequest, tokens func(n int)) (string, error) {
+	req.Stream = true
+	stream, err := client.CreateChatCompletionStream(context.Background(), req)
+	if err != nil {
+		return "", fmt.Errorf("failed to create chat completion: %w", err)
+	}
+	defer stream.Close()
+
+	var summary strings.Builder
+	var n int
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to receive chat completion: %w", err)
+		}
+		if len(resp.Choices) == 0 || resp.Choices[0].Delta.Content == "" {
+			continue
+		}
+		summary.WriteString(resp.Choices[0].Delta.Content)
+		n++
+		tokens(n)
+	}
+
+	if summary.Len() == 0 {
+		return "", fmt.Errorf("no response choices returned from API")
+	}
+	return summary.String(), nil
+}
+
 // MockOpenAIClient implements OpenAIClient for testing
 type MockOpenAIClient struct{}
 
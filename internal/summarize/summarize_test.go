@@ -1,11 +1,17 @@
 package summarize
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/giantswarm/mnote/internal/config"
+	"github.com/sashabaranov/go-openai"
 )
 
 func TestNewSummarizer(t *testing.T) {
@@ -86,3 +92,85 @@ func TestSummarizeTranscript(t *testing.T) {
 		t.Error("SummarizeTranscript() returned empty summary")
 	}
 }
+
+func TestSummarizeTranscriptProgress(t *testing.T) {
+	tmpDir := t.TempDir()
+	configDir := filepath.Join(tmpDir, ".config", "mnote", "prompts")
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		t.Fatalf("failed to create config directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(configDir, "test_prompt"), []byte("Summarize."), 0644); err != nil {
+		t.Fatalf("failed to create prompt file: %v", err)
+	}
+	oldHome := os.Getenv("HOME")
+	os.Setenv("HOME", tmpDir)
+	defer os.Setenv("HOME", oldHome)
+
+	// Stream the summary as server-sent events
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, token := range []string{"Short", " test", " summary."} {
+			fmt.Fprintf(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":%q}}]}\n\n", token)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	clientConfig := openai.DefaultConfig("test-key")
+	clientConfig.BaseURL = server.URL
+	summarizer := &SummarizerImpl{client: openai.NewClientWithConfig(clientConfig), config: config.DefaultConfig()}
+
+	var tokens int
+	summary, err := summarizer.SummarizeTranscriptProgress("A test transcript.", "test_prompt", false, func(n int) {
+		tokens = n
+	})
+	if err != nil {
+		t.Fatalf("SummarizeTranscriptProgress() error = %v", err)
+	}
+	if summary != "Short test summary." {
+		t.Errorf("unexpected summary %q", summary)
+	}
+	if tokens != 3 {
+		t.Errorf("expected 3 tokens to be reported, got %d", tokens)
+	}
+}
+
+// recordingClient remembers the requests it receives
+type recordingClient struct {
+	requests []openai.ChatCompletionRequest
+}
+
+func (c *recordingClient) CreateChatCompletion(_ context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	c.requests = append(c.requests, req)
+	return openai.ChatCompletionResponse{Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Content: "combined"}}}}, nil
+}
+
+func TestCombineSummaries(t *testing.T) {
+	tmpDir := t.TempDir()
+	configDir := filepath.Join(tmpDir, ".config", "mnote", "prompts")
+	os.MkdirAll(configDir, 0755)
+	os.WriteFile(filepath.Join(configDir, "test_prompt"), []byte("Summarize."), 0644)
+	oldHome := os.Getenv("HOME")
+	os.Setenv("HOME", tmpDir)
+	defer os.Setenv("HOME", oldHome)
+
+	client := &recordingClient{}
+	summarizer := &SummarizerImpl{client: client, config: config.DefaultConfig()}
+	if _, err := summarizer.CombineSummaries([]string{"- A [00:00:01]", "- B [00:10:00]"}, "test_prompt", true); err != nil {
+		t.Fatalf("CombineSummaries() error = %v", err)
+	}
+	if _, err := summarizer.CombineSummaries([]string{"- A", "- B"}, "test_prompt", false); err != nil {
+		t.Fatalf("CombineSummaries() error = %v", err)
+	}
+
+	// References are kept when the parts have them
+	if got := client.requests[0].Messages[0].Content; !strings.HasPrefix(got, "Summarize.") || !strings.Contains(got, combineInstruction) {
+		t.Errorf("expected the combine instruction in the prompt, got %q", got)
+	}
+	if got := client.requests[0].Messages[1].Content; got != "- A [00:00:01]\n\n- B [00:10:00]" {
+		t.Errorf("unexpected combined input %q", got)
+	}
+	if got := client.requests[1].Messages[0].Content; got != "Summarize." {
+		t.Errorf("expected the plain prompt without citations, got %q", got)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"mime/multipart"
 	"net/http"
 	"os"
@@ -40,11 +41,25 @@ func NewTranscriber(cfg *config.Config) Transcriber {
 
 // TranscriptionResult represents the JSON response from the API
 type TranscriptionResult struct {
-	Text string `json:"text"`
+	Text     string    `json:"text"`
+	Segments []Segment `json:"segments,omitempty"`
+}
+
+// Segment is a timestamped part of a transcription, offsets are in seconds
+type Segment struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Text  string  `json:"text"`
 }
 
 // TranscribeAudio transcribes the audio file at the given path
 func (t *TranscriberImpl) TranscribeAudio(audioPath, language string) (*TranscriptionResult, error) {
+	return t.TranscribeAudioProgress(audioPath, language, nil)
+}
+
+// TranscribeAudioProgress transcribes the audio file like TranscribeAudio and calls uploaded
+// with the bytes of the request sent so far and its size while the audio is uploaded
+func (t *TranscriberImpl) TranscribeAudioProgress(audioPath, language string, uploaded func(sent, total int64)) (*TranscriptionResult, error) {
 	// Open the audio file
 	file, err := os.Open(audioPath)
 	if err != nil {
@@ -74,11 +89,16 @@ func (t *TranscriberImpl) TranscribeAudio(audioPath, language string) (*Transcri
 
 	// Add model parameter after language
 	model := t.config.GetWhisperModel(language)
-	fmt.Printf("Transcribing using model: %s (language: %s)\n", model, language)
+	slog.Debug("Sending audio for transcription", "path", audioPath, "model", model, "language", language)
 	if err := writer.WriteField("model", model); err != nil {
 		return nil, fmt.Errorf("failed to add model field: %w", err)
 	}
 
+	// Request segment timestamps along with the text
+	if err := writer.WriteField("response_format", "verbose_json"); err != nil {
+		return nil, fmt.Errorf("failed to add response format field: %w", err)
+	}
+
 	// Close multipart writer
 	if err := writer.Close(); err != nil {
 		return nil, fmt.Errorf("failed to close writer: %w", err)
@@ -91,10 +111,16 @@ func (t *TranscriberImpl) TranscribeAudio(audioPath, language string) (*Transcri
 	}
 
 	// Create request
-	req, err := http.NewRequest("POST", apiURL, &buf)
+	var body io.Reader = &buf
+	size := int64(buf.Len())
+	if uploaded != nil {
+		body = &countingReader{r: &buf, total: size, report: uploaded}
+	}
+	req, err := http.NewRequest("POST", apiURL, body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
+	req.ContentLength = size
 	req.Header.Set("Content-Type", writer.FormDataContentType())
 
 	// Send request
@@ -119,6 +145,23 @@ func (t *TranscriberImpl) TranscribeAudio(audioPath, language string) (*Transcri
 	return &result, nil
 }
 
+// countingReader reports the bytes read from a request body
+type countingReader struct {
+	r      io.Reader
+	sent   int64
+	total  int64
+	report func(sent, total int64)
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if n > 0 {
+		c.sent += int64(n)
+		c.report(c.sent, c.total)
+	}
+	return n, err
+}
+
 // MockHTTPClient implements HTTPClient for testing
 type MockHTTPClient struct{}
 
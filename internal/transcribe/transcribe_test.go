@@ -95,3 +95,67 @@ func TestTranscribeAudio(t *testing.T) {
 		})
 	}
 }
+
+func TestTranscribeAudioSegments(t *testing.T) {
+	cfg := config.DefaultConfig()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(10 << 20); err != nil {
+			t.Fatalf("failed to parse multipart form: %v", err)
+		}
+		if format := r.FormValue("response_format"); format != "verbose_json" {
+			t.Errorf("expected verbose_json response format, got %q", format)
+		}
+		w.Write([]byte(`{"text": "Hello world.", "segments": [
+			{"start": 0.0, "end": 1.5, "text": "Hello"},
+			{"start": 1.5, "end": 2.0, "text": " world."}
+		]}`))
+	}))
+	defer server.Close()
+	cfg.TranscriptionAPIURL = server.URL
+
+	audioPath := filepath.Join(t.TempDir(), "test.mp3")
+	if err := os.WriteFile(audioPath, []byte("test audio data"), 0644); err != nil {
+		t.Fatalf("failed to create test audio file: %v", err)
+	}
+
+	result, err := NewTranscriber(cfg).TranscribeAudio(audioPath, "en")
+	if err != nil {
+		t.Fatalf("TranscribeAudio() error = %v", err)
+	}
+	if len(result.Segments) != 2 {
+		t.Fatalf("expected 2 segments, got %d", len(result.Segments))
+	}
+	if result.Segments[1].Start != 1.5 || result.Segments[1].Text != " world." {
+		t.Errorf("unexpected second segment: %+v", result.Segments[1])
+	}
+}
+
+func TestTranscribeAudioProgress(t *testing.T) {
+	cfg := config.DefaultConfig()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength <= 0 {
+			t.Errorf("expected the request size to be set, got %d", r.ContentLength)
+		}
+		json.NewEncoder(w).Encode(TranscriptionResult{Text: "Test transcription"})
+	}))
+	defer server.Close()
+	cfg.TranscriptionAPIURL = server.URL
+
+	audioPath := filepath.Join(t.TempDir(), "test.mp3")
+	if err := os.WriteFile(audioPath, []byte("test audio data"), 0644); err != nil {
+		t.Fatalf("failed to create test audio file: %v", err)
+	}
+
+	var sent, total int64
+	_, err := NewTranscriber(cfg).(*TranscriberImpl).TranscribeAudioProgress(audioPath, "en", func(s, t int64) {
+		sent, total = s, t
+	})
+	if err != nil {
+		t.Fatalf("TranscribeAudioProgress() error = %v", err)
+	}
+	if total == 0 || sent != total {
+		t.Errorf("expected the whole request to be reported as sent, got %d of %d", sent, total)
+	}
+}
@@ -0,0 +1,136 @@
+package transcript
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/giantswarm/mnote/internal/transcribe"
+)
+
+var (
+	// timestampLinePattern matches the timestamp at the start of a timestamped transcript line
+	timestampLinePattern = regexp.MustCompile(`(?m)^\[\d{2}:\d{2}:\d{2}\] `)
+	// citationPattern matches one or more timestamps in brackets, optionally followed by a link target
+	citationPattern = regexp.MustCompile(`\[((?:\d{1,2}:)?\d{1,2}:\d{2}(?:\s*[,;]\s*(?:\d{1,2}:)?\d{1,2}:\d{2})*)\](\()?`)
+	// citationSeparator separates several timestamps within one reference
+	citationSeparator = regexp.MustCompile(`\s*[,;]\s*`)
+	// anchorPattern matches the anchors written by FormatMarkdown
+	anchorPattern = regexp.MustCompile(`<a id="t-\d{2}-\d{2}-\d{2}"></a>`)
+)
+
+// Anchor returns the anchor id of a transcript line starting at the given offset
+func Anchor(seconds float64) string {
+	return "t-" + strings.ReplaceAll(FormatTimestamp(seconds), ":", "-")
+}
+
+// FormatMarkdown renders segments as a markdown transcript with one anchored paragraph per segment
+func FormatMarkdown(segments []transcribe.Segment) string {
+	var b strings.Builder
+	for _, seg := range segments {
+		text := strings.TrimSpace(seg.Text)
+		if text == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "<a id=\"%s\"></a>[%s] %s\n\n", Anchor(seg.Start), FormatTimestamp(seg.Start), text)
+	}
+	return b.String()
+}
+
+// FormatTimestamped renders segments as plain text lines prefixed with their timestamp
+func FormatTimestamped(segments []transcribe.Segment) string {
+	var b strings.Builder
+	for _, seg := range segments {
+		text := strings.TrimSpace(seg.Text)
+		if text == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "[%s] %s\n", FormatTimestamp(seg.Start), text)
+	}
+	return b.String()
+}
+
+// StripAnchors removes the anchors of a markdown transcript, leaving timestamped lines
+func StripAnchors(text string) string {
+	return anchorPattern.ReplaceAllString(text, "")
+}
+
+// HasTimestamps checks if the text contains timestamped transcript lines
+func HasTimestamps(text string) bool {
+	return timestampLinePattern.MatchString(text)
+}
+
+// ParseTimestamp parses hh:mm:ss or mm:ss into seconds
+func ParseTimestamp(ts string) (float64, bool) {
+	parts := strings.Split(strings.TrimSpace(ts), ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, false
+	}
+	seconds := 0
+	for _, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return 0, false
+		}
+		seconds = seconds*60 + n
+	}
+	return float64(seconds), true
+}
+
+// LinkCitations turns [hh:mm:ss] references in a summary into links to the transcript anchors.
+// Each reference links to the segment starting at or before the cited time, so slightly rounded
+// timestamps still resolve. If mediaLink is set, a link to the media fragment is added as well.
+// References that are already links are left unchanged.
+func LinkCitations(summary string, starts []float64, transcriptLink, mediaLink string) string {
+	sorted := append([]float64(nil), starts...)
+	sort.Float64s(sorted)
+
+	return replaceAllSubmatch(citationPattern, summary, func(match string, groups []string) string {
+		if groups[1] != "" {
+			return match
+		}
+
+		var links []string
+		for _, ts := range citationSeparator.Split(groups[0], -1) {
+			seconds, ok := ParseTimestamp(ts)
+			if !ok {
+				return match
+			}
+			link := fmt.Sprintf("[%s](%s#%s)", FormatTimestamp(seconds), transcriptLink, Anchor(snap(sorted, seconds)))
+			if mediaLink != "" {
+				link += fmt.Sprintf(" ([▶](%s#t=%d))", mediaLink, int(seconds))
+			}
+			links = append(links, link)
+		}
+		return strings.Join(links, ", ")
+	})
+}
+
+// snap returns the latest start at or before the given offset, or the offset itself if there is none
+func snap(sorted []float64, seconds float64) float64 {
+	i := sort.Search(len(sorted), func(i int) bool { return sorted[i] > seconds })
+	if i == 0 {
+		return seconds
+	}
+	return sorted[i-1]
+}
+
+func replaceAllSubmatch(re *regexp.Regexp, s string, repl func(match string, groups []string) string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range re.FindAllStringSubmatchIndex(s, -1) {
+		b.WriteString(s[last:loc[0]])
+		groups := make([]string, len(loc)/2-1)
+		for g := range groups {
+			if loc[2*g+2] >= 0 {
+				groups[g] = s[loc[2*g+2]:loc[2*g+3]]
+			}
+		}
+		b.WriteString(repl(s[loc[0]:loc[1]], groups))
+		last = loc[1]
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
@@ -0,0 +1,72 @@
+package transcript
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/giantswarm/mnote/internal/transcribe"
+)
+
+func TestFormatMarkdown(t *testing.T) {
+	segments := []transcribe.Segment{
+		{Start: 0, Text: " Hello."},
+		{Start: 754.4, Text: " We ship on Friday."},
+		{Start: 760, Text: "  "},
+	}
+
+	md := FormatMarkdown(segments)
+	want := "<a id=\"t-00-00-00\"></a>[00:00:00] Hello.\n\n<a id=\"t-00-12-34\"></a>[00:12:34] We ship on Friday.\n\n"
+	if md != want {
+		t.Errorf("FormatMarkdown() = %q, want %q", md, want)
+	}
+	if !HasTimestamps(StripAnchors(md)) {
+		t.Error("expected stripped markdown to keep timestamps")
+	}
+	if got := FormatTimestamped(segments); got != "[00:00:00] Hello.\n[00:12:34] We ship on Friday.\n" {
+		t.Errorf("FormatTimestamped() = %q", got)
+	}
+	if HasTimestamps("No timestamps [here]") {
+		t.Error("HasTimestamps() should be false for plain text")
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		ts   string
+		want float64
+		ok   bool
+	}{
+		{"00:12:34", 754, true},
+		{"12:34", 754, true},
+		{"1:02:03", 3723, true},
+		{"12", 0, false},
+		{"aa:bb", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseTimestamp(tt.ts)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseTimestamp(%s) = %v, %v, want %v, %v", tt.ts, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestLinkCitations(t *testing.T) {
+	starts := []float64{0, 750, 800}
+	summary := "- Ship on Friday [00:12:34]\n- Greeting [00:00:00, 13:20]\n- Linked [00:00:00](other.md#x)"
+
+	got := LinkCitations(summary, starts, "meeting_transcript.md", "")
+	for _, want := range []string{
+		"Ship on Friday [00:12:34](meeting_transcript.md#t-00-12-30)",
+		"Greeting [00:00:00](meeting_transcript.md#t-00-00-00), [00:13:20](meeting_transcript.md#t-00-13-20)",
+		"Linked [00:00:00](other.md#x)",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("LinkCitations() = %q, want it to contain %q", got, want)
+		}
+	}
+
+	got = LinkCitations("Decision [00:12:34]", starts, "meeting_transcript.md", "meeting.mp4")
+	if want := "Decision [00:12:34](meeting_transcript.md#t-00-12-30) ([▶](meeting.mp4#t=754))"; got != want {
+		t.Errorf("LinkCitations() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,312 @@
+package transcript
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/giantswarm/mnote/internal/layout"
+	"github.com/giantswarm/mnote/internal/transcribe"
+	"github.com/giantswarm/mnote/internal/utils"
+)
+
+// Suffix is the file name suffix of transcripts written by mnote
+const Suffix = "_transcript.md"
+
+// DefaultPassageWords is the default number of words per passage
+const DefaultPassageWords = 150
+
+// Passage is a part of a transcript that can be cited on its own
+type Passage struct {
+	File  string
+	Start float64 // Start offset in seconds, negative if unknown
+	End   float64
+	Text  string
+}
+
+// Timestamp returns the formatted start time of the passage, or an empty string if unknown
+func (p Passage) Timestamp() string {
+	if p.Start < 0 {
+		return ""
+	}
+	return FormatTimestamp(p.Start)
+}
+
+// FormatTimestamp formats an offset in seconds as hh:mm:ss
+func FormatTimestamp(seconds float64) string {
+	total := int(seconds)
+	return fmt.Sprintf("%02d:%02d:%02d", total/3600, total%3600/60, total%60)
+}
+
+// IsTranscriptFile checks if the given path looks like a transcript written by mnote
+func IsTranscriptFile(path string) bool {
+	return strings.HasSuffix(path, Suffix)
+}
+
+// SegmentsPath returns the path of the segments file belonging to a transcript
+func SegmentsPath(transcriptPath string) string {
+	if IsTranscriptFile(transcriptPath) {
+		return strings.TrimSuffix(transcriptPath, Suffix) + "_segments.json"
+	}
+	return strings.TrimSuffix(transcriptPath, filepath.Ext(transcriptPath)) + "_segments.json"
+}
+
+// SaveSegments writes transcription segments as JSON
+func SaveSegments(path string, segments []transcribe.Segment) error {
+	data, err := json.MarshalIndent(segments, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode segments: %w", err)
+	}
+	return utils.WriteFile(path, data)
+}
+
+// LoadSegments reads transcription segments written by SaveSegments
+func LoadSegments(path string) ([]transcribe.Segment, error) {
+	data, err := utils.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var segments []transcribe.Segment
+	if err := json.Unmarshal(data, &segments); err != nil {
+		return nil, fmt.Errorf("failed to decode segments: %w", err)
+	}
+	return segments, nil
+}
+
+// currentSegments loads the segments file of a transcript. Segments older than the
+// transcript are ignored, the transcript was edited after it was written.
+func currentSegments(transcriptPath string) ([]transcribe.Segment, error) {
+	segmentsPath := SegmentsPath(transcriptPath)
+	segmentsInfo, err := os.Stat(segmentsPath)
+	if err != nil {
+		return nil, nil
+	}
+	transcriptInfo, err := os.Stat(transcriptPath)
+	if err != nil || segmentsInfo.ModTime().Before(transcriptInfo.ModTime()) {
+		return nil, nil
+	}
+	return LoadSegments(segmentsPath)
+}
+
+// LoadPassages splits a transcript into passages of roughly maxWords words.
+// Timestamps are taken from the segments file if one exists next to the transcript and
+// the transcript was not edited since.
+func LoadPassages(transcriptPath string, maxWords int) ([]Passage, error) {
+	if maxWords <= 0 {
+		maxWords = DefaultPassageWords
+	}
+
+	segments, err := currentSegments(transcriptPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load segments for %s: %w", transcriptPath, err)
+	}
+	if len(segments) > 0 {
+		return passagesFromSegments(transcriptPath, segments, maxWords), nil
+	}
+
+	data, err := utils.ReadFile(transcriptPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcript: %w", err)
+	}
+
+	// Recover timestamps from a timestamped transcript without segments file
+	text := StripAnchors(string(data))
+	if segments := ParseTimestamped(text); len(segments) > 0 {
+		return passagesFromSegments(transcriptPath, segments, maxWords), nil
+	}
+	return passagesFromText(transcriptPath, text, maxWords), nil
+}
+
+// ReadTimestamped reads a transcript for summarization. If timestamps are known, the text has
+// one [hh:mm:ss] prefixed line per segment and the segment start offsets are returned as well.
+// The segments file is only used while the transcript was not edited, edits are read from
+// the timestamped lines of the transcript.
+func ReadTimestamped(transcriptPath string) (string, []float64, error) {
+	segments, err := currentSegments(transcriptPath)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to load segments: %w", err)
+	}
+
+	if len(segments) == 0 {
+		data, err := utils.ReadFile(transcriptPath)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to read transcript: %w", err)
+		}
+		text := StripAnchors(string(data))
+		segments = ParseTimestamped(text)
+		if len(segments) == 0 {
+			return text, nil, nil
+		}
+	}
+
+	starts := make([]float64, len(segments))
+	for i, seg := range segments {
+		starts[i] = seg.Start
+	}
+	return FormatTimestamped(segments), starts, nil
+}
+
+// ParseTimestamped reads segments from lines starting with a [hh:mm:ss] timestamp.
+// Lines without timestamp are ignored. Segment end times are not known and set to the start.
+func ParseTimestamped(text string) []transcribe.Segment {
+	var segments []transcribe.Segment
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if !timestampLinePattern.MatchString(line) {
+			continue
+		}
+		start, ok := ParseTimestamp(line[1:9])
+		if !ok {
+			continue
+		}
+		segments = append(segments, transcribe.Segment{Start: start, End: start, Text: line[11:]})
+	}
+	return segments
+}
+
+func passagesFromSegments(file string, segments []transcribe.Segment, maxWords int) []Passage {
+	var passages []Passage
+	var current *Passage
+	words := 0
+	for _, seg := range segments {
+		text := strings.TrimSpace(seg.Text)
+		if text == "" {
+			continue
+		}
+		if current == nil {
+			current = &Passage{File: file, Start: seg.Start}
+		} else {
+			current.Text += " "
+		}
+		current.Text += text
+		current.End = seg.End
+		words += len(strings.Fields(text))
+		if words >= maxWords {
+			passages = append(passages, *current)
+			current = nil
+			words = 0
+		}
+	}
+	if current != nil {
+		passages = append(passages, *current)
+	}
+	return passages
+}
+
+func passagesFromText(file, text string, maxWords int) []Passage {
+	var passages []Passage
+	words := strings.Fields(text)
+	for start := 0; start < len(words); start += maxWords {
+		end := start + maxWords
+		if end > len(words) {
+			end = len(words)
+		}
+		passages = append(passages, Passage{
+			File:  file,
+			Start: -1,
+			End:   -1,
+			Text:  strings.Join(words[start:end], " "),
+		})
+	}
+	return passages
+}
+
+// Resolve turns a list of videos, transcripts and directories into a sorted list of transcript
+// files. The transcripts of videos are found with the layout they were processed with.
+func Resolve(paths []string, outputs *layout.Layout) ([]string, error) {
+	seen := make(map[string]bool)
+	var result []string
+	add := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			result = append(result, path)
+		}
+	}
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to access %s: %w", path, err)
+		}
+
+		switch {
+		case info.IsDir():
+			entries, err := os.ReadDir(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read directory: %w", err)
+			}
+			for _, entry := range entries {
+				file := filepath.Join(path, entry.Name())
+				switch {
+				case entry.IsDir():
+				case IsTranscriptFile(entry.Name()):
+					add(file)
+				case utils.IsMediaFile(file) && utils.FileExists(outputs.Transcript(file)):
+					add(outputs.Transcript(file))
+				}
+			}
+			// Transcripts named by the template, e.g. in an output directory
+			found, err := outputs.Find(path, layout.KindTranscript, ".md")
+			if err != nil {
+				return nil, err
+			}
+			for _, file := range found {
+				add(file)
+			}
+		case utils.IsMediaFile(path):
+			transcriptPath := outputs.Transcript(path)
+			if !utils.FileExists(transcriptPath) {
+				return nil, fmt.Errorf("no transcript found for %s", path)
+			}
+			add(transcriptPath)
+		default:
+			add(path)
+		}
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no transcripts found")
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// Split splits a transcript at line breaks into parts of at most maxChars where
+// possible, lines that are too long are split between words. 0 keeps it whole.
+func Split(text string, maxChars int) []string {
+	if maxChars <= 0 || len(text) <= maxChars {
+		return []string{text}
+	}
+	var parts []string
+	var current strings.Builder
+	add := func(piece, sep string) {
+		if current.Len() > 0 && current.Len()+len(piece)+1 > maxChars {
+			parts = append(parts, current.String())
+			current.Reset()
+		}
+		if current.Len() > 0 {
+			current.WriteString(sep)
+		}
+		current.WriteString(piece)
+	}
+	for _, line := range strings.Split(text, "\n") {
+		if len(line) <= maxChars {
+			add(line, "\n")
+			continue
+		}
+		for i, word := range strings.Fields(line) {
+			if i == 0 {
+				add(word, "\n")
+			} else {
+				add(word, " ")
+			}
+		}
+	}
+	if current.Len() > 0 {
+		parts = append(parts, current.String())
+	}
+	return parts
+}
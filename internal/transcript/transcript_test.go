@@ -0,0 +1,221 @@
+package transcript
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/mnote/internal/layout"
+	"github.com/giantswarm/mnote/internal/transcribe"
+)
+
+func TestFormatTimestamp(t *testing.T) {
+	tests := []struct {
+		seconds float64
+		want    string
+	}{
+		{0, "00:00:00"},
+		{59.9, "00:00:59"},
+		{754, "00:12:34"},
+		{3723, "01:02:03"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatTimestamp(tt.seconds); got != tt.want {
+			t.Errorf("FormatTimestamp(%v) = %s, want %s", tt.seconds, got, tt.want)
+		}
+	}
+}
+
+func TestSegmentsPath(t *testing.T) {
+	if got := SegmentsPath("/notes/standup_transcript.md"); got != "/notes/standup_segments.json" {
+		t.Errorf("SegmentsPath() = %s", got)
+	}
+	if got := SegmentsPath("/notes/other.txt"); got != "/notes/other_segments.json" {
+		t.Errorf("SegmentsPath() = %s", got)
+	}
+}
+
+func TestLoadPassages(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Transcript without segments is split by word count
+	plainPath := filepath.Join(tmpDir, "plain_transcript.md")
+	if err := os.WriteFile(plainPath, []byte("one two three four five"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	passages, err := LoadPassages(plainPath, 2)
+	if err != nil {
+		t.Fatalf("LoadPassages() error = %v", err)
+	}
+	if len(passages) != 3 {
+		t.Fatalf("expected 3 passages, got %d", len(passages))
+	}
+	if passages[0].Text != "one two" || passages[0].Timestamp() != "" {
+		t.Errorf("unexpected first passage: %+v", passages[0])
+	}
+
+	// Transcript with segments keeps timestamps
+	timedPath := filepath.Join(tmpDir, "timed_transcript.md")
+	if err := os.WriteFile(timedPath, []byte("ignored"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	segments := []transcribe.Segment{
+		{Start: 0, End: 5, Text: " We start the standup."},
+		{Start: 5, End: 10, Text: " Deploys are green."},
+		{Start: 754, End: 760, Text: " We decided to ship on Friday."},
+	}
+	if err := SaveSegments(SegmentsPath(timedPath), segments); err != nil {
+		t.Fatal(err)
+	}
+	passages, err = LoadPassages(timedPath, 5)
+	if err != nil {
+		t.Fatalf("LoadPassages() error = %v", err)
+	}
+	if len(passages) != 2 {
+		t.Fatalf("expected 2 passages, got %d", len(passages))
+	}
+	if passages[1].Timestamp() != "00:12:34" {
+		t.Errorf("expected second passage at 00:12:34, got %s", passages[1].Timestamp())
+	}
+	if !strings.Contains(passages[0].Text, "Deploys are green.") {
+		t.Errorf("expected first passage to join segments, got %q", passages[0].Text)
+	}
+}
+
+func TestLoadPassagesTimestampedMarkdown(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "meeting_transcript.md")
+	md := FormatMarkdown([]transcribe.Segment{
+		{Start: 0, Text: "Hello."},
+		{Start: 754, Text: "We ship on Friday."},
+	})
+	if err := os.WriteFile(path, []byte(md), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	passages, err := LoadPassages(path, 3)
+	if err != nil {
+		t.Fatalf("LoadPassages() error = %v", err)
+	}
+	if len(passages) != 1 || passages[0].Text != "Hello. We ship on Friday." || passages[0].Timestamp() != "00:00:00" {
+		t.Errorf("unexpected passages: %+v", passages)
+	}
+}
+
+func TestReadTimestamped(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	plainPath := filepath.Join(tmpDir, "plain_transcript.md")
+	os.WriteFile(plainPath, []byte("Just text."), 0644)
+	text, starts, err := ReadTimestamped(plainPath)
+	if err != nil || text != "Just text." || starts != nil {
+		t.Errorf("ReadTimestamped() = %q, %v, %v", text, starts, err)
+	}
+
+	timedPath := filepath.Join(tmpDir, "timed_transcript.md")
+	segments := []transcribe.Segment{{Start: 0, Text: "Hello."}, {Start: 754, Text: "Bye."}}
+	os.WriteFile(timedPath, []byte(FormatMarkdown(segments)), 0644)
+	SaveSegments(SegmentsPath(timedPath), segments)
+	text, starts, err = ReadTimestamped(timedPath)
+	if err != nil {
+		t.Fatalf("ReadTimestamped() error = %v", err)
+	}
+	if text != "[00:00:00] Hello.\n[00:12:34] Bye.\n" || len(starts) != 2 || starts[1] != 754 {
+		t.Errorf("ReadTimestamped() = %q, %v", text, starts)
+	}
+
+	// A transcript edited after it was written is read with its edits
+	edited := strings.Replace(FormatMarkdown(segments), "Bye.", "Bye, Alice.", 1)
+	os.WriteFile(timedPath, []byte(edited), 0644)
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(timedPath, later, later)
+	text, starts, err = ReadTimestamped(timedPath)
+	if err != nil {
+		t.Fatalf("ReadTimestamped() error = %v", err)
+	}
+	if text != "[00:00:00] Hello.\n[00:12:34] Bye, Alice.\n" || len(starts) != 2 || starts[1] != 754 {
+		t.Errorf("ReadTimestamped() of an edited transcript = %q, %v", text, starts)
+	}
+	passages, err := LoadPassages(timedPath, 10)
+	if err != nil || len(passages) != 1 || passages[0].Text != "Hello. Bye, Alice." {
+		t.Errorf("LoadPassages() of an edited transcript = %+v, %v", passages, err)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, name := range []string{"a_transcript.md", "b_transcript.md", "a.mp4", "notes.md"} {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := Resolve([]string{tmpDir, filepath.Join(tmpDir, "a.mp4")}, layout.Default())
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	want := []string{filepath.Join(tmpDir, "a_transcript.md"), filepath.Join(tmpDir, "b_transcript.md")}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Resolve() = %v, want %v", got, want)
+	}
+
+	if _, err := Resolve([]string{filepath.Join(tmpDir, "missing.mp4")}, layout.Default()); err == nil {
+		t.Error("Resolve() should fail for missing input")
+	}
+
+	// Transcripts written to an output directory are found for the videos
+	outDir := filepath.Join(t.TempDir(), "notes")
+	outputs, err := layout.New(layout.Options{OutputDir: outDir, Roots: []string{tmpDir}, Template: "{{.Title}}/{{.Kind}}.md"})
+	if err != nil {
+		t.Fatalf("layout.New() error = %v", err)
+	}
+	os.MkdirAll(filepath.Join(outDir, "a"), 0755)
+	os.WriteFile(filepath.Join(outDir, "a", "transcript.md"), []byte("x"), 0644)
+	for _, input := range []string{tmpDir, filepath.Join(tmpDir, "a.mp4")} {
+		got, err = Resolve([]string{input}, outputs)
+		if err != nil {
+			t.Fatalf("Resolve() error = %v", err)
+		}
+		if got[len(got)-1] != filepath.Join(outDir, "a", "transcript.md") {
+			t.Errorf("Resolve(%s) = %v, want the transcript in the output directory", input, got)
+		}
+	}
+
+	// The output directory itself is searched with the name template
+	dated, err := layout.New(layout.Options{Template: "{{.Date}}-{{.Title}}/{{.Kind}}.md"})
+	if err != nil {
+		t.Fatalf("layout.New() error = %v", err)
+	}
+	os.MkdirAll(filepath.Join(outDir, "2024-03-11-standup"), 0755)
+	os.WriteFile(filepath.Join(outDir, "2024-03-11-standup", "transcript.md"), []byte("x"), 0644)
+	got, err = Resolve([]string{outDir}, dated)
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if len(got) != 1 || got[0] != filepath.Join(outDir, "2024-03-11-standup", "transcript.md") {
+		t.Errorf("Resolve() with a name template = %v", got)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		maxChars int
+		want     []string
+	}{
+		{name: "short", text: "a\nb", maxChars: 10, want: []string{"a\nb"}},
+		{name: "disabled", text: "aaaa\nbbbb", maxChars: 0, want: []string{"aaaa\nbbbb"}},
+		{name: "lines", text: "aaaa\nbbbb\ncccc", maxChars: 10, want: []string{"aaaa\nbbbb", "cccc"}},
+		{name: "long line", text: "one two three four", maxChars: 9, want: []string{"one two", "three", "four"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Split(tt.text, tt.maxChars); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("Split() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
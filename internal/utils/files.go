@@ -2,6 +2,7 @@ package utils
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
@@ -9,12 +10,17 @@ import (
 
 // GetOutputPath generates the output path for a given input file and suffix
 func GetOutputPath(inputPath, suffix string) string {
+	return GetOutputPathWithExt(inputPath, suffix, ".md")
+}
+
+// GetOutputPathWithExt generates the output path for a given input file, suffix and extension
+func GetOutputPathWithExt(inputPath, suffix, ext string) string {
 	dir := filepath.Dir(inputPath)
 	base := strings.TrimSuffix(filepath.Base(inputPath), filepath.Ext(inputPath))
 	if suffix != "" {
-		return filepath.Join(dir, fmt.Sprintf("%s_%s.md", base, suffix))
+		return filepath.Join(dir, fmt.Sprintf("%s_%s%s", base, suffix, ext))
 	}
-	return filepath.Join(dir, base+".md")
+	return filepath.Join(dir, base+ext)
 }
 
 // FileExists checks if a file exists and is not a directory
@@ -32,12 +38,83 @@ func EnsureDirectory(path string) error {
 	return os.MkdirAll(dir, 0755)
 }
 
-// WriteFile writes data to a file, creating the directory if needed
+// RelativeLink returns the markdown link target of a file relative to the document at
+// docPath. Every path segment is percent-encoded, so names with spaces stay valid links.
+func RelativeLink(docPath, target string) string {
+	rel, err := filepath.Rel(filepath.Dir(docPath), target)
+	if err != nil {
+		rel = target
+	}
+	segments := strings.Split(filepath.ToSlash(rel), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// WriteFile writes data to a file atomically, creating the directory if needed. An
+// interrupted write leaves the previous file or no file, never a truncated one.
 func WriteFile(path string, data []byte) error {
+	return WriteFileMode(path, data, 0644)
+}
+
+// WriteFileMode writes data to a file atomically with the given permissions. The data is
+// written to a temporary file in the same directory, synced and renamed over the file.
+func WriteFileMode(path string, data []byte, perm os.FileMode) error {
 	if err := EnsureDirectory(path); err != nil {
 		return fmt.Errorf("failed to create directory: %w", err)
 	}
-	return os.WriteFile(path, data, 0644)
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	// Removing fails harmlessly once the file was renamed
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	syncDir(filepath.Dir(path))
+	return nil
+}
+
+// syncDir flushes a directory so a rename in it survives a crash, on a best effort basis
+// as not every platform and file system supports syncing directories
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
+
+// syncFile flushes a file written by another process to disk
+func syncFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
 }
 
 // ReadFile reads the entire file into memory
@@ -36,6 +36,13 @@ func TestGetOutputPath(t *testing.T) {
 	}
 }
 
+func TestGetOutputPathWithExt(t *testing.T) {
+	got := GetOutputPathWithExt("/path/to/video.mp4", "segments", ".json")
+	if want := "/path/to/video_segments.json"; got != want {
+		t.Errorf("GetOutputPathWithExt() = %v, want %v", got, want)
+	}
+}
+
 func TestFileExists(t *testing.T) {
 	// Create temporary directory
 	tmpDir := t.TempDir()
@@ -83,3 +90,56 @@ func TestWriteFile(t *testing.T) {
 		t.Errorf("WriteFile() wrote %v, want %v", string(got), string(testData))
 	}
 }
+
+func TestWriteFileReplaces(t *testing.T) {
+	tmpDir := t.TempDir()
+	testPath := filepath.Join(tmpDir, "test.txt")
+	if err := os.WriteFile(testPath, []byte("old content that is longer"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	if err := WriteFileMode(testPath, []byte("new"), 0600); err != nil {
+		t.Fatalf("WriteFileMode() error = %v", err)
+	}
+	got, _ := os.ReadFile(testPath)
+	if string(got) != "new" {
+		t.Errorf("WriteFileMode() wrote %q, want %q", got, "new")
+	}
+	info, _ := os.Stat(testPath)
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("WriteFileMode() permissions = %v, want 0600", perm)
+	}
+
+	// No temporary files are left behind
+	entries, _ := os.ReadDir(tmpDir)
+	if len(entries) != 1 {
+		t.Errorf("expected only the written file, got %d entries", len(entries))
+	}
+}
+
+func TestRelativeLink(t *testing.T) {
+	tests := []struct {
+		docPath, target, want string
+	}{
+		{"/notes/standup_summarize.md", "/notes/standup_transcript.md", "standup_transcript.md"},
+		{"/notes/Team Standup_summarize.md", "/notes/Team Standup_transcript.md", "Team%20Standup_transcript.md"},
+		{"/notes/2024/Team Standup/summarize.md", "/videos/Team Standup.mp4", "../../../videos/Team%20Standup.mp4"},
+		{"/notes/a_summarize.md", "/notes/50%#1.mp4", "50%25%231.mp4"},
+	}
+	for _, tt := range tests {
+		if got := RelativeLink(tt.docPath, tt.target); got != tt.want {
+			t.Errorf("RelativeLink(%q, %q) = %q, want %q", tt.docPath, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestSyncFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audio.mp3")
+	if err := syncFile(path); err == nil {
+		t.Error("syncFile() should fail for a missing file")
+	}
+	os.WriteFile(path, []byte("audio"), 0644)
+	if err := syncFile(path); err != nil {
+		t.Errorf("syncFile() error = %v", err)
+	}
+}
@@ -1,25 +1,44 @@
 package utils
 
 import (
+	"encoding/json"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	ffmpeg "github.com/u2takey/ffmpeg-go"
 )
 
-// FFmpegRunner defines the interface for audio extraction
+// FFmpegRunner defines the interface for audio extraction and media inspection
 type FFmpegRunner interface {
-	ExtractAudioFromVideo(inputPath, outputPath string) error
+	// ExtractAudioFromVideo extracts the audio, progress is called with the position in the
+	// input while ffmpeg runs if it is not nil
+	ExtractAudioFromVideo(inputPath, outputPath string, progress func(time.Duration)) error
+	// CutAudio copies length of the audio from start on to outputPath without re-encoding
+	CutAudio(inputPath, outputPath string, start, length time.Duration) error
+	ProbeDuration(path string) (time.Duration, error)
+	ProbeStreams(path string) ([]Stream, error)
+}
+
+// Stream is a stream of a media file as reported by ffprobe
+type Stream struct {
+	// Type is "audio", "video", "subtitle" or "data"
+	Type  string
+	Codec string
+	// AttachedPic is set for the cover art of audio files, which does not make them videos
+	AttachedPic bool
 }
 
 // DefaultFFmpegRunner implements FFmpegRunner using ffmpeg-go
 type DefaultFFmpegRunner struct{}
 
 // ExtractAudioFromVideo implements FFmpegRunner interface
-func (r *DefaultFFmpegRunner) ExtractAudioFromVideo(inputPath, outputPath string) error {
-	return ffmpeg.Input(inputPath).
+func (r *DefaultFFmpegRunner) ExtractAudioFromVideo(inputPath, outputPath string, progress func(time.Duration)) error {
+	stream := ffmpeg.Input(inputPath).
 		Output(outputPath, ffmpeg.KwArgs{
 			"acodec": "libmp3lame",
 			"ab":     "192k",
@@ -27,23 +46,143 @@ func (r *DefaultFFmpegRunner) ExtractAudioFromVideo(inputPath, outputPath string
 			"y":      "", // Overwrite output file if it exists
 		}).
 		OverWriteOutput().
-		Run()
+		Silent(true)
+	if progress != nil {
+		// ffmpeg writes key=value progress blocks to stdout
+		stream = stream.GlobalArgs("-progress", "pipe:1", "-nostats").
+			WithOutput(&progressWriter{report: progress})
+	}
+	slog.Debug("Running ffmpeg", "args", strings.Join(stream.GetArgs(), " "))
+	return stream.Run()
+}
+
+// CutAudio implements FFmpegRunner interface
+func (r *DefaultFFmpegRunner) CutAudio(inputPath, outputPath string, start, length time.Duration) error {
+	stream := ffmpeg.Input(inputPath, ffmpeg.KwArgs{"ss": fmt.Sprintf("%.3f", start.Seconds())}).
+		Output(outputPath, ffmpeg.KwArgs{
+			"t": fmt.Sprintf("%.3f", length.Seconds()),
+			"c": "copy",
+		}).
+		OverWriteOutput().
+		Silent(true)
+	slog.Debug("Running ffmpeg", "args", strings.Join(stream.GetArgs(), " "))
+	return stream.Run()
+}
+
+// progressWriter parses the out_time_us lines of ffmpeg's -progress output
+type progressWriter struct {
+	report func(time.Duration)
+	line   []byte
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	for _, b := range p {
+		if b != '\n' {
+			w.line = append(w.line, b)
+			continue
+		}
+		if value, ok := strings.CutPrefix(string(w.line), "out_time_us="); ok {
+			if us, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64); err == nil && us >= 0 {
+				w.report(time.Duration(us) * time.Microsecond)
+			}
+		}
+		w.line = w.line[:0]
+	}
+	return len(p), nil
+}
+
+// ProbeDuration implements FFmpegRunner interface using ffprobe
+func (r *DefaultFFmpegRunner) ProbeDuration(path string) (time.Duration, error) {
+	out, err := ffmpeg.Probe(path)
+	if err != nil {
+		return 0, err
+	}
+	var probe struct {
+		Format struct {
+			Duration string `json:"duration"`
+		} `json:"format"`
+	}
+	if err := json.Unmarshal([]byte(out), &probe); err != nil {
+		return 0, fmt.Errorf("failed to parse ffprobe output: %w", err)
+	}
+	seconds, err := strconv.ParseFloat(probe.Format.Duration, 64)
+	if err != nil {
+		return 0, fmt.Errorf("no duration in ffprobe output: %w", err)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// ProbeStreams implements FFmpegRunner interface using ffprobe
+func (r *DefaultFFmpegRunner) ProbeStreams(path string) ([]Stream, error) {
+	out, err := ffmpeg.Probe(path)
+	if err != nil {
+		return nil, err
+	}
+	var probe struct {
+		Streams []struct {
+			CodecType   string `json:"codec_type"`
+			CodecName   string `json:"codec_name"`
+			Disposition struct {
+				AttachedPic int `json:"attached_pic"`
+			} `json:"disposition"`
+		} `json:"streams"`
+	}
+	if err := json.Unmarshal([]byte(out), &probe); err != nil {
+		return nil, fmt.Errorf("failed to parse ffprobe output: %w", err)
+	}
+	streams := make([]Stream, len(probe.Streams))
+	for i, s := range probe.Streams {
+		streams[i] = Stream{Type: s.CodecType, Codec: s.CodecName, AttachedPic: s.Disposition.AttachedPic == 1}
+	}
+	return streams, nil
 }
 
 // MockFFmpegRunner implements FFmpegRunner for testing
 type MockFFmpegRunner struct {
 	ExtractCalled bool
 	ForceError    bool
+	// Duration is reported by ProbeDuration for every file
+	Duration time.Duration
+	// Cuts records the start of every cut audio part
+	Cuts []time.Duration
+	// Streams is reported by ProbeStreams for every file, none leaves the media type to
+	// the extension
+	Streams []Stream
 }
 
-func (m *MockFFmpegRunner) ExtractAudioFromVideo(inputPath, outputPath string) error {
+func (m *MockFFmpegRunner) ExtractAudioFromVideo(inputPath, outputPath string, progress func(time.Duration)) error {
 	m.ExtractCalled = true
 	if m.ForceError {
 		return fmt.Errorf("mock ffmpeg error")
 	}
+	if progress != nil {
+		progress(m.Duration)
+	}
 	return os.WriteFile(outputPath, []byte("mock mp3 content"), 0644)
 }
 
+func (m *MockFFmpegRunner) CutAudio(inputPath, outputPath string, start, length time.Duration) error {
+	if m.ForceError {
+		return fmt.Errorf("mock ffmpeg error")
+	}
+	m.Cuts = append(m.Cuts, start)
+	return os.WriteFile(outputPath, []byte(fmt.Sprintf("mock mp3 content from %s", start)), 0644)
+}
+
+func (m *MockFFmpegRunner) ProbeDuration(_ string) (time.Duration, error) {
+	if m.ForceError {
+		return 0, fmt.Errorf("mock ffprobe error")
+	}
+	return m.Duration, nil
+}
+
+func (m *MockFFmpegRunner) ProbeStreams(_ string) ([]Stream, error) {
+	if m.ForceError {
+		return nil, fmt.Errorf("mock ffprobe error")
+	}
+	return m.Streams, nil
+}
+
 // defaultFFmpeg is the default FFmpeg runner implementation
 var defaultFFmpeg FFmpegRunner = &DefaultFFmpegRunner{}
 
@@ -55,46 +194,154 @@ func SetFFmpegRunner(runner FFmpegRunner) {
 // SupportedVideoFormats contains the list of supported video file extensions
 var SupportedVideoFormats = []string{".mp4", ".mkv", ".avi", ".mov"}
 
-// ExtractAudio extracts audio from a video file and saves it in the same directory
-func ExtractAudio(videoPath string, forceRebuild bool) (string, error) {
-	// Validate video format
-	ext := strings.ToLower(filepath.Ext(videoPath))
-	supported := false
-	for _, format := range SupportedVideoFormats {
-		if ext == format {
-			supported = true
-			break
+// SupportedAudioFormats contains the list of supported audio file extensions. WebM files
+// hold audio or video, ProbeMedia tells them apart.
+var SupportedAudioFormats = []string{".mp3", ".wav", ".m4a", ".ogg", ".flac", ".opus", ".webm"}
+
+// TranscriptionFormats are the audio file extensions and TranscriptionCodecs the codecs
+// the transcription backend accepts, other audio files are converted to mp3 first
+var (
+	TranscriptionFormats = []string{".mp3", ".wav", ".m4a", ".ogg", ".flac", ".webm"}
+	TranscriptionCodecs  = []string{"mp3", "aac", "flac", "vorbis", "opus", "pcm_s16le"}
+)
+
+// Media kinds
+const (
+	MediaVideo = "video"
+	MediaAudio = "audio"
+)
+
+// MediaInfo describes what a media file holds
+type MediaInfo struct {
+	// Kind is MediaVideo or MediaAudio
+	Kind string
+	// Ext is the lower case extension of the file
+	Ext string
+	// AudioCodec is the codec of the first audio stream, empty if it is not known
+	AudioCodec string
+}
+
+// ProbeMedia detects whether a file is a video or audio only from the streams reported
+// by ffprobe, cover art does not count as video. If ffprobe cannot read the file the
+// extension decides.
+func ProbeMedia(path string) MediaInfo {
+	info := MediaInfo{Kind: MediaAudio, Ext: strings.ToLower(filepath.Ext(path))}
+	if IsVideoFile(path) {
+		info.Kind = MediaVideo
+	}
+	streams, err := defaultFFmpeg.ProbeStreams(path)
+	if err != nil || len(streams) == 0 {
+		return info
+	}
+	info.Kind = MediaAudio
+	for _, s := range streams {
+		switch {
+		case s.Type == "video" && !s.AttachedPic:
+			info.Kind = MediaVideo
+		case s.Type == "audio" && info.AudioCodec == "":
+			info.AudioCodec = s.Codec
 		}
 	}
-	if !supported {
-		return "", fmt.Errorf("unsupported video format: %s", ext)
+	return info
+}
+
+// NeedsTranscode reports whether the audio of the file has to be converted before it is
+// transcribed, videos always do
+func (m MediaInfo) NeedsTranscode() bool {
+	if m.Kind == MediaVideo || !contains(TranscriptionFormats, m.Ext) {
+		return true
 	}
+	return m.AudioCodec != "" && !contains(TranscriptionCodecs, m.AudioCodec)
+}
+
+// ExtractAudio extracts audio from a video file and saves it in the same directory
+func ExtractAudio(videoPath string, forceRebuild bool) (string, error) {
+	return ExtractAudioTo(videoPath, AudioPath(videoPath), forceRebuild)
+}
+
+// ExtractAudioTo extracts audio from a video file to the given path, creating its directory
+func ExtractAudioTo(videoPath, audioPath string, forceRebuild bool) (string, error) {
+	return ExtractAudioProgress(videoPath, audioPath, forceRebuild, nil)
+}
 
-	// Generate output path in the same directory
-	dir := filepath.Dir(videoPath)
-	base := strings.TrimSuffix(filepath.Base(videoPath), ext)
-	audioPath := filepath.Join(dir, base+".mp3")
+// ExtractAudioProgress extracts audio like ExtractAudioTo and calls progress with the
+// position in the video while ffmpeg runs
+func ExtractAudioProgress(videoPath, audioPath string, forceRebuild bool, progress func(time.Duration)) (string, error) {
+	// Validate media format, the audio of audio files is converted
+	if !IsMediaFile(videoPath) {
+		return "", fmt.Errorf("unsupported media format: %s", filepath.Ext(videoPath))
+	}
+	if audioPath == videoPath {
+		return "", fmt.Errorf("audio would overwrite its source: %s", videoPath)
+	}
 
 	// Check if file exists and skip if not forcing rebuild
 	if !forceRebuild && FileExists(audioPath) {
-		fmt.Printf("Audio file already exists: %s\n", audioPath)
 		return audioPath, nil
 	}
+	if err := EnsureDirectory(audioPath); err != nil {
+		return "", fmt.Errorf("failed to create audio directory: %w", err)
+	}
 
-	// Extract audio using ffmpeg
-	err := defaultFFmpeg.ExtractAudioFromVideo(videoPath, audioPath)
-	if err != nil {
+	// Extract audio using ffmpeg into a partial file that replaces the audio file once it
+	// is complete, an interrupted extraction is not mistaken for a complete one
+	partialPath := filepath.Join(filepath.Dir(audioPath), "."+strings.TrimSuffix(filepath.Base(audioPath), ".mp3")+".partial.mp3")
+	defer os.Remove(partialPath)
+	if err := defaultFFmpeg.ExtractAudioFromVideo(videoPath, partialPath, progress); err != nil {
 		return "", fmt.Errorf("failed to extract audio: %w", err)
 	}
+	if err := syncFile(partialPath); err != nil {
+		return "", fmt.Errorf("failed to save audio: %w", err)
+	}
+	if err := os.Rename(partialPath, audioPath); err != nil {
+		return "", fmt.Errorf("failed to save audio: %w", err)
+	}
+	syncDir(filepath.Dir(audioPath))
 
 	return audioPath, nil
 }
 
+// CutAudio copies a part of an audio file, starting at start and at most length long
+func CutAudio(audioPath, partPath string, start, length time.Duration) error {
+	if err := defaultFFmpeg.CutAudio(audioPath, partPath, start, length); err != nil {
+		return fmt.Errorf("failed to cut audio: %w", err)
+	}
+	return nil
+}
+
+// MediaDuration returns the duration of an audio or video file
+func MediaDuration(path string) (time.Duration, error) {
+	duration, err := defaultFFmpeg.ProbeDuration(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to probe %s: %w", path, err)
+	}
+	return duration, nil
+}
+
+// AudioPath returns the path of the audio file extracted from a video, <name>_audio.mp3 in
+// the same directory, so it never replaces an input mp3 of the same name
+func AudioPath(videoPath string) string {
+	return GetOutputPathWithExt(videoPath, "audio", ".mp3")
+}
+
 // IsVideoFile checks if the given file is a supported video file
 func IsVideoFile(path string) bool {
-	ext := strings.ToLower(filepath.Ext(path))
-	for _, format := range SupportedVideoFormats {
-		if ext == format {
+	return contains(SupportedVideoFormats, strings.ToLower(filepath.Ext(path)))
+}
+
+// IsAudioFile checks if the given file is a supported audio file by its extension
+func IsAudioFile(path string) bool {
+	return contains(SupportedAudioFormats, strings.ToLower(filepath.Ext(path)))
+}
+
+// IsMediaFile checks if the given file is a supported video or audio file
+func IsMediaFile(path string) bool {
+	return IsVideoFile(path) || IsAudioFile(path)
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
 			return true
 		}
 	}
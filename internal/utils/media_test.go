@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestExtractAudio(t *testing.T) {
@@ -126,3 +127,128 @@ func TestIsVideoFile(t *testing.T) {
 		})
 	}
 }
+
+func TestIsAudioFile(t *testing.T) {
+	tests := []struct {
+		path  string
+		audio bool
+		media bool
+	}{
+		{"memo.m4a", true, true},
+		{"podcast.MP3", true, true},
+		{"voice.opus", true, true},
+		{"call.webm", true, true},
+		{"video.mp4", false, true},
+		{"notes.txt", false, false},
+	}
+	for _, tt := range tests {
+		if got := IsAudioFile(tt.path); got != tt.audio {
+			t.Errorf("IsAudioFile(%s) = %v, want %v", tt.path, got, tt.audio)
+		}
+		if got := IsMediaFile(tt.path); got != tt.media {
+			t.Errorf("IsMediaFile(%s) = %v, want %v", tt.path, got, tt.media)
+		}
+	}
+}
+
+func TestProbeMedia(t *testing.T) {
+	origFFmpeg := defaultFFmpeg
+	defer func() { defaultFFmpeg = origFFmpeg }()
+
+	tests := []struct {
+		name      string
+		path      string
+		streams   []Stream
+		kind      string
+		transcode bool
+	}{
+		{name: "video by extension", path: "call.mp4", kind: MediaVideo, transcode: true},
+		{name: "audio by extension", path: "memo.m4a", kind: MediaAudio},
+		{name: "unsupported extension", path: "voice.opus", kind: MediaAudio, transcode: true},
+		{
+			name:      "webm with video",
+			path:      "call.webm",
+			streams:   []Stream{{Type: "video", Codec: "vp9"}, {Type: "audio", Codec: "opus"}},
+			kind:      MediaVideo,
+			transcode: true,
+		},
+		{name: "webm audio only", path: "call.webm", streams: []Stream{{Type: "audio", Codec: "opus"}}, kind: MediaAudio},
+		{
+			name:    "cover art",
+			path:    "podcast.mp3",
+			streams: []Stream{{Type: "audio", Codec: "mp3"}, {Type: "video", Codec: "mjpeg", AttachedPic: true}},
+			kind:    MediaAudio,
+		},
+		{name: "unsupported codec", path: "memo.m4a", streams: []Stream{{Type: "audio", Codec: "alac"}}, kind: MediaAudio, transcode: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defaultFFmpeg = &MockFFmpegRunner{Streams: tt.streams}
+			info := ProbeMedia(tt.path)
+			if info.Kind != tt.kind {
+				t.Errorf("ProbeMedia() kind = %s, want %s", info.Kind, tt.kind)
+			}
+			if got := info.NeedsTranscode(); got != tt.transcode {
+				t.Errorf("NeedsTranscode() = %v, want %v", got, tt.transcode)
+			}
+		})
+	}
+}
+
+func TestExtractAudioKeepsSource(t *testing.T) {
+	tmpDir := t.TempDir()
+	audioPath := filepath.Join(tmpDir, "podcast.mp3")
+	os.WriteFile(audioPath, []byte("podcast"), 0644)
+
+	origFFmpeg := defaultFFmpeg
+	defaultFFmpeg = &MockFFmpegRunner{}
+	defer func() { defaultFFmpeg = origFFmpeg }()
+
+	converted, err := ExtractAudio(audioPath, true)
+	if err != nil {
+		t.Fatalf("ExtractAudio() error = %v", err)
+	}
+	if filepath.Base(converted) != "podcast_audio.mp3" {
+		t.Errorf("expected the audio to be written to podcast_audio.mp3, got %s", converted)
+	}
+	if content, _ := os.ReadFile(audioPath); string(content) != "podcast" {
+		t.Errorf("expected the source to be kept, got %q", content)
+	}
+	if _, err := ExtractAudioTo(audioPath, audioPath, true); err == nil {
+		t.Error("expected an error when the audio would overwrite its source")
+	}
+}
+
+func TestMediaDuration(t *testing.T) {
+	origFFmpeg := defaultFFmpeg
+	defer func() { defaultFFmpeg = origFFmpeg }()
+
+	defaultFFmpeg = &MockFFmpegRunner{Duration: 90 * time.Second}
+	duration, err := MediaDuration("meeting.mp4")
+	if err != nil {
+		t.Fatalf("MediaDuration() error = %v", err)
+	}
+	if duration != 90*time.Second {
+		t.Errorf("MediaDuration() = %v, want 1m30s", duration)
+	}
+
+	defaultFFmpeg = &MockFFmpegRunner{ForceError: true}
+	if _, err := MediaDuration("meeting.mp4"); err == nil {
+		t.Error("expected an error when ffprobe fails")
+	}
+}
+
+func TestProgressWriter(t *testing.T) {
+	var positions []time.Duration
+	w := &progressWriter{report: func(d time.Duration) { positions = append(positions, d) }}
+
+	// Blocks may be split anywhere
+	output := "frame=0\nout_time_us=1500000\nout_time=00:00:01.500000\nprogress=continue\nout_time_us=N/A\nout_ti"
+	w.Write([]byte(output))
+	w.Write([]byte("me_us=3000000\nprogress=end\n"))
+
+	want := []time.Duration{1500 * time.Millisecond, 3 * time.Second}
+	if len(positions) != len(want) || positions[0] != want[0] || positions[1] != want[1] {
+		t.Errorf("progressWriter reported %v, want %v", positions, want)
+	}
+}
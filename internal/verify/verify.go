@@ -0,0 +1,448 @@
+package verify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/giantswarm/mnote/internal/config"
+	"github.com/giantswarm/mnote/internal/summarize"
+	"github.com/giantswarm/mnote/internal/transcript"
+	"github.com/sashabaranov/go-openai"
+)
+
+// QuoteThreshold is the minimum similarity for a quote to count as found in the transcript
+const QuoteThreshold = 0.8
+
+// maxClaims limits the number of claims sent to the judge
+const maxClaims = 60
+
+// sectionMarker precedes the verification section so it can be replaced on later runs
+const sectionMarker = "<!-- mnote:verification -->"
+
+const judgePrompt = `You check whether statements from a meeting summary are supported by the meeting transcript.
+The user sends the transcript, or a part of it, followed by a numbered list of statements.
+A statement is supported only if the transcript states it or it follows directly from what was said.
+Decisions, owners, dates and numbers must match exactly.
+Respond with a JSON object of the form {"verdicts": [{"claim": 1, "supported": true, "reason": "short explanation"}]}
+containing one verdict per statement.`
+
+// Finding is a statement from the summary and whether the transcript supports it
+type Finding struct {
+	Text      string
+	Supported bool
+	Score     float64 // Similarity for quotes, 1 or 0 for names and judged claims
+	Reason    string
+}
+
+// Report holds the results of a verification
+type Report struct {
+	Quotes []Finding
+	Names  []Finding
+	Claims []Finding
+}
+
+// Unsupported returns all findings that are not supported by the transcript
+func (r *Report) Unsupported() []Finding {
+	var unsupported []Finding
+	for _, group := range [][]Finding{r.Quotes, r.Names, r.Claims} {
+		for _, f := range group {
+			if !f.Supported {
+				unsupported = append(unsupported, f)
+			}
+		}
+	}
+	return unsupported
+}
+
+// Markdown renders the report as a summary section
+func (r *Report) Markdown() string {
+	var b strings.Builder
+	b.WriteString(sectionMarker + "\n## Verification\n\n")
+	fmt.Fprintf(&b, "Checked %d quotes, %d names and %d claims against the transcript.\n\n",
+		len(r.Quotes), len(r.Names), len(r.Claims))
+
+	unsupported := r.Unsupported()
+	if len(unsupported) == 0 {
+		b.WriteString("All checked statements are supported by the transcript.\n")
+		return b.String()
+	}
+
+	b.WriteString("The following statements are not supported by the transcript:\n\n")
+	write := func(kind string, findings []Finding) {
+		for _, f := range findings {
+			if f.Supported {
+				continue
+			}
+			fmt.Fprintf(&b, "- **%s**: %s", kind, f.Text)
+			if f.Reason != "" {
+				fmt.Fprintf(&b, " (%s)", f.Reason)
+			}
+			b.WriteString("\n")
+		}
+	}
+	write("Quote", r.Quotes)
+	write("Name", r.Names)
+	write("Claim", r.Claims)
+	return b.String()
+}
+
+// StripSection removes a verification section added by an earlier run
+func StripSection(summary string) string {
+	if i := strings.Index(summary, sectionMarker); i >= 0 {
+		return strings.TrimRight(summary[:i], "\n") + "\n"
+	}
+	return summary
+}
+
+// AppendSection adds the report to the summary, replacing an earlier verification section
+func AppendSection(summary string, r *Report) string {
+	return strings.TrimRight(StripSection(summary), "\n") + "\n\n" + r.Markdown()
+}
+
+// Verifier checks summaries against their transcripts
+type Verifier struct {
+	client summarize.OpenAIClient
+	config *config.Config
+}
+
+// NewVerifier creates a new Verifier instance. The client may be nil if claims are not judged.
+func NewVerifier(cfg *config.Config, client summarize.OpenAIClient) *Verifier {
+	return &Verifier{
+		client: client,
+		config: cfg,
+	}
+}
+
+// Verify checks the quotes and names of the summary against the transcript.
+// If judge is set, the claims of the summary are also checked by the chat model.
+func (v *Verifier) Verify(summary, transcript string, judge bool) (*Report, error) {
+	summary = StripSection(summary)
+	report := &Report{}
+
+	transcriptWords := words(transcript)
+	for _, quote := range ExtractQuotes(summary) {
+		score := MatchQuote(quote, transcriptWords)
+		report.Quotes = append(report.Quotes, Finding{
+			Text:      quote,
+			Supported: score >= QuoteThreshold,
+			Score:     score,
+		})
+	}
+
+	known := make(map[string]bool)
+	for _, w := range transcriptWords {
+		known[w] = true
+	}
+	for _, name := range ExtractNames(summary) {
+		supported := true
+		for _, part := range words(name) {
+			if !known[part] {
+				supported = false
+				break
+			}
+		}
+		report.Names = append(report.Names, Finding{Text: name, Supported: supported, Score: boolScore(supported)})
+	}
+
+	if judge {
+		claims := ExtractClaims(summary)
+		findings, err := v.judge(claims, transcript)
+		if err != nil {
+			return nil, err
+		}
+		report.Claims = findings
+	}
+
+	return report, nil
+}
+
+type verdicts struct {
+	Verdicts []struct {
+		Claim     int    `json:"claim"`
+		Supported bool   `json:"supported"`
+		Reason    string `json:"reason"`
+	} `json:"verdicts"`
+}
+
+// judge asks the chat model which claims the transcript supports. Transcripts longer than
+// the summary chunk size are judged in parts like they are summarized, a claim is supported
+// if any part supports it.
+func (v *Verifier) judge(claims []string, transcriptText string) ([]Finding, error) {
+	if len(claims) == 0 {
+		return nil, nil
+	}
+	if v.client == nil {
+		return nil, fmt.Errorf("no chat client available to judge claims")
+	}
+
+	// Claims without a verdict count as unsupported
+	findings := make([]Finding, len(claims))
+	for i, claim := range claims {
+		findings[i] = Finding{Text: claim, Reason: "no verdict returned"}
+	}
+
+	parts := transcript.Split(transcriptText, v.config.SummaryChunkChars)
+	for n, part := range parts {
+		heading := "Transcript:"
+		if len(parts) > 1 {
+			heading = fmt.Sprintf("Transcript, part %d of %d:", n+1, len(parts))
+		}
+		result, err := v.judgePart(claims, heading, part)
+		if err != nil {
+			if len(parts) > 1 {
+				return nil, fmt.Errorf("failed to judge part %d of %d: %w", n+1, len(parts), err)
+			}
+			return nil, err
+		}
+		for _, verdict := range result.Verdicts {
+			if verdict.Claim < 1 || verdict.Claim > len(claims) {
+				continue
+			}
+			f := &findings[verdict.Claim-1]
+			if f.Supported {
+				continue
+			}
+			f.Supported = verdict.Supported
+			f.Score = boolScore(verdict.Supported)
+			f.Reason = verdict.Reason
+			if f.Supported {
+				f.Reason = ""
+			}
+		}
+	}
+	return findings, nil
+}
+
+// judgePart asks for the verdicts on the claims against one part of the transcript
+func (v *Verifier) judgePart(claims []string, heading, part string) (*verdicts, error) {
+	var b strings.Builder
+	b.WriteString(heading + "\n\n")
+	b.WriteString(part)
+	b.WriteString("\n\nStatements:\n\n")
+	for i, claim := range claims {
+		fmt.Fprintf(&b, "%d. %s\n", i+1, claim)
+	}
+
+	resp, err := v.client.CreateChatCompletion(
+		context.Background(),
+		openai.ChatCompletionRequest{
+			Model: v.config.ChatGPTModel,
+			Messages: []openai.ChatCompletionMessage{
+				{Role: openai.ChatMessageRoleSystem, Content: judgePrompt},
+				{Role: openai.ChatMessageRoleUser, Content: b.String()},
+			},
+			ResponseFormat: &openai.ChatCompletionResponseFormat{
+				Type: openai.ChatCompletionResponseFormatTypeJSONObject,
+			},
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create chat completion: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("no response choices returned from API")
+	}
+
+	var result verdicts
+	if err := json.Unmarshal([]byte(resp.Choices[0].Message.Content), &result); err != nil {
+		return nil, fmt.Errorf("failed to decode claim verdicts: %w", err)
+	}
+	return &result, nil
+}
+
+var quotePattern = regexp.MustCompile(`"([^"\n]+)"|“([^”\n]+)”|„([^“”\n]+)[“”]`)
+
+// ExtractQuotes returns the quoted passages of at least three words in the summary
+func ExtractQuotes(summary string) []string {
+	var quotes []string
+	for _, m := range quotePattern.FindAllStringSubmatch(summary, -1) {
+		for _, group := range m[1:] {
+			if group != "" && len(strings.Fields(group)) >= 3 {
+				quotes = append(quotes, strings.TrimSpace(group))
+			}
+		}
+	}
+	return quotes
+}
+
+var listPrefix = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])\s+`)
+
+// ExtractNames returns capitalized words and word groups that are not at the start of a
+// sentence, which in meeting summaries are mostly names of people, teams and products
+func ExtractNames(summary string) []string {
+	seen := make(map[string]bool)
+	var names []string
+
+	for _, line := range strings.Split(summary, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		line = listPrefix.ReplaceAllString(line, "")
+		line = strings.NewReplacer("**", " ", "__", " ", "`", " ").Replace(line)
+
+		var current []string
+		flush := func() {
+			if len(current) > 0 {
+				name := strings.Join(current, " ")
+				if !seen[name] {
+					seen[name] = true
+					names = append(names, name)
+				}
+				current = nil
+			}
+		}
+
+		sentenceStart := true
+		for _, token := range strings.Fields(line) {
+			word := strings.TrimFunc(token, func(r rune) bool {
+				return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+			})
+			if isCapitalized(word) && !sentenceStart && !commonWords[strings.ToLower(word)] {
+				current = append(current, word)
+			} else {
+				flush()
+			}
+			sentenceStart = strings.HasSuffix(token, ".") || strings.HasSuffix(token, ":") ||
+				strings.HasSuffix(token, "!") || strings.HasSuffix(token, "?")
+			if sentenceStart || strings.HasSuffix(token, ",") {
+				flush()
+			}
+		}
+		flush()
+	}
+	return names
+}
+
+// ExtractClaims splits the summary into the statements that are judged: list items and sentences
+func ExtractClaims(summary string) []string {
+	var claims []string
+	for _, line := range strings.Split(summary, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "<!--") {
+			continue
+		}
+		if listPrefix.MatchString(line) {
+			claims = appendClaim(claims, listPrefix.ReplaceAllString(line, ""))
+			continue
+		}
+		for _, sentence := range splitSentences(trimmed) {
+			claims = appendClaim(claims, sentence)
+		}
+	}
+	if len(claims) > maxClaims {
+		claims = claims[:maxClaims]
+	}
+	return claims
+}
+
+func appendClaim(claims []string, claim string) []string {
+	claim = strings.TrimSpace(strings.NewReplacer("**", "", "__", "").Replace(claim))
+	if len(strings.Fields(claim)) < 4 || strings.HasSuffix(claim, ":") {
+		return claims
+	}
+	return append(claims, claim)
+}
+
+func splitSentences(text string) []string {
+	var sentences []string
+	start := 0
+	for i := 0; i < len(text); i++ {
+		if (text[i] == '.' || text[i] == '!' || text[i] == '?') && (i+1 == len(text) || text[i+1] == ' ') {
+			sentences = append(sentences, text[start:i+1])
+			start = i + 1
+		}
+	}
+	if start < len(text) {
+		sentences = append(sentences, text[start:])
+	}
+	return sentences
+}
+
+// MatchQuote returns the best similarity of the quote to any passage of the transcript words,
+// based on the word-level edit distance
+func MatchQuote(quote string, transcriptWords []string) float64 {
+	q := words(quote)
+	if len(q) == 0 || len(transcriptWords) == 0 {
+		return 0
+	}
+
+	best := 0.0
+	for start := 0; start < len(transcriptWords); start++ {
+		end := start + len(q)
+		if end > len(transcriptWords) {
+			end = len(transcriptWords)
+		}
+		distance := editDistance(q, transcriptWords[start:end])
+		similarity := 1 - float64(distance)/float64(len(q))
+		if similarity > best {
+			best = similarity
+			if best == 1 {
+				break
+			}
+		}
+	}
+	if best < 0 {
+		best = 0
+	}
+	return best
+}
+
+func editDistance(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, minInt(curr[j-1]+1, prev[j-1]+cost))
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r) && r != '\''
+	})
+}
+
+func isCapitalized(word string) bool {
+	for _, r := range word {
+		return unicode.IsUpper(r)
+	}
+	return false
+}
+
+func boolScore(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// commonWords are capitalized words that are not names
+var commonWords = map[string]bool{
+	"i": true, "i'm": true, "monday": true, "tuesday": true, "wednesday": true, "thursday": true,
+	"friday": true, "saturday": true, "sunday": true, "january": true, "february": true,
+	"march": true, "april": true, "may": true, "june": true, "july": true, "august": true,
+	"september": true, "october": true, "november": true, "december": true,
+	"ok": true, "okay": true, "q1": true, "q2": true, "q3": true, "q4": true,
+}
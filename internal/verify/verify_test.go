@@ -0,0 +1,179 @@
+package verify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/giantswarm/mnote/internal/config"
+	"github.com/sashabaranov/go-openai"
+)
+
+// judgeClient marks every claim mentioning Friday as unsupported
+type judgeClient struct {
+	request openai.ChatCompletionRequest
+}
+
+func (c *judgeClient) CreateChatCompletion(_ context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	c.request = req
+	var verdicts []string
+	for _, line := range strings.Split(req.Messages[1].Content, "\n") {
+		var n int
+		if _, err := fmt.Sscanf(line, "%d.", &n); err != nil {
+			continue
+		}
+		supported := !strings.Contains(line, "Friday")
+		verdicts = append(verdicts, fmt.Sprintf(`{"claim": %d, "supported": %t, "reason": "not mentioned"}`, n, supported))
+	}
+	return openai.ChatCompletionResponse{
+		Choices: []openai.ChatCompletionChoice{
+			{Message: openai.ChatCompletionMessage{Content: `{"verdicts": [` + strings.Join(verdicts, ",") + `]}`}},
+		},
+	}, nil
+}
+
+const testTranscript = `Alice: Good morning. The migration of the billing database is done.
+Bob: Great, then we can tell the customer that it's finished. I'll write to Carol today.`
+
+const testSummary = `# Standup
+
+- The billing database migration was completed, as Alice said: "the migration of the billing database is done".
+- Bob will write to Carol today.
+- The team will release on Friday with Dave.
+- Alice quoted the plan as "we ship everything next week".`
+
+func TestExtractQuotes(t *testing.T) {
+	got := ExtractQuotes(`He said "we are done here" and "ok". She said “let us ship it”.`)
+	want := []string{"we are done here", "let us ship it"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("ExtractQuotes() = %v, want %v", got, want)
+	}
+}
+
+func TestExtractNames(t *testing.T) {
+	got := ExtractNames(testSummary)
+	want := []string{"Alice", "Carol", "Dave"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("ExtractNames() = %v, want %v", got, want)
+	}
+}
+
+func TestExtractClaims(t *testing.T) {
+	claims := ExtractClaims(testSummary)
+	if len(claims) != 4 {
+		t.Fatalf("expected 4 claims, got %d: %v", len(claims), claims)
+	}
+	if claims[1] != "Bob will write to Carol today." {
+		t.Errorf("unexpected claim: %q", claims[1])
+	}
+}
+
+func TestMatchQuote(t *testing.T) {
+	transcriptWords := words(testTranscript)
+	if got := MatchQuote("the migration of the billing database is done", transcriptWords); got != 1 {
+		t.Errorf("exact quote similarity = %v, want 1", got)
+	}
+	if got := MatchQuote("the migration of our billing database is done", transcriptWords); got < QuoteThreshold {
+		t.Errorf("near quote similarity = %v, want >= %v", got, QuoteThreshold)
+	}
+	if got := MatchQuote("we ship everything next week", transcriptWords); got >= QuoteThreshold {
+		t.Errorf("invented quote similarity = %v, want < %v", got, QuoteThreshold)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	client := &judgeClient{}
+	v := NewVerifier(config.DefaultConfig(), client)
+
+	report, err := v.Verify(testSummary, testTranscript, true)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	var unsupported []string
+	for _, f := range report.Unsupported() {
+		unsupported = append(unsupported, f.Text)
+	}
+	want := []string{
+		"we ship everything next week",
+		"Dave",
+		"The team will release on Friday with Dave.",
+	}
+	if strings.Join(unsupported, "|") != strings.Join(want, "|") {
+		t.Errorf("Unsupported() = %v, want %v", unsupported, want)
+	}
+	if client.request.ResponseFormat == nil {
+		t.Error("expected JSON response format for the judge")
+	}
+
+	// The section is replaced rather than appended twice
+	verified := AppendSection(testSummary, report)
+	verified = AppendSection(verified, report)
+	if strings.Count(verified, "## Verification") != 1 {
+		t.Errorf("expected one verification section, got %q", verified)
+	}
+	if !strings.Contains(verified, "- **Name**: Dave") {
+		t.Errorf("expected unsupported name in section, got %q", verified)
+	}
+
+	// Without judging, no chat client is needed
+	report, err = NewVerifier(config.DefaultConfig(), nil).Verify(verified, testTranscript, false)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if len(report.Claims) != 0 || len(report.Names) != 3 {
+		t.Errorf("unexpected report without judge: %+v", report)
+	}
+}
+
+// partClient supports a claim if the transcript part contains its last word
+type partClient struct {
+	requests int
+}
+
+func (c *partClient) CreateChatCompletion(_ context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	c.requests++
+	content := req.Messages[1].Content
+	part := content[:strings.Index(content, "Statements:")]
+	var verdicts []string
+	for _, line := range strings.Split(content, "\n") {
+		var n int
+		if _, err := fmt.Sscanf(line, "%d.", &n); err != nil {
+			continue
+		}
+		fields := strings.Fields(strings.TrimSuffix(line, "."))
+		supported := strings.Contains(part, fields[len(fields)-1])
+		verdicts = append(verdicts, fmt.Sprintf(`{"claim": %d, "supported": %t, "reason": "not in this part"}`, n, supported))
+	}
+	return openai.ChatCompletionResponse{
+		Choices: []openai.ChatCompletionChoice{
+			{Message: openai.ChatCompletionMessage{Content: `{"verdicts": [` + strings.Join(verdicts, ",") + `]}`}},
+		},
+	}, nil
+}
+
+func TestVerifyParts(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.SummaryChunkChars = 80
+	client := &partClient{}
+
+	// Each claim is supported by another part of the transcript
+	summary := "- The team finished the billing migration.\n- Bob will write to Carol.\n- The team will release on Friday."
+	report, err := NewVerifier(cfg, client).Verify(summary, testTranscript, true)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if client.requests != 2 {
+		t.Errorf("expected the transcript to be judged in 2 parts, got %d requests", client.requests)
+	}
+	var unsupported []string
+	for _, f := range report.Claims {
+		if !f.Supported {
+			unsupported = append(unsupported, f.Text)
+		}
+	}
+	if len(report.Claims) != 3 || strings.Join(unsupported, "|") != "The team will release on Friday." {
+		t.Errorf("unexpected claims %+v", report.Claims)
+	}
+}
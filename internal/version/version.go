@@ -0,0 +1,5 @@
+package version
+
+// Version is the mnote version, set at build time with
+// -ldflags "-X github.com/giantswarm/mnote/internal/version.Version=x.y.z"
+var Version = "dev"
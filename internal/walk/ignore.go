@@ -0,0 +1,116 @@
+package walk
+
+import (
+	"bufio"
+	"path"
+	"strings"
+)
+
+// IgnoreFile is the name of the files listing paths to skip, in gitignore syntax
+const IgnoreFile = ".mnoteignore"
+
+// rule is a single gitignore style pattern
+type rule struct {
+	pattern  string
+	base     string // directory of the ignore file, relative to the root
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// Patterns is an ordered list of gitignore style patterns, the last matching pattern wins
+type Patterns []rule
+
+// ParsePatterns parses patterns in gitignore syntax that are relative to base, a slash
+// separated directory below the walk root ("" for the root itself). Empty lines and
+// lines starting with # are ignored, ! negates a pattern, a trailing / only matches
+// directories and patterns containing a / are relative to base instead of matching
+// at any level.
+func ParsePatterns(content, base string) Patterns {
+	var patterns Patterns
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, `\#`) || strings.HasPrefix(line, `\!`) {
+			line = line[1:]
+		} else if strings.HasPrefix(line, "!") {
+			patterns = append(patterns, newRule(line[1:], base, true))
+			continue
+		}
+		patterns = append(patterns, newRule(line, base, false))
+	}
+	return patterns
+}
+
+func newRule(pattern, base string, negate bool) rule {
+	r := rule{base: base, negate: negate}
+	if strings.HasSuffix(pattern, "/") {
+		r.dirOnly = true
+		pattern = strings.TrimRight(pattern, "/")
+	}
+	if strings.Contains(pattern, "/") {
+		r.anchored = true
+		pattern = strings.TrimPrefix(pattern, "/")
+	}
+	r.pattern = pattern
+	return r
+}
+
+// Match reports whether the slash separated path relative to the walk root is matched.
+// matched is false if no pattern applies or the last applying pattern is negated.
+func (p Patterns) Match(rel string, isDir bool) bool {
+	matched := false
+	for _, r := range p {
+		if r.match(rel, isDir) {
+			matched = !r.negate
+		}
+	}
+	return matched
+}
+
+func (r rule) match(rel string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if r.base != "" {
+		if !strings.HasPrefix(rel, r.base+"/") {
+			return false
+		}
+		rel = strings.TrimPrefix(rel, r.base+"/")
+	}
+	if r.anchored {
+		return matchGlob(r.pattern, rel)
+	}
+	return matchGlob(r.pattern, path.Base(rel))
+}
+
+// matchGlob matches a slash separated path against a pattern where * and ? do not
+// cross directory boundaries and ** matches any number of directories
+func matchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			rest := pattern[1:]
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(rest, name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
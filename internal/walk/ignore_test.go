@@ -0,0 +1,52 @@
+package walk
+
+import "testing"
+
+func TestPatternsMatch(t *testing.T) {
+	patterns := ParsePatterns(`
+# comment
+*.mkv
+!keep.mkv
+drafts/
+/top.mp4
+archive/**/old-*.mp4
+`, "")
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"a.mkv", false, true},
+		{"team/a.mkv", false, true},
+		{"team/keep.mkv", false, false},
+		{"drafts", true, true},
+		{"team/drafts", true, true},
+		{"drafts", false, false},
+		{"top.mp4", false, true},
+		{"team/top.mp4", false, false},
+		{"archive/old-1.mp4", false, true},
+		{"archive/2023/01/old-1.mp4", false, true},
+		{"archive/2023/new.mp4", false, false},
+		{"a.mp4", false, false},
+	}
+	for _, tt := range tests {
+		if got := patterns.Match(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("Match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestPatternsBase(t *testing.T) {
+	patterns := ParsePatterns("/raw.mp4\n*.mov\n", "2024/01")
+
+	if !patterns.Match("2024/01/raw.mp4", false) {
+		t.Error("expected anchored pattern to match in its directory")
+	}
+	if patterns.Match("raw.mp4", false) || patterns.Match("2024/02/a.mov", false) {
+		t.Error("expected patterns not to match outside their directory")
+	}
+	if !patterns.Match("2024/01/team/a.mov", false) {
+		t.Error("expected pattern to match below its directory")
+	}
+}
@@ -0,0 +1,158 @@
+package walk
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/giantswarm/mnote/internal/utils"
+)
+
+// Stdin is the input that reads newline separated paths from standard input
+const Stdin = "-"
+
+// Resolve expands files, directories and glob patterns into the list of recordings to process.
+// Directories are searched with Find, "-" reads further inputs from stdin, one per line.
+// The result is deduplicated and sorted by path.
+func Resolve(inputs []string, stdin io.Reader, opts Options) ([]string, error) {
+	expanded, err := readStdin(inputs, stdin)
+	if err != nil {
+		return nil, err
+	}
+
+	byAbs := map[string]string{}
+	add := func(path string) error {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s: %w", path, err)
+		}
+		if _, ok := byAbs[abs]; !ok {
+			byAbs[abs] = filepath.Clean(path)
+		}
+		return nil
+	}
+
+	for _, input := range expanded {
+		matches := []string{input}
+		_, err := os.Stat(input)
+		switch {
+		case os.IsNotExist(err) && !isGlob(input):
+			return nil, fmt.Errorf("input does not exist: %s", input)
+		case os.IsNotExist(err):
+			if matches, err = filepath.Glob(input); err != nil {
+				return nil, fmt.Errorf("invalid pattern %s: %w", input, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no files match: %s", input)
+			}
+		case err != nil:
+			return nil, fmt.Errorf("failed to read %s: %w", input, err)
+		}
+
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", match, err)
+			}
+			if !info.IsDir() {
+				// Files named explicitly must be recordings, files matched by a glob are filtered
+				if match == input && !utils.IsMediaFile(match) {
+					return nil, fmt.Errorf("not a supported video or audio file: %s", input)
+				}
+				if match != input && !isSource(match) {
+					continue
+				}
+				if err := add(match); err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			videos, err := Find(match, opts)
+			if err != nil {
+				return nil, err
+			}
+			for _, video := range videos {
+				if err := add(video); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	abs := make([]string, 0, len(byAbs))
+	for a := range byAbs {
+		abs = append(abs, a)
+	}
+	sort.Strings(abs)
+	paths := make([]string, len(abs))
+	for i, a := range abs {
+		paths[i] = byAbs[a]
+	}
+	return paths, nil
+}
+
+// readStdin replaces the stdin input with the paths read from stdin
+func readStdin(inputs []string, stdin io.Reader) ([]string, error) {
+	var expanded []string
+	read := false
+	for _, input := range inputs {
+		if input != Stdin {
+			expanded = append(expanded, input)
+			continue
+		}
+		if read {
+			continue
+		}
+		read = true
+		scanner := bufio.NewScanner(stdin)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				expanded = append(expanded, line)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read inputs from stdin: %w", err)
+		}
+	}
+	return expanded, nil
+}
+
+func isGlob(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+// Roots returns the directories the videos of the inputs are relative to when the source
+// tree is mirrored: a directory itself, the directory of a file, and the directory part of
+// a glob pattern before the first wildcard. Paths read from stdin have no root.
+func Roots(inputs []string) []string {
+	var roots []string
+	for _, input := range inputs {
+		if input == Stdin {
+			continue
+		}
+		root := input
+		if info, err := os.Stat(input); err == nil && !info.IsDir() {
+			root = filepath.Dir(input)
+		} else if err != nil && isGlob(input) {
+			root = globDir(input)
+		}
+		if abs, err := filepath.Abs(root); err == nil {
+			roots = append(roots, abs)
+		}
+	}
+	return roots
+}
+
+// globDir returns the leading directories of a pattern that contain no wildcards
+func globDir(pattern string) string {
+	dir := filepath.Dir(pattern)
+	for isGlob(dir) {
+		dir = filepath.Dir(dir)
+	}
+	return dir
+}
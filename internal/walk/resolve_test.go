@@ -0,0 +1,70 @@
+package walk
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	root := t.TempDir()
+	createTree(t, root, "b.mp4", "a.mkv", "notes.txt", "talks/z.mov", "talks/y.mp4")
+
+	stdin := strings.NewReader(filepath.Join(root, "talks", "y.mp4") + "\n\n" + filepath.Join(root, "b.mp4") + "\n")
+	inputs := []string{
+		filepath.Join(root, "talks"),
+		filepath.Join(root, "*.m*"),
+		Stdin,
+		filepath.Join(root, "b.mp4"),
+		filepath.Join(root, "talks", "..", "a.mkv"),
+	}
+	files, err := Resolve(inputs, stdin, Options{})
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	want := []string{"a.mkv", "b.mp4", "talks/y.mp4", "talks/z.mov"}
+	if got := relPaths(t, root, files); !reflect.DeepEqual(got, want) {
+		t.Errorf("Resolve() = %v, want %v", got, want)
+	}
+}
+
+func TestResolveErrors(t *testing.T) {
+	root := t.TempDir()
+	createTree(t, root, "notes.txt")
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{filepath.Join(root, "missing.mp4"), "input does not exist"},
+		{filepath.Join(root, "*.mp4"), "no files match"},
+		{filepath.Join(root, "notes.txt"), "not a supported video or audio file"},
+	}
+	for _, tt := range tests {
+		_, err := Resolve([]string{tt.input}, strings.NewReader(""), Options{})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Resolve(%q) error = %v, want %q", tt.input, err, tt.want)
+		}
+	}
+}
+
+func TestRoots(t *testing.T) {
+	root := t.TempDir()
+	createTree(t, root, "2024/01/a.mp4", "2024/02/b.mp4")
+
+	inputs := []string{
+		filepath.Join(root, "2024"),
+		filepath.Join(root, "2024", "01", "a.mp4"),
+		filepath.Join(root, "2024", "0*", "*.mp4"),
+		Stdin,
+	}
+	want := []string{
+		filepath.Join(root, "2024"),
+		filepath.Join(root, "2024", "01"),
+		filepath.Join(root, "2024"),
+	}
+	if got := Roots(inputs); !reflect.DeepEqual(got, want) {
+		t.Errorf("Roots() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,214 @@
+package walk
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/giantswarm/mnote/internal/state"
+	"github.com/giantswarm/mnote/internal/utils"
+)
+
+// Options controls which files Find returns
+type Options struct {
+	// Recursive descends into subdirectories
+	Recursive bool
+	// MaxDepth limits how many directory levels below the root are searched, 0 means no limit
+	MaxDepth int
+	// Include only returns files matching one of these patterns, if any are given
+	Include []string
+	// Exclude skips files and directories matching one of these patterns
+	Exclude []string
+	// FollowSymlinks descends into symlinked directories, symlinked files are always returned
+	FollowSymlinks bool
+}
+
+// Find returns the supported video and audio files below root, depth first in name order. Patterns use
+// gitignore syntax relative to root, and .mnoteignore files exclude paths in their directory and below.
+func Find(root string, opts Options) ([]string, error) {
+	w := &walker{
+		opts:    opts,
+		include: ParsePatterns(strings.Join(opts.Include, "\n"), ""),
+		exclude: ParsePatterns(strings.Join(opts.Exclude, "\n"), ""),
+		visited: map[string]bool{},
+	}
+	if err := w.walk(root, "", 0, nil); err != nil {
+		return nil, err
+	}
+	return w.files, nil
+}
+
+type walker struct {
+	opts    Options
+	include Patterns
+	exclude Patterns
+	visited map[string]bool
+	files   []string
+}
+
+// walk searches dir, which is rel below the root, with the ignore patterns of its parents
+func (w *walker) walk(dir, rel string, depth int, ignored Patterns) error {
+	// Guard against symlink loops
+	real, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve directory: %w", err)
+	}
+	if w.visited[real] {
+		return nil
+	}
+	w.visited[real] = true
+
+	if ignored, err = loadIgnore(dir, rel, ignored); err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read directory: %w", err)
+	}
+	for _, entry := range entries {
+		entryPath := filepath.Join(dir, entry.Name())
+		entryRel := path.Join(rel, entry.Name())
+
+		isDir := entry.IsDir()
+		if entry.Type()&os.ModeSymlink != 0 {
+			info, err := os.Stat(entryPath)
+			if err != nil {
+				// Dangling symlink
+				continue
+			}
+			if info.IsDir() && !w.opts.FollowSymlinks {
+				continue
+			}
+			isDir = info.IsDir()
+		}
+
+		if ignored.Match(entryRel, isDir) || w.exclude.Match(entryRel, isDir) {
+			continue
+		}
+
+		if isDir {
+			if !w.opts.Recursive || entry.Name() == state.Dir {
+				continue
+			}
+			if w.opts.MaxDepth > 0 && depth+1 > w.opts.MaxDepth {
+				continue
+			}
+			if err := w.walk(entryPath, entryRel, depth+1, ignored); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !isSource(entryPath) {
+			continue
+		}
+		if len(w.include) > 0 && !w.include.Match(entryRel, false) {
+			continue
+		}
+		w.files = append(w.files, entryPath)
+	}
+	return nil
+}
+
+// Match reports whether Find would return the file at path, which must be below root
+func Match(root, path string, opts Options) (bool, error) {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return false, nil
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+
+	depth := len(parts) - 1
+	if (depth > 0 && !opts.Recursive) || (opts.MaxDepth > 0 && depth > opts.MaxDepth) {
+		return false, nil
+	}
+	if !isSource(path) {
+		return false, nil
+	}
+
+	// Check the file and every directory on the way with the ignore files above them
+	exclude := ParsePatterns(strings.Join(opts.Exclude, "\n"), "")
+	var ignored Patterns
+	dir := root
+	for i, part := range parts {
+		dirRel := strings.Join(parts[:i], "/")
+		if ignored, err = loadIgnore(dir, dirRel, ignored); err != nil {
+			return false, err
+		}
+		isDir := i < len(parts)-1
+		if isDir && part == state.Dir {
+			return false, nil
+		}
+		entryRel := strings.Join(parts[:i+1], "/")
+		if ignored.Match(entryRel, isDir) || exclude.Match(entryRel, isDir) {
+			return false, nil
+		}
+		dir = filepath.Join(dir, part)
+	}
+
+	include := ParsePatterns(strings.Join(opts.Include, "\n"), "")
+	return len(include) == 0 || include.Match(filepath.ToSlash(rel), false), nil
+}
+
+// isSource reports whether a file is a recording to process. Hidden files, such as partial
+// downloads, and audio made from a recording next to it are left out: X_audio.mp3 next to
+// X.*, and X.mp3 next to a video X.*, which older versions extracted audio to.
+func isSource(path string) bool {
+	name := filepath.Base(path)
+	if strings.HasPrefix(name, ".") || !utils.IsMediaFile(path) {
+		return false
+	}
+	if !utils.IsAudioFile(path) {
+		return true
+	}
+	title := strings.TrimSuffix(name, filepath.Ext(name))
+	if strings.EqualFold(filepath.Ext(name), ".mp3") {
+		if recording := strings.TrimSuffix(title, "_audio"); recording != title && hasSibling(path, recording, utils.IsMediaFile) {
+			return false
+		}
+		if hasSibling(path, title, utils.IsVideoFile) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasSibling reports whether the directory of path has another file named title with an
+// extension accepted by match
+func hasSibling(path, title string, match func(string) bool) bool {
+	siblings, _ := filepath.Glob(filepath.Join(filepath.Dir(path), globEscape(title)+".*"))
+	for _, sibling := range siblings {
+		name := filepath.Base(sibling)
+		if sibling != path && strings.TrimSuffix(name, filepath.Ext(name)) == title && match(sibling) {
+			return true
+		}
+	}
+	return false
+}
+
+// globEscape escapes the glob metacharacters in a file name
+func globEscape(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if strings.ContainsRune(`*?[\`, r) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// loadIgnore adds the patterns of the ignore file in dir, which is rel below the root
+func loadIgnore(dir, rel string, ignored Patterns) (Patterns, error) {
+	data, err := os.ReadFile(filepath.Join(dir, IgnoreFile))
+	if os.IsNotExist(err) {
+		return ignored, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", IgnoreFile, err)
+	}
+	return append(append(Patterns{}, ignored...), ParsePatterns(string(data), rel)...), nil
+}
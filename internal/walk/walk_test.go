@@ -0,0 +1,174 @@
+package walk
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// createTree creates empty files for the slash separated paths below dir
+func createTree(t *testing.T, dir string, files ...string) {
+	t.Helper()
+	for _, file := range files {
+		path := filepath.Join(dir, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("content"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func relPaths(t *testing.T, root string, paths []string) []string {
+	t.Helper()
+	var rel []string
+	for _, path := range paths {
+		r, err := filepath.Rel(root, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rel = append(rel, filepath.ToSlash(r))
+	}
+	return rel
+}
+
+func TestFind(t *testing.T) {
+	root := t.TempDir()
+	createTree(t, root,
+		"top.mp4",
+		"notes.txt",
+		"2024/01/platform/standup.mp4",
+		"2024/01/platform/standup_transcript.md",
+		"2024/01/platform/retro.mkv",
+		"2024/02/sales/call.mov",
+		"2024/02/sales/private/secret.mp4",
+		".mnote/ignored.mp4",
+	)
+	os.WriteFile(filepath.Join(root, "2024", "02", IgnoreFile), []byte("private/\n"), 0644)
+
+	tests := []struct {
+		name string
+		opts Options
+		want []string
+	}{
+		{
+			name: "top level only",
+			opts: Options{},
+			want: []string{"top.mp4"},
+		},
+		{
+			name: "recursive",
+			opts: Options{Recursive: true},
+			want: []string{"2024/01/platform/retro.mkv", "2024/01/platform/standup.mp4", "2024/02/sales/call.mov", "top.mp4"},
+		},
+		{
+			name: "max depth",
+			opts: Options{Recursive: true, MaxDepth: 2},
+			want: []string{"top.mp4"},
+		},
+		{
+			name: "include",
+			opts: Options{Recursive: true, Include: []string{"*.mp4", "*.mov"}},
+			want: []string{"2024/01/platform/standup.mp4", "2024/02/sales/call.mov", "top.mp4"},
+		},
+		{
+			name: "exclude",
+			opts: Options{Recursive: true, Exclude: []string{"2024/01/"}},
+			want: []string{"2024/02/sales/call.mov", "top.mp4"},
+		},
+	}
+	all := []string{
+		"top.mp4",
+		"2024/01/platform/standup.mp4",
+		"2024/01/platform/retro.mkv",
+		"2024/02/sales/call.mov",
+		"2024/02/sales/private/secret.mp4",
+		".mnote/ignored.mp4",
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := Find(root, tt.opts)
+			if err != nil {
+				t.Fatalf("Find() error = %v", err)
+			}
+			if got := relPaths(t, root, files); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Find() = %v, want %v", got, tt.want)
+			}
+
+			// Match agrees with Find for every single file
+			found := map[string]bool{}
+			for _, rel := range tt.want {
+				found[rel] = true
+			}
+			for _, rel := range all {
+				matched, err := Match(root, filepath.Join(root, filepath.FromSlash(rel)), tt.opts)
+				if err != nil {
+					t.Fatalf("Match() error = %v", err)
+				}
+				if matched != found[rel] {
+					t.Errorf("Match(%s) = %v, want %v", rel, matched, found[rel])
+				}
+			}
+		})
+	}
+}
+
+func TestFindSymlinks(t *testing.T) {
+	root := t.TempDir()
+	other := t.TempDir()
+	createTree(t, root, "a/video.mp4")
+	createTree(t, other, "linked.mp4")
+
+	if err := os.Symlink(other, filepath.Join(root, "b")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	os.Symlink(filepath.Join(other, "linked.mp4"), filepath.Join(root, "file.mp4"))
+	os.Symlink(root, filepath.Join(root, "a", "loop"))
+	os.Symlink(filepath.Join(root, "missing.mp4"), filepath.Join(root, "dangling.mp4"))
+
+	files, err := Find(root, Options{Recursive: true})
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if got, want := relPaths(t, root, files), []string{"a/video.mp4", "file.mp4"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Find() without following symlinks = %v, want %v", got, want)
+	}
+
+	files, err = Find(root, Options{Recursive: true, FollowSymlinks: true})
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if got, want := relPaths(t, root, files), []string{"a/video.mp4", "b/linked.mp4", "file.mp4"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Find() following symlinks = %v, want %v", got, want)
+	}
+}
+
+func TestFindAudio(t *testing.T) {
+	root := t.TempDir()
+	createTree(t, root,
+		"memo.m4a",
+		"podcast.mp3",
+		"podcast_audio.mp3",
+		"standup.mp4",
+		"standup.mp3",
+		"standup_audio.mp3",
+		"voice.opus",
+		"voice_audio.mp3",
+		".call.partial.mp3",
+		"._memo.m4a",
+	)
+
+	files, err := Find(root, Options{})
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	want := []string{"memo.m4a", "podcast.mp3", "standup.mp4", "voice.opus"}
+	if got := relPaths(t, root, files); !reflect.DeepEqual(got, want) {
+		t.Errorf("Find() = %v, want %v", got, want)
+	}
+	if matched, _ := Match(root, filepath.Join(root, "voice_audio.mp3"), Options{}); matched {
+		t.Error("expected audio converted from a recording not to match")
+	}
+}
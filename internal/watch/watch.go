@@ -0,0 +1,316 @@
+package watch
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/giantswarm/mnote/internal/state"
+	"github.com/giantswarm/mnote/internal/walk"
+)
+
+// Defaults for Options
+const (
+	DefaultSettle   = 10 * time.Second
+	DefaultInterval = time.Second
+)
+
+// Options holds the watch options
+type Options struct {
+	// Walk selects the videos in the watched directory, as for a normal run
+	Walk walk.Options
+	// Settle is how long a file must be unchanged, without events and without growing,
+	// before it is processed
+	Settle time.Duration
+	// Interval is how often pending files are checked
+	Interval time.Duration
+	// StateDir returns the directory with the state manifest of a video, by default the
+	// directory of the video
+	StateDir func(path string) string
+}
+
+// ProcessFunc processes a settled video file and records it in the state manifest
+type ProcessFunc func(path string) error
+
+// pendingFile is a video that changed and is waiting to settle
+type pendingFile struct {
+	size    int64
+	modTime time.Time
+	changed time.Time
+}
+
+// Watcher processes new and changed videos in a directory
+type Watcher struct {
+	dir     string
+	opts    Options
+	process ProcessFunc
+	pending map[string]*pendingFile
+
+	// queue holds the settled videos in the order they are processed, a video is queued
+	// at most once. Queueing never blocks, so the event loop keeps running while a long
+	// recording is processed.
+	mu     sync.Mutex
+	queue  []string
+	queued map[string]bool
+	wake   chan struct{}
+}
+
+// New creates a watcher for dir that calls process for every video that is new or
+// differs from the version recorded in the state manifest of its directory
+func New(dir string, opts Options, process ProcessFunc) *Watcher {
+	if opts.Settle <= 0 {
+		opts.Settle = DefaultSettle
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultInterval
+	}
+	if opts.StateDir == nil {
+		opts.StateDir = filepath.Dir
+	}
+	return &Watcher{
+		dir:     dir,
+		opts:    opts,
+		process: process,
+		pending: map[string]*pendingFile{},
+		queued:  map[string]bool{},
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Run watches the directory until the context is cancelled. Videos that appeared while
+// mnote was not running are picked up first. On cancellation the video currently being
+// processed is finished, queued videos are left for the next run.
+func (w *Watcher) Run(ctx context.Context) error {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	defer fsw.Close()
+
+	if err := w.addDirs(fsw, w.dir); err != nil {
+		return err
+	}
+	if err := w.scan(w.dir); err != nil {
+		return err
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w.work(ctx, stop)
+	}()
+
+	ticker := time.NewTicker(w.opts.Interval)
+	defer ticker.Stop()
+
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case event, ok := <-fsw.Events:
+			if !ok {
+				break loop
+			}
+			w.handle(fsw, event)
+		case err, ok := <-fsw.Errors:
+			if !ok {
+				break loop
+			}
+			slog.Warn("File watcher error", "error", err)
+		case <-ticker.C:
+			w.check(time.Now())
+		}
+	}
+
+	close(stop)
+	<-done
+	return nil
+}
+
+// handle tracks the video of a file system event and watches new subdirectories
+func (w *Watcher) handle(fsw *fsnotify.Watcher, event fsnotify.Event) {
+	if !event.Has(fsnotify.Create) && !event.Has(fsnotify.Write) {
+		return
+	}
+	info, err := os.Stat(event.Name)
+	if err != nil {
+		return
+	}
+	if info.IsDir() {
+		if event.Has(fsnotify.Create) && w.opts.Walk.Recursive {
+			// Files may have been created before the watch was added
+			if err := w.addDirs(fsw, event.Name); err != nil {
+				slog.Warn("Failed to watch directory", "path", event.Name, "error", err)
+			}
+			if err := w.scan(event.Name); err != nil {
+				slog.Warn("Failed to scan directory", "path", event.Name, "error", err)
+			}
+		}
+		return
+	}
+	matched, err := walk.Match(w.dir, event.Name, w.opts.Walk)
+	if err != nil {
+		slog.Warn("Failed to match file", "path", event.Name, "error", err)
+		return
+	}
+	if matched {
+		w.touch(event.Name, info, time.Now())
+	}
+}
+
+// touch marks a video as changed at the given time
+func (w *Watcher) touch(path string, info os.FileInfo, now time.Time) {
+	w.pending[path] = &pendingFile{size: info.Size(), modTime: info.ModTime(), changed: now}
+}
+
+// check queues the pending videos that did not change for the settle time
+func (w *Watcher) check(now time.Time) {
+	var ready []string
+	for path, p := range w.pending {
+		info, err := os.Stat(path)
+		if err != nil {
+			// Removed or renamed before it settled
+			delete(w.pending, path)
+			continue
+		}
+		if info.Size() != p.size || !info.ModTime().Equal(p.modTime) {
+			w.touch(path, info, now)
+			continue
+		}
+		if now.Sub(p.changed) >= w.opts.Settle {
+			delete(w.pending, path)
+			if !w.processed(path, info) {
+				ready = append(ready, path)
+			}
+		}
+	}
+	sort.Strings(ready)
+	w.enqueue(ready)
+}
+
+// enqueue adds videos to the queue unless they are queued already and wakes the worker
+func (w *Watcher) enqueue(paths []string) {
+	w.mu.Lock()
+	for _, path := range paths {
+		if !w.queued[path] {
+			w.queued[path] = true
+			w.queue = append(w.queue, path)
+		}
+	}
+	w.mu.Unlock()
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// next removes the first video from the queue, it reports false if the queue is empty
+func (w *Watcher) next() (string, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.queue) == 0 {
+		return "", false
+	}
+	path := w.queue[0]
+	w.queue = w.queue[1:]
+	delete(w.queued, path)
+	return path, true
+}
+
+// work processes the queued videos one after the other until the context is cancelled,
+// or until stop is closed and the queue is empty
+func (w *Watcher) work(ctx context.Context, stop <-chan struct{}) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+		path, ok := w.next()
+		if !ok {
+			select {
+			case <-ctx.Done():
+				return
+			case <-stop:
+				return
+			case <-w.wake:
+			}
+			continue
+		}
+		// A video can be queued again while it is being processed
+		info, err := os.Stat(path)
+		if err != nil || w.processed(path, info) {
+			continue
+		}
+		slog.Info("Processing new recording", "file", path)
+		if err := w.process(path); err != nil {
+			slog.Error("Failed to process recording", "file", path, "error", err)
+		}
+	}
+}
+
+// scan adds the videos below dir that were not processed in their current version
+func (w *Watcher) scan(dir string) error {
+	videos, err := walk.Find(dir, w.opts.Walk)
+	if err != nil {
+		return err
+	}
+	for _, video := range videos {
+		// Files in subdirectories of a new directory must pass the filters of the watched directory
+		if matched, err := walk.Match(w.dir, video, w.opts.Walk); err != nil || !matched {
+			continue
+		}
+		info, err := os.Stat(video)
+		if err != nil {
+			continue
+		}
+		if !w.processed(video, info) {
+			// Settle from now on, the file may still be written
+			w.touch(video, info, time.Now())
+		}
+	}
+	return nil
+}
+
+// addDirs watches dir and, when recursive, the directories below it
+func (w *Watcher) addDirs(fsw *fsnotify.Watcher, dir string) error {
+	if !w.opts.Walk.Recursive {
+		if err := fsw.Add(dir); err != nil {
+			return fmt.Errorf("failed to watch %s: %w", dir, err)
+		}
+		return nil
+	}
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if d.Name() == state.Dir {
+			return filepath.SkipDir
+		}
+		if err := fsw.Add(path); err != nil {
+			return fmt.Errorf("failed to watch %s: %w", path, err)
+		}
+		return nil
+	})
+}
+
+// processed reports whether the video was processed in its current version, failed
+// videos count as processed so that they are only retried once they change
+func (w *Watcher) processed(path string, info os.FileInfo) bool {
+	m, err := state.Load(w.opts.StateDir(path))
+	if err != nil {
+		return false
+	}
+	f := m.Files[filepath.Base(path)]
+	return f != nil && f.Status != state.StatusRunning && f.Matches(info)
+}
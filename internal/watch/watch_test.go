@@ -0,0 +1,127 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/mnote/internal/state"
+)
+
+// recorder is a ProcessFunc that remembers the processed files and records them in the
+// state manifest like the processing pipeline does
+type recorder struct {
+	mu    sync.Mutex
+	paths []string
+	sizes map[string]int64
+}
+
+func (r *recorder) process(path string) error {
+	info, _ := os.Stat(path)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.paths = append(r.paths, filepath.Base(path))
+	if r.sizes == nil {
+		r.sizes = map[string]int64{}
+	}
+	r.sizes[filepath.Base(path)] = info.Size()
+	return state.Update(filepath.Dir(path), func(m *state.Manifest) error {
+		m.Files[filepath.Base(path)] = &state.FileState{
+			Status:        state.StatusSucceeded,
+			SourceSize:    info.Size(),
+			SourceModTime: info.ModTime(),
+		}
+		return nil
+	})
+}
+
+func (r *recorder) processed() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string{}, r.paths...)
+}
+
+// startWatcher runs a watcher with short timings and returns the function stopping it
+func startWatcher(t *testing.T, dir string, r *recorder) func() {
+	t.Helper()
+	w := New(dir, Options{
+		Settle:   100 * time.Millisecond,
+		Interval: 10 * time.Millisecond,
+	}, r.process)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- w.Run(ctx) }()
+	return func() {
+		cancel()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("Run() error = %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("watcher did not stop")
+		}
+	}
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "old.mp4"), []byte("recorded earlier"), 0644)
+
+	r := &recorder{}
+	stop := startWatcher(t, dir, r)
+
+	// Videos that exist on start are processed
+	waitFor(t, "existing video", func() bool { return len(r.processed()) == 1 })
+
+	// A growing recording is processed once, after it stopped growing
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a video"), 0644)
+	f, err := os.Create(filepath.Join(dir, "new.mp4"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		f.Write([]byte("chunk"))
+		time.Sleep(40 * time.Millisecond)
+	}
+	f.Close()
+	waitFor(t, "new video", func() bool { return len(r.processed()) == 2 })
+	time.Sleep(200 * time.Millisecond)
+	stop()
+
+	got := r.processed()
+	if len(got) != 2 || got[0] != "old.mp4" || got[1] != "new.mp4" {
+		t.Fatalf("expected old.mp4 and new.mp4 to be processed once, got %v", got)
+	}
+	if r.sizes["new.mp4"] != 25 {
+		t.Errorf("expected the complete recording to be processed, got %d bytes", r.sizes["new.mp4"])
+	}
+
+	// After a restart only changed videos are processed again
+	r = &recorder{}
+	stop = startWatcher(t, dir, r)
+	defer stop()
+	time.Sleep(300 * time.Millisecond)
+	if got := r.processed(); len(got) != 0 {
+		t.Fatalf("expected processed videos to be skipped after restart, got %v", got)
+	}
+	later := time.Now().Add(time.Minute)
+	os.WriteFile(filepath.Join(dir, "old.mp4"), []byte("recorded again"), 0644)
+	os.Chtimes(filepath.Join(dir, "old.mp4"), later, later)
+	waitFor(t, "changed video", func() bool { return len(r.processed()) == 1 })
+}
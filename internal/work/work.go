@@ -0,0 +1,108 @@
+package work
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/mnote/internal/utils"
+)
+
+// sourceFile records the source the results of a work directory were made from
+const sourceFile = "source.json"
+
+// Dir is a work directory holding the intermediate results of a source, such as the
+// transcripts of audio chunks, so an interrupted run resumes from the last completed unit.
+// The results are discarded when the source changes.
+type Dir struct {
+	path string
+}
+
+// source identifies the version of a source file
+type source struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// Open opens the work directory at path for the source file, creating it if needed. If it
+// holds results of a different version of the source, they are removed and discarded is set.
+func Open(path, sourcePath string) (d *Dir, discarded bool, err error) {
+	info, err := os.Stat(sourcePath)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to stat source: %w", err)
+	}
+	current := source{Size: info.Size(), ModTime: info.ModTime()}
+	d = &Dir{path: path}
+
+	var recorded source
+	found, err := d.Load(sourceFile, &recorded)
+	if err != nil || (found && (recorded.Size != current.Size || !recorded.ModTime.Equal(current.ModTime))) {
+		if err := os.RemoveAll(path); err != nil {
+			return nil, false, fmt.Errorf("failed to discard work directory: %w", err)
+		}
+		discarded = true
+		found = false
+	}
+	if !found {
+		if err := os.MkdirAll(path, 0755); err != nil {
+			return nil, false, fmt.Errorf("failed to create work directory: %w", err)
+		}
+		if err := d.Save(sourceFile, current); err != nil {
+			return nil, false, err
+		}
+	}
+	return d, discarded, nil
+}
+
+// Path returns the path of a file in the work directory
+func (d *Dir) Path(name string) string {
+	return filepath.Join(d.path, name)
+}
+
+// Load reads the result saved under name into v and reports whether there is one
+func (d *Dir) Load(name string, v interface{}) (bool, error) {
+	data, err := os.ReadFile(d.Path(name))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	return true, nil
+}
+
+// Save saves a result under name, it is written atomically so an interrupted run never
+// leaves a partial result behind
+func (d *Dir) Save(name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", name, err)
+	}
+	if err := utils.WriteFile(d.Path(name), data); err != nil {
+		return fmt.Errorf("failed to save %s: %w", name, err)
+	}
+	return nil
+}
+
+// Remove removes the work directory with all results
+func (d *Dir) Remove() error {
+	if err := os.RemoveAll(d.path); err != nil {
+		return fmt.Errorf("failed to remove work directory: %w", err)
+	}
+	return nil
+}
+
+// Key returns a short hash of the inputs of a result, to name results so that they are
+// only reused for the same inputs
+func Key(inputs ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(inputs, "\x00")))
+	return hex.EncodeToString(sum[:8])
+}
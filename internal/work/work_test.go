@@ -0,0 +1,67 @@
+package work
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestOpen(t *testing.T) {
+	tmpDir := t.TempDir()
+	source := filepath.Join(tmpDir, "meeting.mp4")
+	if err := os.WriteFile(source, []byte("video"), 0644); err != nil {
+		t.Fatalf("failed to create source: %v", err)
+	}
+	path := filepath.Join(tmpDir, ".mnote", "work", "meeting.mp4")
+
+	wd, discarded, err := Open(path, source)
+	if err != nil || discarded {
+		t.Fatalf("Open() = %v, %v", discarded, err)
+	}
+	if err := wd.Save("part.json", "first part"); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// The same source keeps the results
+	wd, discarded, err = Open(path, source)
+	if err != nil || discarded {
+		t.Fatalf("Open() = %v, %v", discarded, err)
+	}
+	var part string
+	if found, err := wd.Load("part.json", &part); err != nil || !found || part != "first part" {
+		t.Errorf("Load() = %q, %v, %v", part, found, err)
+	}
+	if found, err := wd.Load("missing.json", &part); err != nil || found {
+		t.Errorf("Load() of a missing result = %v, %v", found, err)
+	}
+
+	// A changed source discards them
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(source, later, later); err != nil {
+		t.Fatalf("failed to touch source: %v", err)
+	}
+	wd, discarded, err = Open(path, source)
+	if err != nil || !discarded {
+		t.Fatalf("Open() of a changed source = %v, %v", discarded, err)
+	}
+	if found, _ := wd.Load("part.json", &part); found {
+		t.Error("expected the result of the changed source to be discarded")
+	}
+
+	if err := wd.Remove(); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected the work directory to be removed, got %v", err)
+	}
+}
+
+func TestKey(t *testing.T) {
+	if Key("model", "prompt") != Key("model", "prompt") {
+		t.Error("expected the same key for the same inputs")
+	}
+	if Key("model", "prompt") == Key("modelprompt") || Key("a", "bc") == Key("ab", "c") {
+		t.Error("expected different keys for different inputs")
+	}
+}